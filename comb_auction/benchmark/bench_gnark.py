@@ -129,6 +129,7 @@ def load_existing(path: Path) -> tuple[list, set]:
             r.get("auction_start"),
             r.get("auction_end"),
             r.get("auction_index"),
+            r.get("solver_rewards", False),
         )
         for r in results
         if "params" in r
@@ -181,6 +182,8 @@ def main():
     ap.add_argument("--skip_prove", action="store_true")
     ap.add_argument("--go_cmd", default="go")
     ap.add_argument("--icicle", action="store_true")
+    ap.add_argument("--solver_rewards", action="store_true",
+                    help="compile and prove with per-winner reference scores and rewards")
     args = ap.parse_args()
 
     circuit_path = Path(args.circuit_path).resolve()
@@ -219,6 +222,7 @@ def main():
                 args.auction_start,
                 args.auction_end,
                 args.auction_index,
+                args.solver_rewards,
             )
             if key in existing_keys:
                 print(f"\n[{idx+1}/{len(PARAM_GRID)}] Skip (already done): {params}")
@@ -233,6 +237,7 @@ def main():
                 "auction_start": args.auction_start,
                 "auction_end": args.auction_end,
                 "auction_index": args.auction_index,
+                "solver_rewards": args.solver_rewards,
                 "auction_id": None,
                 "setup": {},
                 "prove": {},
@@ -242,7 +247,10 @@ def main():
             circuit_path.write_text(patched)
             print("Patched circuit.go")
 
-            rc, out = run_cmd([args.go_cmd, "run", "."], str(setup_path.parent), "setup")
+            setup_cmd = [args.go_cmd, "run", "."]
+            if args.solver_rewards:
+                setup_cmd.append("--solver_rewards")
+            rc, out = run_cmd(setup_cmd, str(setup_path.parent), "setup")
             if rc != 0:
                 record["error"] = out[-2000:]
                 results.append(record)
@@ -270,6 +278,8 @@ def main():
                 "--vk",            vk_path,
                 "--out",           proof_path,
             ]
+            if args.solver_rewards:
+                prove_cmd.append("--solver_rewards")
 
             rc, out = run_cmd(prove_cmd, str(prover_cwd), "prove(warmup)")
             if rc != 0:
@@ -3,55 +3,75 @@ package comb_gnark
 import (
 	"math/big"
 
-	"github.com/consensys/gnark/constraint/solver"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
 	"github.com/consensys/gnark/std/math/cmp"
+	"github.com/consensys/gnark/std/multicommit"
 	"github.com/consensys/gnark/std/rangecheck"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/gadgets"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
+)
+
+const (
+	NMax          = 120 // Max number of solutions
+	TMax          = 10  // Max number of trades per solution
+	WMax          = 30  // Max number of winners
+	TreeDepth     = 7   // must satisfy 2^TreeDepth >= NMax
+	AMT_BITS      = 120 // Bit width for token amounts; divisions by an amount need 2*AMT_BITS+3 < 254
+	PRICE_BITS    = 96  // Bit width for native price
+	PairMax       = 10
+	PriceMax      = 128       // Max number of tokens in the auction price vector
+	TRADE_ID_BITS = 248       // Bit width for trade IDs (truncated keccak of the order uid)
+	FEE_BITS      = 20        // Bit width for fee factors
+	FEE_ONE       = 1_000_000 // fee factors are parts per FEE_ONE, in [0, FEE_ONE)
+
+	// Bit width for a sum of WMax solution scores: trade scores are below
+	// 2^(AMT_BITS+FEE_BITS+PRICE_BITS-57), see computeSolutionScore
+	TOTAL_SCORE_BITS = AMT_BITS + FEE_BITS + PRICE_BITS - 57 + 4 + 5
 )
 
+// Trade.FeeKind
 const (
-	NMax       = 120 // Max number of solutions
-	TMax       = 10  // Max number of trades per solution
-	WMax       = 30  // Max number of winners
-	TreeDepth  = 7   // must satisfy 2^TreeDepth >= NMax
-	AMT_BITS   = 128 // Bit width for token amounts
-	PRICE_BITS = 96  // Bit width for native price
-	PairMax    = 10
+	FeeNone             = 0
+	FeeSurplus          = 1
+	FeeVolume           = 2
+	FeePriceImprovement = 3
 )
 
 var ONE_E18 = big.NewInt(1_000_000_000_000_000_000)
 
 type Comparators struct {
-	LenSol  *cmp.BoundedComparator // for SolutionsLen and i < SolutionsLen
-	LenTr   *cmp.BoundedComparator // for TradesLen and t < TradesLen
-	LenWin  *cmp.BoundedComparator // for WinnersLen, winCount
-	LenPair *cmp.BoundedComparator // for PairsLen and idx < PairsLen
+	LenSol   *cmp.BoundedComparator // for SolutionsLen and i < SolutionsLen
+	LenTr    *cmp.BoundedComparator // for TradesLen and t < TradesLen
+	LenWin   *cmp.BoundedComparator // for WinnersLen, winCount
+	LenPair  *cmp.BoundedComparator // for PairsLen and idx < PairsLen
+	LenPrice *cmp.BoundedComparator // for PricesLen and idx < PricesLen
 
 	Score *cmp.BoundedComparator // for score ordering (cap < 2^128-ish)
-	U64   *cmp.BoundedComparator // for solution_id ordering
 	Amt   *cmp.BoundedComparator
 }
 
 func newComparators(api frontend.API) Comparators {
 	lenSol := cmp.NewBoundedComparator(api, pow2(9), true)
-	lenTr := cmp.NewBoundedComparator(api, pow2(8), true)   // up to 150
-	lenWin := cmp.NewBoundedComparator(api, pow2(7), true)  // up to 60
-	lenPair := cmp.NewBoundedComparator(api, pow2(6), true) // up to 20
+	lenTr := cmp.NewBoundedComparator(api, pow2(8), true)    // up to 150
+	lenWin := cmp.NewBoundedComparator(api, pow2(7), true)   // up to 60
+	lenPair := cmp.NewBoundedComparator(api, pow2(6), true)  // up to 20
+	lenPrice := cmp.NewBoundedComparator(api, pow2(8), true) // up to 128
 
 	score := cmp.NewBoundedComparator(api, pow2(128), true)
-	u64c := cmp.NewBoundedComparator(api, pow2(64), true)
 
 	amt := cmp.NewBoundedComparator(api, pow2(AMT_BITS+1), true)
 
 	return Comparators{
-		LenSol:  lenSol,
-		LenTr:   lenTr,
-		LenWin:  lenWin,
-		LenPair: lenPair,
+		LenSol:   lenSol,
+		LenTr:    lenTr,
+		LenWin:   lenWin,
+		LenPair:  lenPair,
+		LenPrice: lenPrice,
 
 		Score: score,
-		U64:   u64c,
 		Amt:   amt,
 	}
 }
@@ -66,7 +86,8 @@ type U64 struct {
 }
 
 type Trade struct {
-	// hash(uid bytes) outside and provided as field element
+	// hash(uid bytes) outside, truncated to TRADE_ID_BITS; strictly ascending
+	// among the active trades of a solution
 	ID frontend.Variable
 
 	SellToken Address
@@ -79,6 +100,17 @@ type Trade struct {
 	Side         frontend.Variable // 0 = Sell, 1 = Buy (enforced boolean)
 
 	NativePriceBuy frontend.Variable
+
+	// protocol fee policy, see computeProtocolFee
+	FeeKind   frontend.Variable // FeeNone, FeeSurplus, FeeVolume or FeePriceImprovement
+	FeeFactor frontend.Variable // parts per FEE_ONE
+	FeeCap    frontend.Variable // max volume factor of surplus and price improvement fees, parts per FEE_ONE
+	QuoteSell frontend.Variable // quoted amounts, price improvement only
+	QuoteBuy  frontend.Variable
+
+	// 0: fill-or-kill, the order's side amount must be executed exactly
+	// 1: partially fillable, at most the order's side amount
+	PartiallyFillable frontend.Variable
 }
 
 type Solution struct {
@@ -95,6 +127,14 @@ type Solution struct {
 	PairKey      [PairMax]frontend.Variable // directed pair key for each bucket (sell + rPair*buy)
 	PairScore    [PairMax]frontend.Variable // aggregated score per bucket
 	TradePairIdx [TMax]frontend.Variable    // which bucket each trade belongs to
+
+	TradePriceIdx [TMax]frontend.Variable // entry of Circuit.Prices for each trade's buy token
+}
+
+// Price is an entry of the auction's native price vector
+type Price struct {
+	Token    frontend.Variable
+	PriceE18 frontend.Variable
 }
 
 // Winner entry (required on-chain).
@@ -102,17 +142,27 @@ type Winner struct {
 	SolutionID frontend.Variable
 	Solver     frontend.Variable
 	Score      frontend.Variable
-	// TradeID frontend.Variable
+	// the winning solution's trades, see hashTrades; settlements are
+	// checked against it
+	TradesCommit frontend.Variable
+
+	// With Circuit.SolverRewards, see solverRewards; 0 otherwise
+	Reference frontend.Variable // total score of the winners without this solver, filter rerun
+	Reward    frontend.Variable // total score of the winners minus Reference
 }
 
+// Packed is a solution as ranked and selected: Solutions in score order
+// (ByScore) and the packed survivors
 type Packed struct {
-	SolutionID frontend.Variable
-	Solver     frontend.Variable
-	Score      frontend.Variable
-	Commit     frontend.Variable
-	PairsLen   frontend.Variable
-	PairKey    [PairMax]frontend.Variable
-	PairScore  [PairMax]frontend.Variable
+	SolutionID   frontend.Variable
+	Solver       frontend.Variable
+	Score        frontend.Variable
+	Commit       frontend.Variable
+	TradesCommit frontend.Variable // public for winners
+	Valid        frontend.Variable // every trade respects its order, see computeSolutionScore
+	PairsLen     frontend.Variable
+	PairKey      [PairMax]frontend.Variable
+	PairScore    [PairMax]frontend.Variable
 }
 
 type Circuit struct {
@@ -123,13 +173,23 @@ type Circuit struct {
 	WinnersLen frontend.Variable `gnark:",public"`
 	Winners    [WMax]Winner      `gnark:",public"`
 
+	// Compile-time option: trades of a solution on the same directed pair must
+	// clear at a uniform price, see uniformPriceViolations
+	UniformPrices bool `gnark:"-"`
+	// Compile-time option: prove each winner's reference score and reward,
+	// see solverRewards. Costs one greedy selection per winner slot.
+	SolverRewards bool `gnark:"-"`
+
 	// Private
-	SolutionsLen frontend.Variable
-	Solutions    [NMax]Solution
-}
+	// auction-wide native prices, tokens strictly ascending; hashed into BidsetRoot
+	PricesLen frontend.Variable
+	Prices    [PriceMax]Price
 
-func init() {
-	solver.RegisterHint(IsLessHint, DivModHint)
+	SolutionsLen frontend.Variable
+	// in bidset order: leaf commits strictly ascending, as the autopilot builds the tree
+	Solutions [NMax]Solution
+	// Solutions by score descending, a permutation of the active ones
+	ByScore [NMax]Packed
 }
 
 func (c *Circuit) Define(api frontend.API) error {
@@ -140,15 +200,6 @@ func (c *Circuit) Define(api frontend.API) error {
 	assertLeqConst(api, cmps.LenSol, c.SolutionsLen, NMax)
 	assertLeqConst(api, cmps.LenWin, c.WinnersLen, WMax)
 
-	// we enforce solutions are provided sorted by SolutionID (ascending) among active solutions.
-	for i := 0; i+1 < NMax; i++ {
-		both := api.Mul(
-			isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen),
-			isLessThanConst(api, cmps.LenSol, i+1, c.SolutionsLen),
-		)
-		assertLtIf(api, cmps.U64, c.Solutions[i].SolutionID.Value, c.Solutions[i+1].SolutionID.Value, both)
-	}
-
 	// Compute per-solution:
 	// enforce tradesLen bound
 	// enforce pair aggregation & assignment (guest pairs_scores)
@@ -157,10 +208,14 @@ func (c *Circuit) Define(api frontend.API) error {
 	leaves := make([]frontend.Variable, 1<<TreeDepth) // padded to power-of-two
 	commits := make([]frontend.Variable, NMax)
 	totalScores := make([]frontend.Variable, NMax)
+	tradesCommits := make([]frontend.Variable, NMax)
+	validSols := make([]frontend.Variable, NMax)
 
-	// derive global challenge r from public inputs
-	r := deriveChallengeR(api, c.AuctionID)
-	rPair := derivePairChallenge(api, c.AuctionID)
+	assertLeqConst(api, cmps.LenPrice, c.PricesLen, PriceMax)
+	tokenTable, priceTable, pricesCommit := commitPrices(api, cmps, rc, c.Prices[:], c.PricesLen)
+
+	rPair := derivePairChallenge(api, c.BidsetRoot)
+	tradeScores := make([][TMax]frontend.Variable, NMax)
 
 	for i := 0; i < (1 << TreeDepth); i++ {
 		if i < NMax {
@@ -168,25 +223,32 @@ func (c *Circuit) Define(api frontend.API) error {
 			active := isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen)
 
 			// enforce tradesLen <= TMax if active
-			assertLeqConstIf(api, cmps.LenTr, c.Solutions[i].TradesLen, TMax, active)
+			gadgets.AssertLeqConstIf(api, cmps.LenTr, c.Solutions[i].TradesLen, TMax, active)
 			// enforce pairsLen <= PairMax if active
-			assertLeqConstIf(api, cmps.LenPair, c.Solutions[i].PairsLen, PairMax, active)
+			gadgets.AssertLeqConstIf(api, cmps.LenPair, c.Solutions[i].PairsLen, PairMax, active)
 
 			//enforce bucket keys are unique among active pair slots
 			enforceUniquePairKeys(api, cmps, &c.Solutions[i], active)
 
-			// polynomial accumulator commitment to trades
-			tradesCommit := computeTradesCommit(api, cmps, &c.Solutions[i], active, r)
+			// hash of the trades, public for winners
+			tradesCommit := hashTrades(api, cmps, rc, &c.Solutions[i], active)
+			tradesCommits[i] = tradesCommit
+
+			// score, and per trade for the pair aggregation binding
+			sc, ts, valid := computeSolutionScore(api, cmps, rc, &c.Solutions[i], active, rPair)
+			tradeScores[i] = ts
 
-			// score + pair aggregation binding in one pass (no double-scoring)
-			alpha := deriveAlpha(api, c.AuctionID, c.Solutions[i].Solver.Value, c.Solutions[i].SolutionID.Value)
-			sc, lhsAlpha := computeSolutionScore(api, cmps, rc, &c.Solutions[i], active, alpha, rPair)
+			// every trade is scored at the auction's price of its buy token
+			assertTradePrices(api, cmps, &c.Solutions[i], active, tokenTable, priceTable, c.PricesLen)
 			totalScores[i] = sc
+			if c.UniformPrices {
+				valid = api.Mul(valid, api.IsZero(uniformPriceViolations(api, cmps, rc, &c.Solutions[i], active)))
+			}
+			validSols[i] = valid
 			// Enforce total score fits in the Score comparator bound (128 bits).
 			rc.Check(api.Select(active, totalScores[i], 0), 128)
 
-			// bind PairScore[] to trades via RHS alpha identity (no scoring here)
-			enforcePairAggregationRHS(api, cmps, rc, &c.Solutions[i], active, alpha, lhsAlpha)
+			enforcePairSlots(api, cmps, rc, &c.Solutions[i], active)
 
 			// leaf commitment (binds dataset; does NOT include score)
 			leaf := hashSolutionLeaf(api, &c.Solutions[i], tradesCommit, active)
@@ -199,34 +261,86 @@ func (c *Circuit) Define(api frontend.API) error {
 		}
 	}
 
+	// Leaves are sorted by commit, so the root only depends on the set of
+	// solutions. Strict order also makes the commits distinct, which turns the
+	// multiset check below into a permutation check.
+	for i := 0; i+1 < NMax; i++ {
+		both := api.Mul(
+			isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen),
+			isLessThanConst(api, cmps.LenSol, i+1, c.SolutionsLen),
+		)
+		ltCommit := api.IsZero(api.Add(api.Cmp(commits[i], commits[i+1]), 1))
+		api.AssertIsEqual(api.Mul(both, api.Sub(1, ltCommit)), 0)
+	}
+
+	// bind PairScore[] to the trade scores
+	assertPairAggregation(api, c.Solutions[:], tradeScores)
+
 	// Rebuild Merkle root from leaves
-	root := merkleRootMimc(api, leaves, TreeDepth)
+	hTree, _ := mimc.NewMiMC(api)
+	solutionsRoot := merkle.RootFromLeaves(api, &hTree, leaves)
+
+	// Enforce bidset_root matches: it binds the auction, the solutions and the
+	// price vector. AuctionID only enters the proof here, and through the root
+	// in rPair, so a proof does not verify for another auction.
+	hRoot, _ := mimc.NewMiMC(api)
+	hRoot.Write(999003, c.AuctionID, solutionsRoot, pricesCommit)
+	api.AssertIsEqual(hRoot.Sum(), c.BidsetRoot)
+
+	// ByScore is Solutions reordered: everything below reads the solutions from it
+	committed := make([]Packed, NMax)
+	for i := 0; i < NMax; i++ {
+		committed[i] = Packed{
+			SolutionID:   c.Solutions[i].SolutionID.Value,
+			Solver:       c.Solutions[i].Solver.Value,
+			Score:        totalScores[i],
+			Commit:       commits[i],
+			TradesCommit: tradesCommits[i],
+			Valid:        validSols[i],
+			PairsLen:     c.Solutions[i].PairsLen,
+			PairKey:      c.Solutions[i].PairKey,
+			PairScore:    c.Solutions[i].PairScore,
+		}
+	}
+	assertPermutation(api, cmps, committed, c.ByScore[:], c.SolutionsLen)
+	sol := c.ByScore
 
-	// Enforce bidset_root matches
-	api.AssertIsEqual(root, c.BidsetRoot)
+	// ByScore is sorted over all active solutions, not only the survivors: the
+	// reference selections of solverRewards rerun the filter on it
+	for i := 0; i+1 < NMax; i++ {
+		both := api.Mul(
+			isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen),
+			isLessThanConst(api, cmps.LenSol, i+1, c.SolutionsLen),
+		)
+
+		// score[i] >= score[i+1]
+		assertGeqIf(api, cmps.Score, sol[i].Score, sol[i+1].Score, both)
+
+		// if scores equal: commit[i] >= commit[i+1] (desc)
+		// commits are full field elements: a 254-bit range check on their difference
+		// accepts either order, so compare their canonical bit decompositions
+		eqScore := api.IsZero(api.Sub(sol[i].Score, sol[i+1].Score))
+		cond := api.Mul(both, eqScore)
+		ltCommit := api.IsZero(api.Add(api.Cmp(sol[i].Commit, sol[i+1].Commit), 1))
+		api.AssertIsEqual(api.Mul(cond, ltCommit), 0)
+	}
 
 	// baseline filter
 	// baseline[pairKey] = max score among single-pair solutions for that pair
-	baseKey := make([]frontend.Variable, NMax)
-	baseScore := make([]frontend.Variable, NMax)
-	baseUsed := make([]frontend.Variable, NMax)
-	for j := 0; j < NMax; j++ {
-		baseKey[j] = 0
-		baseScore[j] = 0
-		baseUsed[j] = 0
-	}
+	base := newBaselineTable(c.SolverRewards)
 
 	for i := 0; i < NMax; i++ {
 		active := isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen)
 
 		// isSingle := (PairsLen == 1)
-		isSingle := api.IsZero(api.Sub(c.Solutions[i].PairsLen, 1))
-		use := api.Mul(active, isSingle)
+		isSingle := api.IsZero(api.Sub(sol[i].PairsLen, 1))
+		// solutions with an invalid trade are discarded, they set no baseline either
+		use := api.Mul(api.Mul(active, sol[i].Valid), isSingle)
 
 		// baseline_update(pairKey, pairScore) for single-pair solutions
-		key := c.Solutions[i].PairKey[0]
-		sc := c.Solutions[i].PairScore[0]
-		baselineUpdate(api, cmps, rc, baseKey, baseScore, baseUsed, key, sc, use)
+		key := sol[i].PairKey[0]
+		sc := sol[i].PairScore[0]
+		baselineUpdate(api, rc, base, key, sc, sol[i].Solver, use)
 	}
 
 	// survives[i] = 1 if:
@@ -238,15 +352,15 @@ func (c *Circuit) Define(api frontend.API) error {
 	for i := 0; i < NMax; i++ {
 		active := isLessThanConst(api, cmps.LenSol, i, c.SolutionsLen)
 
-		isSingle := api.IsZero(api.Sub(c.Solutions[i].PairsLen, 1))
+		isSingle := api.IsZero(api.Sub(sol[i].PairsLen, 1))
 		isMulti := api.Sub(1, isSingle)
 
 		passMulti := frontend.Variable(1)
 		for p := 0; p < PairMax; p++ {
-			pa := api.Mul(active, isLessThanConst(api, cmps.LenPair, p, c.Solutions[i].PairsLen))
-			key := c.Solutions[i].PairKey[p]
-			sc := c.Solutions[i].PairScore[p]
-			b := baselineGet(api, baseKey, baseScore, baseUsed, key)
+			pa := api.Mul(active, isLessThanConst(api, cmps.LenPair, p, sol[i].PairsLen))
+			key := sol[i].PairKey[p]
+			sc := sol[i].PairScore[p]
+			b := baselineGet(api, base, key)
 
 			cond := api.Mul(pa, isMulti) // check applies only for active buckets of multi-pair sols
 
@@ -260,9 +374,9 @@ func (c *Circuit) Define(api frontend.API) error {
 
 		// survive = active * (isSingle OR (isMulti AND passMulti))
 		survive := api.Add(isSingle, api.Mul(isMulti, passMulti))
-		survives[i] = api.Mul(active, survive)
+		survives[i] = api.Mul(api.Mul(active, sol[i].Valid), survive)
 		alive[i] = survives[i]
-		assertBoolIf(api, alive[i], 1)
+		gadgets.AssertBoolIf(api, alive[i], 1)
 	}
 
 	var packed [NMax]Packed
@@ -272,6 +386,8 @@ func (c *Circuit) Define(api frontend.API) error {
 		packed[s].Solver = 0
 		packed[s].Score = 0
 		packed[s].Commit = 0
+		packed[s].TradesCommit = 0
+		packed[s].Valid = 1 // survivors are valid
 		packed[s].PairsLen = 0
 		for p := 0; p < PairMax; p++ {
 			packed[s].PairKey[p] = 0
@@ -294,50 +410,188 @@ func (c *Circuit) Define(api frontend.API) error {
 			isSlot := api.IsZero(api.Sub(rank[i], sIdx))
 			write := api.Mul(alive[i], isSlot)
 
-			packed[sIdx].SolutionID = api.Select(write, c.Solutions[i].SolutionID.Value, packed[sIdx].SolutionID)
-			packed[sIdx].Solver = api.Select(write, c.Solutions[i].Solver.Value, packed[sIdx].Solver)
-			packed[sIdx].Score = api.Select(write, totalScores[i], packed[sIdx].Score)
-			packed[sIdx].Commit = api.Select(write, commits[i], packed[sIdx].Commit)
-			packed[sIdx].PairsLen = api.Select(write, c.Solutions[i].PairsLen, packed[sIdx].PairsLen)
+			packed[sIdx].SolutionID = api.Select(write, sol[i].SolutionID, packed[sIdx].SolutionID)
+			packed[sIdx].Solver = api.Select(write, sol[i].Solver, packed[sIdx].Solver)
+			packed[sIdx].Score = api.Select(write, sol[i].Score, packed[sIdx].Score)
+			packed[sIdx].Commit = api.Select(write, sol[i].Commit, packed[sIdx].Commit)
+			packed[sIdx].TradesCommit = api.Select(write, sol[i].TradesCommit, packed[sIdx].TradesCommit)
+			packed[sIdx].PairsLen = api.Select(write, sol[i].PairsLen, packed[sIdx].PairsLen)
 
 			for p := 0; p < PairMax; p++ {
-				packed[sIdx].PairKey[p] = api.Select(write, c.Solutions[i].PairKey[p], packed[sIdx].PairKey[p])
-				packed[sIdx].PairScore[p] = api.Select(write, c.Solutions[i].PairScore[p], packed[sIdx].PairScore[p])
+				packed[sIdx].PairKey[p] = api.Select(write, sol[i].PairKey[p], packed[sIdx].PairKey[p])
+				packed[sIdx].PairScore[p] = api.Select(write, sol[i].PairScore[p], packed[sIdx].PairScore[p])
 			}
 		}
 	}
 
-	for i := 0; i+1 < NMax; i++ {
-		both := api.Mul(
-			isLessThanConst(api, cmps.LenSol, i, aliveLen),
-			isLessThanConst(api, cmps.LenSol, i+1, aliveLen),
-		)
-
-		// score[i] >= score[i+1]
-		assertGeqIf(api, cmps.Score, packed[i].Score, packed[i+1].Score, both)
-
-		// if scores equal: commit[i] >= commit[i+1] (desc)
-		eqScore := api.IsZero(api.Sub(packed[i].Score, packed[i+1].Score))
-		cond := api.Mul(both, eqScore)
-		ltCommit := hintIsLessStrict(api, rc, packed[i].Commit, packed[i+1].Commit, 254, cond)
-		api.AssertIsEqual(api.Mul(cond, ltCommit), 0)
-	}
-
+	// the survivors keep the order of ByScore
 	// Greedy select winners with disjoint directed pairs across solutions
 	computed := greedySelectWinners(api, cmps, packed, aliveLen)
 
+	var reference, reward [WMax]frontend.Variable
+	if c.SolverRewards {
+		reference, reward = solverRewards(api, cmps, rc, sol, c.SolutionsLen, base, computed)
+	} else {
+		for w := 0; w < WMax; w++ {
+			reference[w], reward[w] = 0, 0
+		}
+	}
+
 	// Compare computed winners to public Winners (up to WinnersLen)
 	for w := 0; w < WMax; w++ {
 		activeW := isLessThanConst(api, cmps.LenWin, w, c.WinnersLen)
 		api.AssertIsEqual(api.Mul(activeW, api.Sub(computed[w].SolutionID, c.Winners[w].SolutionID)), 0)
 		api.AssertIsEqual(api.Mul(activeW, api.Sub(computed[w].Solver, c.Winners[w].Solver)), 0)
 		api.AssertIsEqual(api.Mul(activeW, api.Sub(computed[w].Score, c.Winners[w].Score)), 0)
+		api.AssertIsEqual(api.Mul(activeW, api.Sub(computed[w].TradesCommit, c.Winners[w].TradesCommit)), 0)
+		api.AssertIsEqual(api.Mul(activeW, api.Sub(reference[w], c.Winners[w].Reference)), 0)
+		api.AssertIsEqual(api.Mul(activeW, api.Sub(reward[w], c.Winners[w].Reward)), 0)
 
 	}
 
 	return nil
 }
 
+// assertPermutation enforces that the active entries of b (the first n) are a
+// reordering of those of a, given that they are distinct in a. Each entry is
+// fingerprinted as Σ field_j·β^j and the two products Π(γ - fingerprint) must
+// agree; β and γ come from a commitment to both sides, so they are fixed only
+// after the prover has chosen b.
+func assertPermutation(api frontend.API, cmps Comparators, a, b []Packed, n frontend.Variable) {
+	fields := func(p *Packed) []frontend.Variable {
+		out := []frontend.Variable{p.SolutionID, p.Solver, p.Score, p.Commit, p.TradesCommit, p.Valid, p.PairsLen}
+		out = append(out, p.PairKey[:]...)
+		return append(out, p.PairScore[:]...)
+	}
+
+	var committed []frontend.Variable
+	for i := range a {
+		committed = append(committed, fields(&a[i])...)
+		committed = append(committed, fields(&b[i])...)
+	}
+
+	multicommit.WithCommitment(api, func(api frontend.API, gamma frontend.Variable) error {
+		h, _ := mimc.NewMiMC(api)
+		h.Write(gamma, 0xA11CE004)
+		beta := h.Sum()
+
+		fingerprint := func(p *Packed) frontend.Variable {
+			acc := frontend.Variable(0)
+			pow := frontend.Variable(1)
+			for _, f := range fields(p) {
+				acc = api.Add(acc, api.Mul(f, pow))
+				pow = api.Mul(pow, beta)
+			}
+			return acc
+		}
+
+		prodA, prodB := frontend.Variable(1), frontend.Variable(1)
+		for i := range a {
+			active := isLessThanConst(api, cmps.LenSol, i, n)
+			prodA = api.Mul(prodA, api.Select(active, api.Sub(gamma, fingerprint(&a[i])), 1))
+			prodB = api.Mul(prodB, api.Select(active, api.Sub(gamma, fingerprint(&b[i])), 1))
+		}
+		api.AssertIsEqual(prodA, prodB)
+		return nil
+	}, committed...)
+}
+
+// uniformPriceViolations counts the pairs of active trades of s in the same
+// PairKey bucket whose executed ratios differ by more than rounding:
+//
+//	|sell_a*buy_b - sell_b*buy_a| <= sell_a + sell_b + buy_a + buy_b
+//
+// i.e. one unit of rounding on each executed amount. Executed amounts are
+// below 2^AMT_BITS, so the difference is shifted by 2^(2*AMT_BITS+1) to
+// compare it without wrapping.
+func uniformPriceViolations(api frontend.API, cmps Comparators, rc frontend.Rangechecker, s *Solution, active frontend.Variable) frontend.Variable {
+	shift := pow2(2*AMT_BITS + 1)
+	bits := 2*AMT_BITS + 3
+
+	ta := make([]frontend.Variable, TMax)
+	for t := 0; t < TMax; t++ {
+		ta[t] = api.Mul(active, isLessThanConst(api, cmps.LenTr, t, s.TradesLen))
+	}
+
+	violations := frontend.Variable(0)
+	for a := 0; a < TMax; a++ {
+		for b := a + 1; b < TMax; b++ {
+			x, y := &s.Trades[a], &s.Trades[b]
+			samePair := api.IsZero(api.Sub(s.TradePairIdx[a], s.TradePairIdx[b]))
+			cond := api.Mul(api.Mul(ta[a], ta[b]), samePair)
+
+			diff := api.Sub(api.Mul(x.ExecutedSell, y.ExecutedBuy), api.Mul(y.ExecutedSell, x.ExecutedBuy))
+			shifted := api.Add(diff, shift)
+			tol := api.Add(x.ExecutedSell, y.ExecutedSell, x.ExecutedBuy, y.ExecutedBuy)
+
+			below := gadgets.IsLessIf(api, rc, shifted, api.Sub(shift, tol), bits, cond)
+			above := gadgets.IsLessIf(api, rc, api.Add(shift, tol), shifted, bits, cond)
+			violations = api.Add(violations, below, above)
+		}
+	}
+	return violations
+}
+
+// commitPrices checks the price vector and loads it into lookup tables. Tokens
+// are addresses, strictly ascending so that the commitment
+// H(DOMAIN_PRICES || len || token_0 || price_0 || ...) is canonical; unused
+// entries are 0.
+func commitPrices(
+	api frontend.API,
+	cmps Comparators,
+	rc frontend.Rangechecker,
+	prices []Price,
+	n frontend.Variable,
+) (tokens, values logderivlookup.Table, commit frontend.Variable) {
+	tokens = logderivlookup.New(api)
+	values = logderivlookup.New(api)
+	h, _ := mimc.NewMiMC(api)
+	h.Write(999002, n)
+
+	for k := range prices {
+		active := isLessThanConst(api, cmps.LenPrice, k, n)
+		api.AssertIsEqual(api.Mul(api.Sub(1, active), prices[k].Token), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, active), prices[k].PriceE18), 0)
+		rc.Check(prices[k].Token, 160)
+		rc.Check(prices[k].PriceE18, PRICE_BITS)
+
+		if k+1 < len(prices) {
+			both := api.Mul(active, isLessThanConst(api, cmps.LenPrice, k+1, n))
+			lt := gadgets.IsLessIf(api, rc, prices[k].Token, prices[k+1].Token, 160, both)
+			api.AssertIsEqual(lt, both)
+		}
+
+		tokens.Insert(prices[k].Token)
+		values.Insert(prices[k].PriceE18)
+		h.Write(prices[k].Token, prices[k].PriceE18)
+	}
+	return tokens, values, h.Sum()
+}
+
+// assertTradePrices enforces NativePriceBuy == price of BuyToken in the price
+// vector for every active trade of s. Inactive trades look up entry 0.
+func assertTradePrices(
+	api frontend.API,
+	cmps Comparators,
+	s *Solution,
+	active frontend.Variable,
+	tokens, values logderivlookup.Table,
+	n frontend.Variable,
+) {
+	for t := 0; t < TMax; t++ {
+		ta := api.Mul(active, isLessThanConst(api, cmps.LenTr, t, s.TradesLen))
+		idx := api.Mul(ta, s.TradePriceIdx[t])
+
+		inRange := cmps.LenPrice.IsLess(idx, n)
+		api.AssertIsEqual(api.Mul(ta, api.Sub(1, inRange)), 0)
+
+		token := tokens.Lookup(idx)[0]
+		price := values.Lookup(idx)[0]
+		api.AssertIsEqual(api.Mul(ta, api.Sub(token, s.Trades[t].BuyToken.Value)), 0)
+		api.AssertIsEqual(api.Mul(ta, api.Sub(price, s.Trades[t].NativePriceBuy)), 0)
+	}
+}
+
 func hashSolutionLeaf(
 	api frontend.API,
 	s *Solution,
@@ -348,84 +602,68 @@ func hashSolutionLeaf(
 	return api.Select(active, h.Sum(), 0)
 }
 
-// ideally it should also have some randomness after bidset_root is fixed to prevent malleability when solver chooses the witnesses.
-func deriveChallengeR(api frontend.API, auctionID frontend.Variable) frontend.Variable {
+// hashTrades hashes a solution's trades, 7 field elements per slot (0 for an
+// inactive one):
+//
+//	id, sell_token | flags << 160, buy_token, native_price_buy,
+//	sell_amount | buy_amount << AMT_BITS, executed_sell | executed_buy << AMT_BITS,
+//	quote_sell | quote_buy << AMT_BITS
+//
+// with flags = side | partially_fillable << 1 | fee_kind << 2 | fee_factor << 4
+// | fee_cap << (4+FEE_BITS). Every packed value is range checked, so the packing
+// is injective. It feeds the bidset leaf, which the autopilot computes before
+// any proof: a hash is needed, as a random-evaluation commitment would use a
+// challenge known to the prover before it chooses the witness.
+func hashTrades(api frontend.API, cmps Comparators, rc frontend.Rangechecker, s *Solution, active frontend.Variable) frontend.Variable {
 	h, _ := mimc.NewMiMC(api)
-	h.Write(auctionID, 0xA11CE001)
-	r := h.Sum()
-	return r
-}
-
-func merkleRootMimc(api frontend.API, leaves []frontend.Variable, depth int) frontend.Variable {
-	nodes := make([]frontend.Variable, len(leaves))
-	copy(nodes, leaves)
+	shift := pow2(AMT_BITS)
+	for t := 0; t < TMax; t++ {
+		ta := api.Mul(active, isLessThanConst(api, cmps.LenTr, t, s.TradesLen))
+		tr := s.Trades[t]
 
-	for level := 0; level < depth; level++ {
-		next := make([]frontend.Variable, len(nodes)/2)
-		for i := 0; i < len(next); i++ {
-			h, _ := mimc.NewMiMC(api)
-			h.Write(nodes[2*i], nodes[2*i+1])
-			next[i] = h.Sum()
+		// the other fields are 0 in inactive slots, see computeSolutionScore
+		sellToken := api.Select(ta, tr.SellToken.Value, 0)
+		buyToken := api.Select(ta, tr.BuyToken.Value, 0)
+		rc.Check(sellToken, 160)
+		rc.Check(buyToken, 160)
+		for _, a := range []frontend.Variable{tr.SellAmount, tr.BuyAmount, tr.QuoteSell, tr.QuoteBuy} {
+			rc.Check(a, AMT_BITS)
 		}
-		nodes = next
+		flags := api.Add(
+			tr.Side,
+			api.Mul(tr.PartiallyFillable, 2),
+			api.Mul(tr.FeeKind, 4),
+			api.Mul(tr.FeeFactor, 16),
+			api.Mul(tr.FeeCap, pow2(4+FEE_BITS)),
+		)
+
+		h.Write(
+			api.Select(ta, tr.ID, 0),
+			api.Add(sellToken, api.Mul(flags, pow2(160))),
+			buyToken,
+			tr.NativePriceBuy,
+			api.Add(tr.SellAmount, api.Mul(tr.BuyAmount, shift)),
+			api.Add(tr.ExecutedSell, api.Mul(tr.ExecutedBuy, shift)),
+			api.Add(tr.QuoteSell, api.Mul(tr.QuoteBuy, shift)),
+		)
 	}
-	return nodes[0]
+	return api.Select(active, h.Sum(), 0)
 }
 
-func computeTradesCommit(api frontend.API, cmps Comparators, s *Solution, active, r frontend.Variable) frontend.Variable {
-	acc := frontend.Variable(0)
-	pow := frontend.Variable(1)
-
-	for t := 0; t < TMax; t++ {
-		ta := api.Mul(active, isLessThanConst(api, cmps.LenTr, t, s.TradesLen))
-		tr := s.Trades[t]
-
-		// gated fields (0 when trade inactive)
-		f0 := api.Select(ta, tr.ID, 0)
-		f1 := api.Select(ta, tr.SellToken.Value, 0)
-		f2 := api.Select(ta, tr.BuyToken.Value, 0)
-		f3 := api.Select(ta, tr.SellAmount, 0)
-		f4 := api.Select(ta, tr.BuyAmount, 0)
-		f5 := api.Select(ta, tr.ExecutedSell, 0)
-		f6 := api.Select(ta, tr.ExecutedBuy, 0)
-		f7 := api.Select(ta, tr.Side, 0)
-		f8 := api.Select(ta, tr.NativePriceBuy, 0)
-
-		// acc += f_k * pow && pow *= r  (fixed order)
-		acc = api.Add(acc, api.Mul(f0, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f1, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f2, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f3, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f4, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f5, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f6, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f7, pow))
-		pow = api.Mul(pow, r)
-		acc = api.Add(acc, api.Mul(f8, pow))
-		pow = api.Mul(pow, r)
-	}
-
-	return api.Select(active, acc, 0)
-}
-
-func derivePairChallenge(api frontend.API, auctionID frontend.Variable) frontend.Variable {
+// derivePairChallenge compresses directed pairs into keys. The bidset root fixes
+// every token before it is drawn.
+func derivePairChallenge(api frontend.API, bidsetRoot frontend.Variable) frontend.Variable {
 	h, _ := mimc.NewMiMC(api)
-	// domain separator so it's not the same as the trades-commit challenge
-	h.Write(auctionID, 0xA11CE002)
+	h.Write(bidsetRoot, 0xA11CE002)
 	return h.Sum()
 }
 
-func deriveAlpha(api frontend.API, auctionID, solver, solutionID frontend.Variable) frontend.Variable {
+// deriveAlpha binds the pair scores, see assertPairAggregation. commitment is a
+// gnark commitment to them, as the prover chooses them after the bidset root.
+func deriveAlpha(api frontend.API, commitment frontend.Variable) frontend.Variable {
 	h, _ := mimc.NewMiMC(api)
-	// domain separator distinct from r/rPair
-	h.Write(auctionID, 0xA11CE003, solver, solutionID)
+	// domain separator distinct from rPair and the permutation challenge
+	h.Write(commitment, 0xA11CE003)
 	return h.Sum()
 }
 
@@ -435,18 +673,11 @@ func computeSolutionScore(
 	rc frontend.Rangechecker,
 	s *Solution,
 	active frontend.Variable,
-	alpha frontend.Variable,
 	rPair frontend.Variable,
-) (total frontend.Variable, lhsAlpha frontend.Variable) {
+) (total frontend.Variable, tradeScores [TMax]frontend.Variable, valid frontend.Variable) {
 	total = frontend.Variable(0)
-	lhsAlpha = frontend.Variable(0)
-
-	// precompute alpha^k for k in [0..PairMax-1]
-	alphaPow := make([]frontend.Variable, PairMax)
-	alphaPow[0] = 1
-	for k := 1; k < PairMax; k++ {
-		alphaPow[k] = api.Mul(alphaPow[k-1], alpha)
-	}
+	violations := frontend.Variable(0)
+	prevID, prevTa := frontend.Variable(0), frontend.Variable(0)
 
 	for t := 0; t < TMax; t++ {
 		ta := api.Mul(active, isLessThanConst(api, cmps.LenTr, t, s.TradesLen))
@@ -459,9 +690,31 @@ func computeSolutionScore(
 		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.ExecutedBuy), 0)
 		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.NativePriceBuy), 0)
 		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.Side), 0)
-
-		// side boolean
-		assertBoolIf(api, tr.Side, ta)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.FeeKind), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.FeeFactor), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.FeeCap), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.QuoteSell), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.QuoteBuy), 0)
+		api.AssertIsEqual(api.Mul(api.Sub(1, ta), tr.PartiallyFillable), 0)
+
+		// Trades are sorted by ID, which fixes the trades hash of a set of
+		// trades. A repeated order is not rejected outright: it invalidates the
+		// solution, like any other trade that does not respect its order.
+		id := api.Select(ta, tr.ID, 0)
+		rc.Check(id, TRADE_ID_BITS)
+		both := api.Mul(prevTa, ta)
+		descending := gadgets.IsLessIf(api, rc, id, prevID, TRADE_ID_BITS, both)
+		api.AssertIsEqual(descending, 0)
+		repeated := api.Mul(both, api.IsZero(api.Sub(id, prevID)))
+		prevID, prevTa = id, ta
+
+		// side and fill flag boolean
+		gadgets.AssertBoolIf(api, tr.Side, ta)
+		gadgets.AssertBoolIf(api, tr.PartiallyFillable, ta)
+
+		// executed amounts are not divisors, nothing else bounds them
+		rc.Check(tr.ExecutedSell, AMT_BITS)
+		rc.Check(tr.ExecutedBuy, AMT_BITS)
 
 		pi := s.TradePairIdx[t]
 		piOK := cmps.LenPair.IsLess(pi, s.PairsLen)
@@ -469,12 +722,14 @@ func computeSolutionScore(
 
 		// expectedKey = sell + rPair*buy
 		expKey := api.Add(tr.SellToken.Value, api.Mul(rPair, tr.BuyToken.Value))
-		enforceKeyMatchBySelector(api, s.PairKey[:], pi, expKey, ta)
+		gadgets.AssertKeyAtIf(api, s.PairKey[:], pi, expKey, ta)
 
 		// Guard divisors against 0 for inactive trades
 		safeLimSell := api.Select(ta, tr.SellAmount, 1)
 		safeLimBuy := api.Select(ta, tr.BuyAmount, 1)
 
+		// A trade is valid if it respects its limit price and fill constraints.
+		//
 		// Sell side:
 		// partial_limit_buy = ceil(limit_buy * executed_sell / limit_sell)
 		// score_native = floor((executed_buy - partial_limit_buy) * native_price_buy / 1e18) if executed_buy > partial_limit_buy
@@ -485,100 +740,176 @@ func computeSolutionScore(
 		// surplus_buy_equiv = floor(surplus_sell * limit_buy / limit_sell)
 		// score_native = floor(surplus_buy_equiv * native_price_buy / 1e18)
 
-		limBuy_mul_exSell := api.Mul(tr.BuyAmount, tr.ExecutedSell)
-		limSell_mul_exBuy := api.Mul(tr.SellAmount, tr.ExecutedBuy)
+		sellCond := api.Mul(ta, api.Sub(1, tr.Side))
+		buyCond := api.Mul(ta, tr.Side)
 
-		partialLimitBuy := divCeil(api, rc, limBuy_mul_exSell, safeLimSell, AMT_BITS*2+1, AMT_BITS, AMT_BITS+1)
-		partialLimitSell := divFloor(api, rc, limSell_mul_exBuy, safeLimBuy, AMT_BITS*2, AMT_BITS, AMT_BITS+1)
+		// fill: the order's side amount (sell amount of a sell order, buy amount
+		// of a buy order) is not exceeded, and is executed exactly if fill-or-kill
+		filled := api.Select(tr.Side, tr.ExecutedBuy, tr.ExecutedSell)
+		amount := api.Select(tr.Side, tr.BuyAmount, tr.SellAmount)
+		overFill := gadgets.IsLessIf(api, rc, amount, filled, AMT_BITS+2, ta)
+		partial := api.Mul(api.Mul(ta, api.Sub(1, tr.PartiallyFillable)), api.Sub(1, api.IsZero(api.Sub(amount, filled))))
 
-		sellPos := hintIsLessStrict(api, rc, partialLimitBuy, tr.ExecutedBuy, AMT_BITS+2, ta)  // 1 iff execBuy > partial
-		buyPos := hintIsLessStrict(api, rc, tr.ExecutedSell, partialLimitSell, AMT_BITS+2, ta) // 1 iff partial > execSell
+		// Each partial limit is only needed for its side. Over-filled trades are
+		// clamped to the order amount, which keeps the quotients below 2^AMT_BITS
+		// so that invalid solutions in the bidset can still be proven invalid.
+		clamped := api.Select(overFill, amount, filled)
+		limBuy_mul_exSell := api.Mul(sellCond, api.Mul(tr.BuyAmount, clamped))
+		limSell_mul_exBuy := api.Mul(buyCond, api.Mul(tr.SellAmount, clamped))
 
-		// Sell: executed_buy > partialLimitBuy
-		// Buy:  partialLimitSell > executed_sell
-		sellCond := api.Mul(ta, api.Sub(1, tr.Side))
-		buyCond := api.Mul(ta, tr.Side)
+		partialLimitBuy := gadgets.DivCeil(api, rc, limBuy_mul_exSell, safeLimSell, AMT_BITS, AMT_BITS+1)
+		partialLimitSell := gadgets.DivFloor(api, rc, limSell_mul_exBuy, safeLimBuy, AMT_BITS, AMT_BITS+1)
+
+		// limit price: executed_buy >= partialLimitBuy, partialLimitSell >= executed_sell
+		sellBelowLimit := gadgets.IsLessIf(api, rc, tr.ExecutedBuy, partialLimitBuy, AMT_BITS+2, sellCond)
+		buyAboveLimit := gadgets.IsLessIf(api, rc, partialLimitSell, tr.ExecutedSell, AMT_BITS+2, buyCond)
+
+		tradeViolations := api.Add(sellBelowLimit, buyAboveLimit, overFill, partial, repeated)
+		violations = api.Add(violations, tradeViolations)
 
 		rawSurplusBuySell := api.Sub(tr.ExecutedBuy, partialLimitBuy) // may be 0 or wrap
 		rawSurplusSellBuy := api.Sub(partialLimitSell, tr.ExecutedSell)
 
-		surplusBuySell := api.Mul(rawSurplusBuySell, api.Mul(sellCond, sellPos)) // only nonzero for active sell trades with execBuy > partial
-		surplusSellBuy := api.Mul(rawSurplusSellBuy, api.Mul(buyCond, buyPos))
+		// 0 when the limit price is violated
+		surplusBuySell := api.Mul(rawSurplusBuySell, api.Mul(sellCond, api.Sub(1, sellBelowLimit)))
+		surplusSellBuy := api.Mul(rawSurplusSellBuy, api.Mul(buyCond, api.Sub(1, buyAboveLimit)))
 
-		// Buy side conversion: surplusBuyEquiv = floor(surplusSell * limitBuy / limitSell)
-		// When buyPos=0, surplusSellBuy=0 so this is forced to 0 cleanly.
-		surplusSell_mul_limBuy := api.Mul(surplusSellBuy, tr.BuyAmount)
-		surplusBuyEquiv := divFloor(api, rc, surplusSell_mul_limBuy, safeLimSell, AMT_BITS*2+1, AMT_BITS, AMT_BITS+1)
+		// protocol fee in the surplus token (buy token for sell orders, sell token for buy orders)
+		fee := computeProtocolFee(api, rc, &tr, ta, clamped, api.Add(surplusBuySell, surplusSellBuy))
+		feeSell := api.Mul(sellCond, fee)
+		// executed_sell <= partialLimitSell <= limit_sell bounds the conversion below
+		feeBuy := api.Mul(api.Mul(buyCond, api.Sub(1, buyAboveLimit)), fee)
 
-		// ensure surplusBuyEquiv is 0 unless (active buy trade AND buyPos)
-		surplusBuyEquiv = api.Mul(surplusBuyEquiv, api.Mul(buyCond, buyPos))
+		// Buy side conversion: surplusBuyEquiv = floor((surplusSell + fee) * limitBuy / limitSell)
+		// Zero for sell trades, whose surplusSellBuy and feeBuy are 0.
+		surplusSell_mul_limBuy := api.Mul(api.Add(surplusSellBuy, feeBuy), tr.BuyAmount)
+		surplusBuyEquiv := gadgets.DivFloor(api, rc, surplusSell_mul_limBuy, safeLimSell, AMT_BITS, AMT_BITS+2)
 
-		surplusInBuyToken := api.Add(surplusBuySell, surplusBuyEquiv)
+		surplusInBuyToken := api.Add(surplusBuySell, feeSell, surplusBuyEquiv)
 
+		// surplus + fee < 2^(AMT_BITS+FEE_BITS+2) and 1e18 >= 2^59, which bounds the quotient
 		surplus_mul_price := api.Mul(surplusInBuyToken, tr.NativePriceBuy)
-		scoreNative := divFloor(api, rc, surplus_mul_price, frontend.Variable(ONE_E18), AMT_BITS*2+PRICE_BITS, 64, AMT_BITS+PRICE_BITS)
+		scoreNative := gadgets.DivFloor(api, rc, surplus_mul_price, frontend.Variable(ONE_E18), 60, AMT_BITS+FEE_BITS+2+PRICE_BITS-59)
 
-		scoreT := api.Mul(ta, scoreNative)
+		// an invalid trade scores 0 and invalidates its solution
+		scoreT := api.Mul(api.Mul(ta, api.IsZero(tradeViolations)), scoreNative)
 		total = api.Add(total, scoreT)
-
-		// lhsAlpha += scoreT * alpha^{pairIdx}
-		alphaAt := selectFromSmallArray(api, alphaPow, pi)
-		lhsAlpha = api.Add(lhsAlpha, api.Mul(scoreT, alphaAt))
+		tradeScores[t] = scoreT
 	}
 
-	return api.Select(active, total, 0), api.Select(active, lhsAlpha, 0)
+	// at most 5 per trade, so the sum cannot wrap
+	valid = api.Mul(active, api.IsZero(violations))
+	return api.Select(active, total, 0), tradeScores, valid
 }
 
-func enforcePairAggregationRHS(
-	api frontend.API,
-	cmps Comparators,
-	rc frontend.Rangechecker,
-	s *Solution,
-	active frontend.Variable,
-	alpha frontend.Variable,
-	lhsAlpha frontend.Variable,
-) {
-	// precompute alpha^k
-	alphaPow := make([]frontend.Variable, PairMax)
-	alphaPow[0] = 1
-	for k := 1; k < PairMax; k++ {
-		alphaPow[k] = api.Mul(alphaPow[k-1], alpha)
+// computeProtocolFee returns the autopilot's protocol fee of an active trade, in
+// its surplus token. Executed amounts already have the fee taken out, so a factor
+// f of the raw surplus is f/(1-f) of the surplus left to the user:
+//
+//	surplus:           min(surplus * f/(1-f), volume * cap/(1∓cap))
+//	price improvement: min(improvement * f/(1-f), volume * cap/(1∓cap))
+//	volume:            volume * f/(1∓f)
+//
+// volume is the executed buy amount of a sell order (∓ is -) and the executed
+// sell amount of a buy order (∓ is +); improvement is the surplus over the quote
+// instead of the limit price. Factors are parts per FEE_ONE. filled is the
+// executed amount on the order's side, clamped to the order amount.
+func computeProtocolFee(api frontend.API, rc frontend.Rangechecker, tr *Trade, ta, filled, surplus frontend.Variable) frontend.Variable {
+	isNone := api.IsZero(tr.FeeKind)
+	isSurplus := api.IsZero(api.Sub(tr.FeeKind, FeeSurplus))
+	isVolume := api.IsZero(api.Sub(tr.FeeKind, FeeVolume))
+	isPI := api.IsZero(api.Sub(tr.FeeKind, FeePriceImprovement))
+	api.AssertIsEqual(api.Add(isNone, isSurplus, isVolume, isPI), 1)
+
+	// factors in [0, FEE_ONE), 0 without a policy
+	for _, f := range []frontend.Variable{tr.FeeFactor, tr.FeeCap} {
+		rc.Check(f, FEE_BITS)
+		rc.Check(api.Sub(FEE_ONE-1, f), FEE_BITS)
+		api.AssertIsEqual(api.Mul(isNone, f), 0)
 	}
 
-	rhs := frontend.Variable(0)
+	// price improvement: the limit-price formulas on the quoted amounts
+	//   sell: improvement = executed_buy - ceil(quote_buy * executed_sell / quote_sell)
+	//   buy:  improvement = floor(quote_sell * executed_buy / quote_buy) - executed_sell
+	// The quotient uses filled, so an over-filled trade (already invalid) cannot
+	// push it past AMT_BITS+1 and make the auction unprovable.
+	usePI := api.Mul(ta, isPI)
+	api.AssertIsEqual(api.Mul(api.Sub(1, usePI), tr.QuoteSell), 0)
+	api.AssertIsEqual(api.Mul(api.Sub(1, usePI), tr.QuoteBuy), 0)
+	piNum := api.Select(tr.Side,
+		api.Mul(tr.QuoteSell, filled),
+		api.Add(api.Mul(tr.QuoteBuy, filled), api.Sub(tr.QuoteSell, 1)),
+	)
+	piDen := api.Select(usePI, api.Select(tr.Side, tr.QuoteBuy, tr.QuoteSell), 1)
+	partialQuote := gadgets.DivFloor(api, rc, api.Mul(usePI, piNum), piDen, AMT_BITS, AMT_BITS+1)
+	piLo := api.Select(tr.Side, tr.ExecutedSell, partialQuote)
+	piHi := api.Select(tr.Side, partialQuote, tr.ExecutedBuy)
+	piPos := gadgets.IsLessIf(api, rc, piLo, piHi, AMT_BITS+2, usePI)
+	improvement := api.Mul(piPos, api.Sub(piHi, piLo))
+
+	// surplus and improvement are below an executed amount, so below 2^(AMT_BITS+1)
+	base := api.Select(isPI, improvement, surplus)
+	surplusFee := gadgets.DivFloor(api, rc, api.Mul(base, tr.FeeFactor), api.Sub(FEE_ONE, tr.FeeFactor), FEE_BITS, AMT_BITS+1+FEE_BITS)
+
+	volume := api.Select(tr.Side, tr.ExecutedSell, tr.ExecutedBuy)
+	vf := api.Select(isVolume, tr.FeeFactor, tr.FeeCap)
+	vDen := api.Select(tr.Side, api.Add(FEE_ONE, vf), api.Sub(FEE_ONE, vf))
+	volumeFee := gadgets.DivFloor(api, rc, api.Mul(volume, vf), vDen, FEE_BITS+1, AMT_BITS+FEE_BITS)
+
+	capped := gadgets.IsLessIf(api, rc, volumeFee, surplusFee, AMT_BITS+FEE_BITS+2, ta)
+	minFee := api.Select(capped, volumeFee, surplusFee)
+	fee := api.Select(isVolume, volumeFee, api.Mul(api.Sub(1, isNone), minFee))
+	return api.Mul(ta, fee)
+}
+
+// enforcePairSlots range checks the active pair scores of s and zeroes the
+// inactive pair slots (prevents hiding junk)
+func enforcePairSlots(api frontend.API, cmps Comparators, rc frontend.Rangechecker, s *Solution, active frontend.Variable) {
 	for k := 0; k < PairMax; k++ {
 		ka := api.Mul(active, isLessThanConst(api, cmps.LenPair, k, s.PairsLen))
-		rhs = api.Add(rhs, api.Mul(ka, api.Mul(s.PairScore[k], alphaPow[k])))
 
 		// enforce PairScore is a u128 when used
 		rc.Check(api.Select(ka, s.PairScore[k], 0), 128)
 
-		// enforce unused pair slots are zero (prevents hiding junk)
 		api.AssertIsEqual(api.Mul(api.Sub(1, ka), s.PairKey[k]), 0)
 		api.AssertIsEqual(api.Mul(api.Sub(1, ka), s.PairScore[k]), 0)
 	}
-
-	api.AssertIsEqual(api.Mul(active, api.Sub(lhsAlpha, rhs)), 0)
 }
 
-// enforce PairKey[idx] == expKey under cond, without indexing
-func enforceKeyMatchBySelector(api frontend.API, keys []frontend.Variable, idx, expKey, cond frontend.Variable) {
-	// For each k: if idx==k and cond==1, then keys[k] == expKey.
-	for k := 0; k < len(keys); k++ {
-		isK := api.IsZero(api.Sub(idx, k))
-		enf := api.Mul(cond, isK)
-		api.AssertIsEqual(api.Mul(enf, api.Sub(keys[k], expKey)), 0)
+// assertPairAggregation binds each solution's PairScore to its trade scores,
+// Σ score_t·α^{pair_idx_t} == Σ pair_score_k·α^k. Inactive trades score 0 and
+// inactive pair slots are 0, see enforcePairSlots. The prover chooses PairScore
+// and TradePairIdx, so α comes from a commitment to them: with α known
+// beforehand, it could move score between buckets while keeping the identity.
+func assertPairAggregation(api frontend.API, sols []Solution, tradeScores [][TMax]frontend.Variable) {
+	var committed []frontend.Variable
+	for i := range sols {
+		committed = append(committed, sols[i].PairScore[:]...)
+		committed = append(committed, sols[i].TradePairIdx[:]...)
+		committed = append(committed, tradeScores[i][:]...)
 	}
-}
 
-// selectFromSmallArray returns arr[idx] (idx is assumed < len(arr))
-func selectFromSmallArray(api frontend.API, arr []frontend.Variable, idx frontend.Variable) frontend.Variable {
-	out := frontend.Variable(0)
-	for k := 0; k < len(arr); k++ {
-		isK := api.IsZero(api.Sub(idx, k))
-		out = api.Add(out, api.Mul(isK, arr[k]))
-	}
-	return out
+	multicommit.WithCommitment(api, func(api frontend.API, commitment frontend.Variable) error {
+		alpha := deriveAlpha(api, commitment)
+		alphaPow := make([]frontend.Variable, PairMax)
+		alphaPow[0] = 1
+		for k := 1; k < PairMax; k++ {
+			alphaPow[k] = api.Mul(alphaPow[k-1], alpha)
+		}
+
+		for i := range sols {
+			lhs, rhs := frontend.Variable(0), frontend.Variable(0)
+			for t := 0; t < TMax; t++ {
+				alphaAt := gadgets.SelectFromSmallArray(api, alphaPow, sols[i].TradePairIdx[t])
+				lhs = api.Add(lhs, api.Mul(tradeScores[i][t], alphaAt))
+			}
+			for k := 0; k < PairMax; k++ {
+				rhs = api.Add(rhs, api.Mul(sols[i].PairScore[k], alphaPow[k]))
+			}
+			api.AssertIsEqual(lhs, rhs)
+		}
+		return nil
+	}, committed...)
 }
 
 func enforceUniquePairKeys(api frontend.API, cmps Comparators, s *Solution, active frontend.Variable) {
@@ -595,53 +926,90 @@ func enforceUniquePairKeys(api frontend.API, cmps Comparators, s *Solution, acti
 	}
 }
 
+// baselineTable maps a pair key to the best pair score of a valid single-pair
+// solution on it. With solver rewards it also keeps, per key, the solver of
+// that score and the best score of the other solvers, so that the baseline
+// without any one solver is a select, see baselineWithout.
+type baselineTable struct {
+	Key, Score, Used []frontend.Variable
+	Solver, Other    []frontend.Variable // nil without solver rewards
+}
+
+func newBaselineTable(withSolvers bool) *baselineTable {
+	zeros := func() []frontend.Variable {
+		out := make([]frontend.Variable, NMax)
+		for j := range out {
+			out[j] = 0
+		}
+		return out
+	}
+	t := &baselineTable{Key: zeros(), Score: zeros(), Used: zeros()}
+	if withSolvers {
+		t.Solver, t.Other = zeros(), zeros()
+	}
+	return t
+}
+
 func baselineGet(
 	api frontend.API,
-	baseKey, baseScore, baseUsed []frontend.Variable,
+	t *baselineTable,
 	key frontend.Variable,
 ) frontend.Variable {
 	out := frontend.Variable(0)
-	for j := 0; j < len(baseKey); j++ {
-		eq := api.IsZero(api.Sub(key, baseKey[j]))
-		out = api.Add(out, api.Mul(api.Mul(baseUsed[j], eq), baseScore[j]))
+	for j := 0; j < len(t.Key); j++ {
+		eq := api.IsZero(api.Sub(key, t.Key[j]))
+		out = api.Add(out, api.Mul(api.Mul(t.Used[j], eq), t.Score[j]))
 	}
 	return out
 }
 
 func baselineUpdate(
 	api frontend.API,
-	cmps Comparators,
 	rc frontend.Rangechecker,
-	baseKey, baseScore, baseUsed []frontend.Variable,
-	key, sc, use frontend.Variable,
+	t *baselineTable,
+	key, sc, solver, use frontend.Variable,
 ) {
 	// found = OR_j (baseUsed[j]==1 && baseKey[j]==key)
 	found := frontend.Variable(0)
-	for j := 0; j < len(baseKey); j++ {
-		eq := api.IsZero(api.Sub(key, baseKey[j]))
-		found = api.Or(found, api.Mul(baseUsed[j], eq))
+	for j := 0; j < len(t.Key); j++ {
+		eq := api.IsZero(api.Sub(key, t.Key[j]))
+		found = api.Or(found, api.Mul(t.Used[j], eq))
 	}
 
 	// First pass: if found, do max-update on the matching slots
-	for j := 0; j < len(baseKey); j++ {
-		eq := api.IsZero(api.Sub(key, baseKey[j]))
-		doUpd := api.Mul(use, api.Mul(baseUsed[j], eq))
+	for j := 0; j < len(t.Key); j++ {
+		eq := api.IsZero(api.Sub(key, t.Key[j]))
+		doUpd := api.Mul(use, api.Mul(t.Used[j], eq))
 
 		// lt == 1 iff baseScore[j] < sc (strict)
-		lt := hintIsLessStrict(api, rc, baseScore[j], sc, 128, doUpd)
-		newScore := api.Select(lt, sc, baseScore[j])
-		baseScore[j] = api.Select(doUpd, newScore, baseScore[j])
+		lt := gadgets.IsLessIf(api, rc, t.Score[j], sc, 128, doUpd)
+
+		if t.Solver != nil {
+			// another solver's score either takes the lead, and the old best
+			// becomes the best of the others, or can raise the best of the others
+			other := api.Mul(doUpd, api.Sub(1, api.IsZero(api.Sub(solver, t.Solver[j]))))
+			lead := api.Mul(other, lt)
+			raise := gadgets.IsLessIf(api, rc, t.Other[j], sc, 128, api.Sub(other, lead))
+			t.Other[j] = api.Select(lead, t.Score[j], api.Select(raise, sc, t.Other[j]))
+			t.Solver[j] = api.Select(lead, solver, t.Solver[j])
+		}
+
+		newScore := api.Select(lt, sc, t.Score[j])
+		t.Score[j] = api.Select(doUpd, newScore, t.Score[j])
 	}
 
 	// Second pass: if not found, insert into first free slot
 	inserted := frontend.Variable(0)
-	for j := 0; j < len(baseKey); j++ {
-		free := api.Sub(1, baseUsed[j])
+	for j := 0; j < len(t.Key); j++ {
+		free := api.Sub(1, t.Used[j])
 		canIns := api.Mul(use, api.Mul(api.Sub(1, found), api.Mul(free, api.Sub(1, inserted))))
 
-		baseKey[j] = api.Select(canIns, key, baseKey[j])
-		baseScore[j] = api.Select(canIns, sc, baseScore[j])
-		baseUsed[j] = api.Select(canIns, frontend.Variable(1), baseUsed[j])
+		t.Key[j] = api.Select(canIns, key, t.Key[j])
+		t.Score[j] = api.Select(canIns, sc, t.Score[j])
+		t.Used[j] = api.Select(canIns, frontend.Variable(1), t.Used[j])
+		if t.Solver != nil {
+			t.Solver[j] = api.Select(canIns, solver, t.Solver[j])
+		}
 
 		inserted = api.Or(inserted, canIns)
 	}
@@ -662,6 +1030,7 @@ func greedySelectWinners(
 		winners[w].SolutionID = frontend.Variable(0)
 		winners[w].Solver = frontend.Variable(0)
 		winners[w].Score = frontend.Variable(0)
+		winners[w].TradesCommit = frontend.Variable(0)
 	}
 
 	var usedKey [WMax][PairMax]frontend.Variable
@@ -719,6 +1088,7 @@ func greedySelectWinners(
 			winners[w].SolutionID = api.Select(write, packed[i].SolutionID, winners[w].SolutionID)
 			winners[w].Solver = api.Select(write, packed[i].Solver, winners[w].Solver)
 			winners[w].Score = api.Select(write, packed[i].Score, winners[w].Score)
+			winners[w].TradesCommit = api.Select(write, packed[i].TradesCommit, winners[w].TradesCommit)
 
 			usedSlotMask[w] = api.Select(write, frontend.Variable(1), usedSlotMask[w])
 
@@ -733,17 +1103,116 @@ func greedySelectWinners(
 	}
 
 	for w := 0; w < WMax; w++ {
-		assertBoolIf(api, usedSlotMask[w], 1)
+		gadgets.AssertBoolIf(api, usedSlotMask[w], 1)
 		for p := 0; p < PairMax; p++ {
-			assertBoolIf(api, usedMask[w][p], 1)
+			gadgets.AssertBoolIf(api, usedMask[w][p], 1)
 		}
 	}
 
 	return winners
 }
 
-func assertBoolIf(api frontend.API, x, cond frontend.Variable) {
-	api.AssertIsEqual(api.Mul(cond, api.Mul(x, api.Sub(1, x))), 0)
+// solverRewards computes, for each winner, the reference score: the total
+// score of the winners greedySelectWinners picks once every solution of that
+// winner's solver is left out, with the baseline filter rerun without them.
+// The reward is the total score of the actual winners minus the reference, or
+// 0 when the reference is higher (greedy selection is not optimal, so leaving
+// a solver out can help). sol is ByScore and n its length.
+func solverRewards(
+	api frontend.API,
+	cmps Comparators,
+	rc frontend.Rangechecker,
+	sol [NMax]Packed,
+	n frontend.Variable,
+	base *baselineTable,
+	winners [WMax]Winner,
+) (reference, reward [WMax]frontend.Variable) {
+	observed := frontend.Variable(0)
+	for w := 0; w < WMax; w++ {
+		observed = api.Add(observed, winners[w].Score)
+	}
+
+	f := newReferenceFilter(api, cmps, sol, n, base)
+	for w := 0; w < WMax; w++ {
+		reference[w] = referenceScore(api, cmps, f, winners[w].Solver)
+		higher := gadgets.IsLessIf(api, rc, observed, reference[w], TOTAL_SCORE_BITS, 1)
+		reward[w] = api.Mul(api.Sub(1, higher), api.Sub(observed, reference[w]))
+	}
+	return reference, reward
+}
+
+// baselineEntry is a baselineTable row: the best score of a key, its solver and
+// the best score of the other solvers
+type baselineEntry struct {
+	Score, Solver, Other frontend.Variable
+}
+
+// baselineWithout is the baseline of e once solver's solutions are left out
+func baselineWithout(api frontend.API, e baselineEntry, solver frontend.Variable) frontend.Variable {
+	return api.Select(api.IsZero(api.Sub(e.Solver, solver)), e.Other, e.Score)
+}
+
+// referenceFilter is what the reference selections share: ByScore, the
+// baseline entries of its pair keys and which of them the filter checks
+type referenceFilter struct {
+	Sol     [NMax]Packed
+	N       frontend.Variable
+	Kept    [NMax]frontend.Variable // active and valid
+	IsMulti [NMax]frontend.Variable
+	Check   [NMax][PairMax]frontend.Variable // active pair of a kept multi-pair solution
+	Entry   [NMax][PairMax]baselineEntry
+}
+
+func newReferenceFilter(api frontend.API, cmps Comparators, sol [NMax]Packed, n frontend.Variable, base *baselineTable) *referenceFilter {
+	f := &referenceFilter{Sol: sol, N: n}
+	for i := 0; i < NMax; i++ {
+		f.Kept[i] = api.Mul(isLessThanConst(api, cmps.LenSol, i, n), sol[i].Valid)
+		f.IsMulti[i] = api.Sub(1, api.IsZero(api.Sub(sol[i].PairsLen, 1)))
+		for p := 0; p < PairMax; p++ {
+			f.Check[i][p] = api.Mul(api.Mul(f.Kept[i], f.IsMulti[i]), isLessThanConst(api, cmps.LenPair, p, sol[i].PairsLen))
+
+			e := baselineEntry{Score: 0, Solver: 0, Other: 0}
+			for j := 0; j < NMax; j++ {
+				hit := api.Mul(base.Used[j], api.IsZero(api.Sub(sol[i].PairKey[p], base.Key[j])))
+				e.Score = api.Add(e.Score, api.Mul(hit, base.Score[j]))
+				e.Solver = api.Add(e.Solver, api.Mul(hit, base.Solver[j]))
+				e.Other = api.Add(e.Other, api.Mul(hit, base.Other[j]))
+			}
+			f.Entry[i][p] = e
+		}
+	}
+	return f
+}
+
+// referenceScore is the total score of the winners selected without solver:
+// its solutions set no baseline, do not survive and are not selected
+func referenceScore(
+	api frontend.API,
+	cmps Comparators,
+	f *referenceFilter,
+	solver frontend.Variable,
+) frontend.Variable {
+	// a solution without pairs is never selected
+	without := f.Sol
+	for i := 0; i < NMax; i++ {
+		kept := api.Mul(f.Kept[i], api.Sub(1, api.IsZero(api.Sub(f.Sol[i].Solver, solver))))
+
+		pass := frontend.Variable(1)
+		for p := 0; p < PairMax; p++ {
+			b := baselineWithout(api, f.Entry[i][p], solver)
+			ok := api.Sub(1, cmps.Score.IsLess(f.Sol[i].PairScore[p], b))
+			cond := f.Check[i][p]
+			pass = api.Mul(pass, api.Add(api.Sub(1, cond), api.Mul(cond, ok)))
+		}
+		survive := api.Mul(kept, api.Add(api.Sub(1, f.IsMulti[i]), api.Mul(f.IsMulti[i], pass)))
+		without[i].PairsLen = api.Mul(survive, f.Sol[i].PairsLen)
+	}
+
+	total := frontend.Variable(0)
+	for _, w := range greedySelectWinners(api, cmps, without, f.N) {
+		total = api.Add(total, w.Score)
+	}
+	return total
 }
 
 func isLessThanConst(api frontend.API, bc *cmp.BoundedComparator, i int, x frontend.Variable) frontend.Variable {
@@ -763,11 +1232,6 @@ func assertLeqConst(api frontend.API, bc *cmp.BoundedComparator, x frontend.Vari
 	api.AssertIsEqual(bc.IsLess(x, frontend.Variable(c+1)), 1)
 }
 
-func assertLeqConstIf(api frontend.API, bc *cmp.BoundedComparator, x frontend.Variable, c int, cond frontend.Variable) {
-	ok := bc.IsLess(x, frontend.Variable(c+1))
-	api.AssertIsEqual(api.Mul(cond, api.Sub(1, ok)), 0)
-}
-
 func assertLtIf(api frontend.API, bc *cmp.BoundedComparator, a, b, cond frontend.Variable) {
 	lt := bc.IsLess(a, b)
 	api.AssertIsEqual(api.Mul(cond, api.Sub(1, lt)), 0)
@@ -782,100 +1246,3 @@ func assertGeqIf(api frontend.API, bc *cmp.BoundedComparator, a, b, cond fronten
 func pow2(bits int) *big.Int {
 	return new(big.Int).Lsh(big.NewInt(1), uint(bits))
 }
-
-func divCeil(api frontend.API, rc frontend.Rangechecker, a, b frontend.Variable, aBits, bBits, qBits int) frontend.Variable {
-	// ceil(a/b) = floor((a + b - 1)/b)
-	a2 := api.Add(a, api.Sub(b, 1))
-	return divFloor(api, rc, a2, b, aBits+1, bBits, qBits)
-}
-
-func divFloor(api frontend.API, rc frontend.Rangechecker, a, b frontend.Variable, aBits, bBits, qBits int) frontend.Variable {
-	// q,r from hint
-	out, err := api.NewHint(DivModHint, 2, a, b)
-	if err != nil {
-		panic(err)
-	}
-	q := out[0]
-	r := out[1]
-
-	// Range-check q,r
-	rc.Check(q, qBits)
-	rc.Check(r, bBits) // r < b so it fits in bBits
-
-	// Enforce: a = b*q + r
-	api.AssertIsEqual(a, api.Add(api.Mul(b, q), r))
-
-	// Enforcing r < b WITHOUT cmp
-	// s = b - 1 - r must be in [0, 2^bBits)
-	// If r >= b then s underflows to a huge field element & fails rangecheck.
-	s := api.Sub(api.Sub(b, 1), r)
-	rc.Check(s, bBits)
-
-	return q
-}
-
-// hintIsLessStrict returns 1 if a < b (strict), else 0.
-// Circuit verifies the hint via range checks on the difference.
-func hintIsLessStrict(
-	api frontend.API,
-	rc frontend.Rangechecker,
-	a, b frontend.Variable,
-	bits int,
-	cond frontend.Variable,
-) frontend.Variable {
-	out, err := api.NewHint(IsLessHint, 1, a, b)
-	if err != nil {
-		panic(err)
-	}
-	lt := out[0]
-	assertBoolIf(api, lt, cond)
-
-	// If lt==1: b - a - 1 >= 0  (meaning b > a)
-	// If lt==0: a - b >= 0      (meaning a >= b)
-	dPos := api.Sub(api.Sub(b, a), 1) // b-a-1
-	dNeg := api.Sub(a, b)             // a-b
-	checkVal := api.Select(lt, dPos, dNeg)
-	rc.Check(api.Select(cond, checkVal, 0), bits)
-
-	return lt
-}
-
-// results[0] = 1 if a < b else 0
-func IsLessHint(mod *big.Int, inputs []*big.Int, results []*big.Int) error {
-	if len(inputs) != 2 || len(results) != 1 {
-		return nil
-	}
-	a := new(big.Int).Set(inputs[0])
-	b := new(big.Int).Set(inputs[1])
-	if a.Cmp(b) < 0 {
-		results[0].SetInt64(1)
-	} else {
-		results[0].SetInt64(0)
-	}
-	return nil
-}
-
-// results[0] = q = floor(a/b)
-// results[1] = r = a - b*q
-func DivModHint(mod *big.Int, inputs []*big.Int, results []*big.Int) error {
-	if len(inputs) != 2 || len(results) != 2 {
-		return nil
-	}
-	a := new(big.Int).Set(inputs[0])
-	b := new(big.Int).Set(inputs[1])
-
-	// b == 0 enforced via r<b constraint
-	// But guard anyway to avoid panic
-	if b.Sign() == 0 {
-		results[0].SetInt64(0)
-		results[1].SetInt64(0)
-		return nil
-	}
-
-	q := new(big.Int).Quo(a, b)
-	r := new(big.Int).Rem(a, b)
-
-	results[0].Set(q)
-	results[1].Set(r)
-	return nil
-}
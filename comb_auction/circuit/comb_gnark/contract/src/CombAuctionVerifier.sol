@@ -10,7 +10,8 @@ import {Verifier} from "./Verifier.sol";
 contract CombAuctionVerifier is Ownable {
     Verifier public verifier;
     uint256 public constant WMAX = 30;
-    uint256 public constant INPUT_LEN = 93;
+    uint256 public constant WINNER_LEN = 6;
+    uint256 public constant INPUT_LEN = 3 + WMAX * WINNER_LEN;
 
     enum AuctionStatus {
         NONE,
@@ -22,6 +23,14 @@ contract CombAuctionVerifier is Ownable {
         uint256 solutionId;
         address solver;
         uint256 score;
+        // commitment to the winning solution's trades (see TradesCommitOf in the comb_gnark package)
+        uint256 tradesCommit;
+        // 0 unless the circuit is compiled with SolverRewards
+        // total score of the winners without this solver's solutions, the
+        // baseline filter and selection rerun without them
+        uint256 reference;
+        // total score of the winners minus reference, floored at 0
+        uint256 reward;
     }
 
     // auctionId => status/root/winners
@@ -30,6 +39,8 @@ contract CombAuctionVerifier is Ownable {
     mapping(uint256 => uint256) public winnersLen;
     mapping(uint256 => Winner[]) public winners;
     mapping(uint256 => mapping(address => bool)) public isWinner;
+    // auctionId => tradesCommit => solver allowed to settle exactly those trades
+    mapping(uint256 => mapping(uint256 => address)) public tradesWinner;
 
     event RootPosted(uint256 indexed auctionId, bytes32 bidsetRoot);
     event WinnersVerified(uint256 indexed auctionId, uint256 winnersLen);
@@ -72,7 +83,7 @@ contract CombAuctionVerifier is Ownable {
         uint256[8] calldata proof,
         uint256[2] calldata commitments,
         uint256[2] calldata commitmentPok,
-        uint256[93] calldata input
+        uint256[183] calldata input
     ) external {
         if (status[auctionId] == AuctionStatus.NONE) revert RootNotPosted();
         if (status[auctionId] == AuctionStatus.WINNERS_VERIFIED)
@@ -98,10 +109,18 @@ contract CombAuctionVerifier is Ownable {
             uint256 score = input[off + 2];
 
             winners[auctionId].push(
-                Winner({solutionId: solId, solver: solverAddr, score: score})
+                Winner({
+                    solutionId: solId,
+                    solver: solverAddr,
+                    score: score,
+                    tradesCommit: input[off + 3],
+                    reference: input[off + 4],
+                    reward: input[off + 5]
+                })
             );
             isWinner[auctionId][solverAddr] = true;
-            off += 3;
+            tradesWinner[auctionId][input[off + 3]] = solverAddr;
+            off += WINNER_LEN;
         }
 
         status[auctionId] = AuctionStatus.WINNERS_VERIFIED;
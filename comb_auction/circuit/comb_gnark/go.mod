@@ -7,6 +7,8 @@ toolchain go1.24.9
 require (
 	github.com/consensys/gnark v0.14.0
 	github.com/consensys/gnark-crypto v0.19.2
+	github.com/cowprotocol/Zk-benchmark/gnark/gadgets v0.0.0-00010101000000-000000000000
+	github.com/cowprotocol/Zk-benchmark/gnark/merkle v0.0.0-00010101000000-000000000000
 	golang.org/x/crypto v0.43.0
 )
 
@@ -28,3 +30,8 @@ require (
 	golang.org/x/sys v0.37.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/cowprotocol/Zk-benchmark/gnark/gadgets => ../../../gnark/gadgets
+	github.com/cowprotocol/Zk-benchmark/gnark/merkle => ../../../gnark/merkle
+)
@@ -0,0 +1,71 @@
+package comb_gnark
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type permCircuit struct {
+	A, B [4]Packed
+	N    frontend.Variable
+}
+
+func (c *permCircuit) Define(api frontend.API) error {
+	assertPermutation(api, newComparators(api), c.A[:], c.B[:], c.N)
+	return nil
+}
+
+func packedOf(id int64) Packed {
+	p := Packed{SolutionID: id, Solver: 100 + id, Score: 10 * id, Commit: 1000 + id, TradesCommit: 2000 + id, Valid: id % 2, PairsLen: 1}
+	for k := range p.PairKey {
+		p.PairKey[k] = 0
+		p.PairScore[k] = 0
+	}
+	p.PairKey[0] = 7 * id
+	p.PairScore[0] = 10 * id
+	return p
+}
+
+func TestPermutation(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		a, b [4]int64
+		n    int
+		edit func(*permCircuit)
+		ok   bool
+	}{
+		{name: "reordered", a: [4]int64{1, 2, 3, 4}, b: [4]int64{3, 1, 4, 2}, n: 4, ok: true},
+		{name: "inactive ignored", a: [4]int64{1, 2, 3, 4}, b: [4]int64{2, 1, 9, 9}, n: 2, ok: true},
+		{name: "duplicate", a: [4]int64{1, 2, 3, 4}, b: [4]int64{3, 1, 1, 2}, n: 4},
+		{name: "dropped", a: [4]int64{1, 2, 3, 4}, b: [4]int64{3, 1, 2, 9}, n: 4},
+		{name: "pair score", a: [4]int64{1, 2, 3, 4}, b: [4]int64{3, 1, 4, 2}, n: 4,
+			edit: func(c *permCircuit) { c.B[1].PairScore[PairMax-1] = 1 }},
+		{name: "fields swapped across entries", a: [4]int64{1, 2, 3, 4}, b: [4]int64{1, 2, 3, 4}, n: 4,
+			edit: func(c *permCircuit) { c.B[0].Score, c.B[1].Score = c.B[1].Score, c.B[0].Score }},
+		{name: "trades commits swapped", a: [4]int64{1, 2, 3, 4}, b: [4]int64{1, 2, 3, 4}, n: 4,
+			edit: func(c *permCircuit) {
+				c.B[0].TradesCommit, c.B[1].TradesCommit = c.B[1].TradesCommit, c.B[0].TradesCommit
+			}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := permCircuit{N: c.n}
+			for i := range w.A {
+				w.A[i] = packedOf(c.a[i])
+				w.B[i] = packedOf(c.b[i])
+			}
+			if c.edit != nil {
+				c.edit(&w)
+			}
+			err := test.IsSolved(&permCircuit{}, &w, ecc.BN254.ScalarField())
+			if c.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !c.ok && err == nil {
+				t.Fatal("not a permutation, accepted")
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/profile"
+	"github.com/consensys/gnark/std/rangecheck"
 )
 
 // run with: go test -run TestProfileCircuit -v
@@ -23,3 +24,72 @@ func TestProfileCircuit(t *testing.T) {
 	t.Logf("profile constraints: %d", p.NbConstraints())
 	t.Logf("\n%s", p.Top())
 }
+
+// selectionCircuit is the winner selection alone, with what SolverRewards adds
+// on top: the reference filter, built once from the baseline table, and one
+// reference rerun per winner slot
+type selectionCircuit struct {
+	N        frontend.Variable
+	ByScore  [NMax]Packed
+	Base     [5][NMax]frontend.Variable // baselineTable columns
+	Excluded frontend.Variable
+
+	Rewards bool `gnark:"-"` // reference filter
+	Rerun   bool `gnark:"-"` // and one reference rerun
+}
+
+func (c *selectionCircuit) Define(api frontend.API) error {
+	cmps := newComparators(api)
+	greedySelectWinners(api, cmps, c.ByScore, c.N)
+	if c.Rewards {
+		base := &baselineTable{Key: c.Base[0][:], Score: c.Base[1][:], Used: c.Base[2][:], Solver: c.Base[3][:], Other: c.Base[4][:]}
+		f := newReferenceFilter(api, cmps, c.ByScore, c.N, base)
+		if c.Rerun {
+			_ = referenceScore(api, cmps, f, c.Excluded)
+		}
+	}
+	return nil
+}
+
+// baselineCircuit is the baseline table, with or without the solver tracking
+// SolverRewards adds
+type baselineCircuit struct {
+	ByScore [NMax]Packed
+
+	Solvers bool `gnark:"-"`
+}
+
+func (c *baselineCircuit) Define(api frontend.API) error {
+	rc := rangecheck.New(api)
+	base := newBaselineTable(c.Solvers)
+	for i := 0; i < NMax; i++ {
+		use := api.IsZero(api.Sub(c.ByScore[i].PairsLen, 1))
+		baselineUpdate(api, rc, base, c.ByScore[i].PairKey[0], c.ByScore[i].PairScore[0], c.ByScore[i].Solver, use)
+	}
+	return nil
+}
+
+// SolverRewards adds WMax reruns of the baseline filter and winner selection
+// to the circuit; measure one rather than compiling the full circuit with it.
+// run with: go test -run TestProfileSolverRewards -v
+func TestProfileSolverRewards(t *testing.T) {
+	count := func(c frontend.Circuit) int {
+		cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, c)
+		if err != nil {
+			t.Fatalf("compile failed: %v", err)
+		}
+		return cs.GetNbConstraints()
+	}
+	tracking := count(&baselineCircuit{Solvers: true}) - count(&baselineCircuit{})
+	selection := count(&selectionCircuit{})
+	filter := count(&selectionCircuit{Rewards: true})
+	rerun := count(&selectionCircuit{Rewards: true, Rerun: true}) - filter
+	filter -= selection
+
+	t.Logf("NMax=%d WMax=%d PairMax=%d", NMax, WMax, PairMax)
+	t.Logf("greedy selection   : %d constraints", selection)
+	t.Logf("solver tracking    : %d constraints (once)", tracking)
+	t.Logf("reference filter   : %d constraints (once)", filter)
+	t.Logf("reference rerun    : %d constraints", rerun)
+	t.Logf("SolverRewards adds : ~%d constraints (once + WMax reruns)", tracking+filter+WMax*rerun)
+}
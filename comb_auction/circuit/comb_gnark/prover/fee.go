@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	comb "github.com/cowprotocol/Zk-benchmark/comb_auction/gnark"
+)
+
+// FeePolicy is an order's protocol fee policy as applied by the autopilot.
+// Factors are parts per million (comb.FEE_ONE); quotes are only set for
+// price improvement.
+type FeePolicy struct {
+	Kind      string `json:"kind"` // surplus, volume or priceimprovement
+	Factor    int64  `json:"factor"`
+	Cap       int64  `json:"cap,omitempty"` // max volume factor, surplus and price improvement
+	QuoteSell string `json:"quote_sell,omitempty"`
+	QuoteBuy  string `json:"quote_buy,omitempty"`
+}
+
+type FeeBuilt struct {
+	Kind      int
+	Factor    *big.Int
+	Cap       *big.Int
+	QuoteSell *big.Int
+	QuoteBuy  *big.Int
+}
+
+func noFee() FeeBuilt {
+	return FeeBuilt{
+		Kind:      comb.FeeNone,
+		Factor:    big.NewInt(0),
+		Cap:       big.NewInt(0),
+		QuoteSell: big.NewInt(0),
+		QuoteBuy:  big.NewInt(0),
+	}
+}
+
+func buildFee(p *FeePolicy) (FeeBuilt, error) {
+	fb := noFee()
+	if p == nil {
+		return fb, nil
+	}
+	switch p.Kind {
+	case "surplus":
+		fb.Kind = comb.FeeSurplus
+	case "volume":
+		fb.Kind = comb.FeeVolume
+	case "priceimprovement":
+		fb.Kind = comb.FeePriceImprovement
+	default:
+		return FeeBuilt{}, fmt.Errorf("unknown fee policy kind %q", p.Kind)
+	}
+	for _, f := range []struct {
+		name string
+		v    int64
+	}{{"factor", p.Factor}, {"cap", p.Cap}} {
+		if f.v < 0 || f.v >= comb.FEE_ONE {
+			return FeeBuilt{}, fmt.Errorf("fee %s %d not in [0, %d)", f.name, f.v, comb.FEE_ONE)
+		}
+	}
+	fb.Factor = big.NewInt(p.Factor)
+	if fb.Kind != comb.FeeVolume {
+		fb.Cap = big.NewInt(p.Cap)
+	}
+	if fb.Kind == comb.FeePriceImprovement {
+		if p.QuoteSell == "" || p.QuoteBuy == "" {
+			return FeeBuilt{}, fmt.Errorf("price improvement fee needs quote_sell and quote_buy")
+		}
+		var err error
+		if fb.QuoteSell, err = parseQuote("quote_sell", p.QuoteSell); err != nil {
+			return FeeBuilt{}, err
+		}
+		if fb.QuoteBuy, err = parseQuote("quote_buy", p.QuoteBuy); err != nil {
+			return FeeBuilt{}, err
+		}
+	}
+	return fb, nil
+}
+
+// parseQuote reads a quoted amount, which the circuit packs into AMT_BITS
+func parseQuote(name, s string) (*big.Int, error) {
+	q, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("%s %q is not a decimal amount", name, s)
+	}
+	if q.Sign() <= 0 || q.BitLen() > comb.AMT_BITS {
+		return nil, fmt.Errorf("%s %s not in (0, 2^%d)", name, q, comb.AMT_BITS)
+	}
+	return q, nil
+}
+
+// protocolFeeGo mirrors computeProtocolFee in the circuit: the fee in the
+// surplus token, given the trade's surplus over its limit price and its
+// executed amount on the order's side clamped to the order amount
+func protocolFeeGo(fee FeeBuilt, execSell, execBuy, filled *big.Int, side int, surplus *big.Int) *big.Int {
+	if fee.Kind == comb.FeeNone {
+		return big.NewInt(0)
+	}
+	one := big.NewInt(comb.FEE_ONE)
+
+	volume, vf := execBuy, fee.Cap
+	if side == 1 {
+		volume = execSell
+	}
+	if fee.Kind == comb.FeeVolume {
+		vf = fee.Factor
+	}
+	vDen := new(big.Int).Sub(one, vf)
+	if side == 1 {
+		vDen.Add(one, vf)
+	}
+	volumeFee := new(big.Int).Div(new(big.Int).Mul(volume, vf), vDen)
+	if fee.Kind == comb.FeeVolume {
+		return volumeFee
+	}
+
+	base := surplus
+	if fee.Kind == comb.FeePriceImprovement {
+		base = priceImprovementGo(fee, execSell, execBuy, filled, side)
+	}
+	surplusFee := new(big.Int).Div(new(big.Int).Mul(base, fee.Factor), new(big.Int).Sub(one, fee.Factor))
+	if volumeFee.Cmp(surplusFee) < 0 {
+		return volumeFee
+	}
+	return surplusFee
+}
+
+// priceImprovementGo is the surplus over the quote instead of the limit price,
+// or 0. Like the circuit, the quoted amount is taken on filled.
+func priceImprovementGo(fee FeeBuilt, execSell, execBuy, filled *big.Int, side int) *big.Int {
+	var improvement *big.Int
+	if side == 0 {
+		quoteBuy := divCeilBig(new(big.Int).Mul(fee.QuoteBuy, filled), fee.QuoteSell)
+		improvement = new(big.Int).Sub(execBuy, quoteBuy)
+	} else {
+		quoteSell := new(big.Int).Div(new(big.Int).Mul(fee.QuoteSell, filled), fee.QuoteBuy)
+		improvement = new(big.Int).Sub(quoteSell, execSell)
+	}
+	if improvement.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	return improvement
+}
@@ -16,22 +16,18 @@ import (
 	"sort"
 	"strings"
 
-	"golang.org/x/crypto/sha3"
-
 	"github.com/consensys/gnark-crypto/ecc"
 	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
-	frmimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
 
 	comb "github.com/cowprotocol/Zk-benchmark/comb_auction/gnark"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
 )
 
 const (
-	DS_R     = 0xA11CE001
 	DS_RPAIR = 0xA11CE002
-	DS_ALPHA = 0xA11CE003
 )
 
 type AuctionsFile struct {
@@ -54,16 +50,18 @@ type Solution struct {
 }
 
 type Trade struct {
-	OrderUID           string `json:"order_uid"`
-	SellToken          string `json:"sell_token"`
-	BuyToken           string `json:"buy_token"`
-	LimitSell          string `json:"limit_sell"`
-	LimitBuy           string `json:"limit_buy"`
-	ExecSell           string `json:"exec_sell"`
-	ExecBuy            string `json:"exec_buy"`
-	Side               int    `json:"side"` // 0 sell, 1 buy
-	BuyTokenPriceE18   string `json:"buy_token_price_e18"`
-	ScoreNativeIgnored string `json:"score_native"`
+	OrderUID           string     `json:"order_uid"`
+	SellToken          string     `json:"sell_token"`
+	BuyToken           string     `json:"buy_token"`
+	LimitSell          string     `json:"limit_sell"`
+	LimitBuy           string     `json:"limit_buy"`
+	ExecSell           string     `json:"exec_sell"`
+	ExecBuy            string     `json:"exec_buy"`
+	Side               int        `json:"side"` // 0 sell, 1 buy
+	BuyTokenPriceE18   string     `json:"buy_token_price_e18"`
+	FeePolicy          *FeePolicy `json:"fee_policy,omitempty"`
+	PartiallyFillable  bool       `json:"partially_fillable"` // false: fill-or-kill
+	ScoreNativeIgnored string     `json:"score_native"`
 }
 
 type Config struct {
@@ -80,6 +78,14 @@ type Config struct {
 
 	OutProof string
 	DoVerify bool
+
+	Options // must match the options the circuit was compiled with
+}
+
+// Options are the circuit's compile-time options
+type Options struct {
+	UniformPrices bool
+	SolverRewards bool
 }
 
 func main() {
@@ -98,6 +104,8 @@ func main() {
 	flag.StringVar(&cfg.OutProof, "out", repoPath("../proof.json"), "output proof json")
 
 	flag.BoolVar(&cfg.DoVerify, "verify", true, "verify proof locally with vk")
+	flag.BoolVar(&cfg.UniformPrices, "uniform_prices", false, "circuit enforces uniform clearing prices per directed pair")
+	flag.BoolVar(&cfg.SolverRewards, "solver_rewards", false, "circuit proves per-winner reference scores and rewards")
 
 	flag.Parse()
 
@@ -135,7 +143,7 @@ func run(cfg Config) error {
 	auc := af.Auctions[cfg.AuctionIndex]
 	fmt.Printf("[i] auction_index=%d -> auction_id=%d (solutions=%d)\n", cfg.AuctionIndex, auc.AuctionID, len(auc.Solutions))
 
-	assignment, publicInputs, err := buildWitnessForAuction(auc)
+	assignment, publicInputs, err := buildWitnessForAuction(auc, cfg.Options)
 	if err != nil {
 		return err
 	}
@@ -213,12 +221,17 @@ type SolnBuilt struct {
 	Trades []TradeBuilt
 
 	PairsLen     int
-	PairKey      []*big.Int
+	PairTokens   [][2]*big.Int // sell, buy
+	PairKey      []*big.Int    // see setPairKeys
 	PairScore    []*big.Int
 	TradePairIdx []int
 
 	TotalScore *big.Int
+	Commit     *big.Int // bidset leaf
+	// hash of the trades, public for winners, see comb.TradesCommitOf
+	TradesCommit *big.Int
 
+	Invalid  error // first trade that does not respect its order, see validateTrade
 	Survives bool
 }
 
@@ -232,18 +245,17 @@ type TradeBuilt struct {
 	ExecBuy     *big.Int
 	Side        int
 	PriceE18    *big.Int
+	Fee         FeeBuilt
+	Partial     bool
 	ScoreNative *big.Int
 }
 
-func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
+func buildWitnessForAuction(auc Auction, opts Options) (*comb.Circuit, []*big.Int, error) {
 	auctionIDBI := big.NewInt(int64(auc.AuctionID))
 
-	r := mimcFrElems(frFromBig(auctionIDBI), frFromBig(big.NewInt(DS_R)))
-	rPair := mimcFrElems(frFromBig(auctionIDBI), frFromBig(big.NewInt(DS_RPAIR)))
-
 	built := make([]SolnBuilt, 0, len(auc.Solutions))
 	for _, s := range auc.Solutions {
-		sb, err := buildOneSolution(s, auctionIDBI, rPair)
+		sb, err := buildOneSolution(s)
 		if err != nil {
 			return nil, nil, fmt.Errorf("solution_uid=%d: %w", s.SolutionUID, err)
 		}
@@ -254,37 +266,85 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 		return nil, nil, fmt.Errorf("auction has %d solutions > NMax=%d; cannot build witness", len(built), comb.NMax)
 	}
 
-	// Sort by score descending, assign sequential SolutionIDs.
-	// This ensures Solutions[] is ordered by SolutionID ascending AND
-	// survivors in packed[] are score-descending, satisfying both circuit constraints.
-	sort.Slice(built, func(i, j int) bool {
-		return built[i].TotalScore.Cmp(built[j].TotalScore) > 0
+	for i := range built {
+		tc, err := tradesCommit(built[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("solution_uid=%d: %w", built[i].SolutionID, err)
+		}
+		built[i].TradesCommit = tc
+		built[i].Commit = frToBig(solutionLeaf(built[i]))
+		if opts.UniformPrices && built[i].Invalid == nil {
+			built[i].Invalid = checkUniformPrices(built[i])
+		}
+	}
+
+	// Solutions[] in bidset order, by leaf commit ascending
+	committed := append([]SolnBuilt(nil), built...)
+	sort.Slice(committed, func(i, j int) bool {
+		return committed[i].Commit.Cmp(committed[j].Commit) < 0
 	})
+	for i := 0; i+1 < len(committed); i++ {
+		if committed[i].Commit.Cmp(committed[i+1].Commit) == 0 {
+			return nil, nil, fmt.Errorf("solution_uid=%d is submitted twice", committed[i].SolutionID)
+		}
+	}
+	prices, err := buildPriceVector(committed)
+	if err != nil {
+		return nil, nil, err
+	}
+	bidsetRoot, err := computeBidsetRoot(auctionIDBI, committed, prices)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pair keys use a challenge drawn from the root
+	rPair := derivePairChallenge(bidsetRoot)
 	for i := range built {
-		built[i].SolutionID = big.NewInt(int64(i))
+		setPairKeys(&built[i], rPair)
+	}
+	for i := range committed {
+		setPairKeys(&committed[i], rPair)
 	}
 
-	applyBaselineFilter(built)
+	// ByScore[], by score descending and ties by leaf commit descending, as the
+	// circuit checks; the survivors keep this order when packed
+	sort.Slice(built, func(i, j int) bool {
+		if c := built[i].TotalScore.Cmp(built[j].TotalScore); c != 0 {
+			return c > 0
+		}
+		return built[i].Commit.Cmp(built[j].Commit) > 0
+	})
 
-	// Survivors are already in score-descending order (built is sorted above).
-	survivors := make([]SolnBuilt, 0, len(built))
 	for _, sb := range built {
-		if sb.Survives && sb.PairsLen > 0 {
-			survivors = append(survivors, sb)
+		if sb.Invalid != nil {
+			fmt.Printf("[i] excluding solution_uid=%d: %v\n", sb.SolutionID, sb.Invalid)
 		}
 	}
+	applyBaselineFilter(built)
+	survivors := survivorsOf(built)
 
 	winners := greedyWinners(survivors, comb.WMax)
-
-	bidsetRoot, err := computeBidsetRoot(built, r)
-	if err != nil {
-		return nil, nil, err
+	var reference, reward []*big.Int
+	if opts.SolverRewards {
+		reference, reward = solverRewards(built, winners)
 	}
 
 	asn := new(comb.Circuit)
+	asn.UniformPrices = opts.UniformPrices
+	asn.SolverRewards = opts.SolverRewards
 	asn.AuctionID = auctionIDBI
 	asn.BidsetRoot = bidsetRoot
 
+	asn.PricesLen = big.NewInt(int64(len(prices.Tokens)))
+	for k := 0; k < comb.PriceMax; k++ {
+		asn.Prices[k].Token = big.NewInt(0)
+		asn.Prices[k].PriceE18 = big.NewInt(0)
+	}
+	for k := range prices.Tokens {
+		asn.Prices[k].Token = prices.Tokens[k]
+		asn.Prices[k].PriceE18 = prices.Prices[k]
+	}
+
 	asn.SolutionsLen = big.NewInt(int64(len(built)))
 
 	asn.WinnersLen = big.NewInt(int64(len(winners)))
@@ -292,11 +352,19 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 		asn.Winners[i].SolutionID = big.NewInt(0)
 		asn.Winners[i].Solver = big.NewInt(0)
 		asn.Winners[i].Score = big.NewInt(0)
+		asn.Winners[i].TradesCommit = big.NewInt(0)
+		asn.Winners[i].Reference = big.NewInt(0)
+		asn.Winners[i].Reward = big.NewInt(0)
 	}
 	for i := 0; i < len(winners); i++ {
 		asn.Winners[i].SolutionID = winners[i].SolutionID
 		asn.Winners[i].Solver = winners[i].SolverAddr
 		asn.Winners[i].Score = winners[i].TotalScore
+		asn.Winners[i].TradesCommit = winners[i].TradesCommit
+		if opts.SolverRewards {
+			asn.Winners[i].Reference = reference[i]
+			asn.Winners[i].Reward = reward[i]
+		}
 	}
 
 	for i := 0; i < comb.NMax; i++ {
@@ -314,7 +382,14 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 			asn.Solutions[i].Trades[t].ExecutedBuy = big.NewInt(0)
 			asn.Solutions[i].Trades[t].Side = big.NewInt(0)
 			asn.Solutions[i].Trades[t].NativePriceBuy = big.NewInt(0)
+			asn.Solutions[i].Trades[t].FeeKind = big.NewInt(0)
+			asn.Solutions[i].Trades[t].FeeFactor = big.NewInt(0)
+			asn.Solutions[i].Trades[t].FeeCap = big.NewInt(0)
+			asn.Solutions[i].Trades[t].QuoteSell = big.NewInt(0)
+			asn.Solutions[i].Trades[t].QuoteBuy = big.NewInt(0)
+			asn.Solutions[i].Trades[t].PartiallyFillable = big.NewInt(0)
 			asn.Solutions[i].TradePairIdx[t] = big.NewInt(0)
+			asn.Solutions[i].TradePriceIdx[t] = big.NewInt(0)
 		}
 		for p := 0; p < comb.PairMax; p++ {
 			asn.Solutions[i].PairKey[p] = big.NewInt(0)
@@ -322,7 +397,34 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 		}
 	}
 
+	for i := 0; i < comb.NMax; i++ {
+		asn.ByScore[i].SolutionID = big.NewInt(0)
+		asn.ByScore[i].Solver = big.NewInt(0)
+		asn.ByScore[i].Score = big.NewInt(0)
+		asn.ByScore[i].Commit = big.NewInt(0)
+		asn.ByScore[i].TradesCommit = big.NewInt(0)
+		asn.ByScore[i].Valid = big.NewInt(0)
+		asn.ByScore[i].PairsLen = big.NewInt(0)
+		for p := 0; p < comb.PairMax; p++ {
+			asn.ByScore[i].PairKey[p] = big.NewInt(0)
+			asn.ByScore[i].PairScore[p] = big.NewInt(0)
+		}
+	}
 	for i, sb := range built {
+		asn.ByScore[i].SolutionID = sb.SolutionID
+		asn.ByScore[i].Solver = sb.SolverAddr
+		asn.ByScore[i].Score = sb.TotalScore
+		asn.ByScore[i].Commit = sb.Commit
+		asn.ByScore[i].TradesCommit = sb.TradesCommit
+		asn.ByScore[i].Valid = boolToBig(sb.Invalid == nil)
+		asn.ByScore[i].PairsLen = big.NewInt(int64(sb.PairsLen))
+		for p := 0; p < sb.PairsLen; p++ {
+			asn.ByScore[i].PairKey[p] = sb.PairKey[p]
+			asn.ByScore[i].PairScore[p] = sb.PairScore[p]
+		}
+	}
+
+	for i, sb := range committed {
 		asn.Solutions[i].SolutionID.Value = sb.SolutionID
 		asn.Solutions[i].Solver.Value = sb.SolverAddr
 		asn.Solutions[i].TradesLen = big.NewInt(int64(len(sb.Trades)))
@@ -339,7 +441,14 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 			asn.Solutions[i].Trades[t].ExecutedBuy = tr.ExecBuy
 			asn.Solutions[i].Trades[t].Side = big.NewInt(int64(tr.Side))
 			asn.Solutions[i].Trades[t].NativePriceBuy = tr.PriceE18
+			asn.Solutions[i].Trades[t].FeeKind = big.NewInt(int64(tr.Fee.Kind))
+			asn.Solutions[i].Trades[t].FeeFactor = tr.Fee.Factor
+			asn.Solutions[i].Trades[t].FeeCap = tr.Fee.Cap
+			asn.Solutions[i].Trades[t].QuoteSell = tr.Fee.QuoteSell
+			asn.Solutions[i].Trades[t].QuoteBuy = tr.Fee.QuoteBuy
+			asn.Solutions[i].Trades[t].PartiallyFillable = boolToBig(tr.Partial)
 			asn.Solutions[i].TradePairIdx[t] = big.NewInt(int64(sb.TradePairIdx[t]))
+			asn.Solutions[i].TradePriceIdx[t] = big.NewInt(int64(prices.Index(tr.BuyToken)))
 		}
 		for p := 0; p < sb.PairsLen && p < comb.PairMax; p++ {
 			asn.Solutions[i].PairKey[p] = sb.PairKey[p]
@@ -352,33 +461,20 @@ func buildWitnessForAuction(auc Auction) (*comb.Circuit, []*big.Int, error) {
 		return nil, nil, err
 	}
 
-	_ = rPair
-	_ = r
-
 	return asn, pubVec, nil
 }
 
-func buildOneSolution(s Solution, auctionIDBI *big.Int, rPair fr.Element) (SolnBuilt, error) {
+// buildOneSolution builds a solution and its pair buckets, except for the pair
+// keys, which need the bidset root: see setPairKeys
+func buildOneSolution(s Solution) (SolnBuilt, error) {
 	sb := SolnBuilt{
 		SolutionID: big.NewInt(int64(s.SolutionUID)),
 		SolverAddr: mustAddrToBig(s.Solver),
 	}
 
-	alpha := mimcFrElems(
-		frFromBig(auctionIDBI),
-		frFromBig(big.NewInt(DS_ALPHA)),
-		frFromBig(sb.SolverAddr),
-		frFromBig(sb.SolutionID),
-	)
-	_ = alpha
-
-	if len(s.Trades) > comb.TMax {
-		return SolnBuilt{}, fmt.Errorf("trades=%d > TMax=%d; cannot prove (increase TMax or skip)", len(s.Trades), comb.TMax)
-	}
-
 	type bucket struct {
-		key   *big.Int
-		score *big.Int
+		tokens [2]*big.Int
+		score  *big.Int
 	}
 	buckets := []bucket{}
 	bucketIndex := map[string]int{}
@@ -387,18 +483,20 @@ func buildOneSolution(s Solution, auctionIDBI *big.Int, rPair fr.Element) (SolnB
 	sb.TradePairIdx = make([]int, 0, len(s.Trades))
 	sb.TotalScore = big.NewInt(0)
 
-	for _, tr := range s.Trades {
-		tb, err := buildTrade(tr)
-		if err != nil {
-			return SolnBuilt{}, err
+	trades, err := buildTrades(s.Trades)
+	if err != nil {
+		return SolnBuilt{}, err
+	}
+
+	for t, tr := range trades {
+		tb := tr.tb
+		// a repeated order scores nothing and invalidates the solution
+		repeated := t > 0 && tb.TradeID.Cmp(trades[t-1].tb.TradeID) == 0
+		if repeated {
+			tb.ScoreNative = big.NewInt(0)
 		}
 
-		keyFr := frAdd(
-			frFromBig(tb.SellToken),
-			frMul(rPair, frFromBig(tb.BuyToken)),
-		)
-		keyBI := frToBig(keyFr)
-		keyStr := keyBI.String()
+		keyStr := tb.SellToken.String() + ":" + tb.BuyToken.String()
 
 		idx, ok := bucketIndex[keyStr]
 		if !ok {
@@ -407,21 +505,28 @@ func buildOneSolution(s Solution, auctionIDBI *big.Int, rPair fr.Element) (SolnB
 			}
 			idx = len(buckets)
 			bucketIndex[keyStr] = idx
-			buckets = append(buckets, bucket{key: keyBI, score: big.NewInt(0)})
+			buckets = append(buckets, bucket{tokens: [2]*big.Int{tb.SellToken, tb.BuyToken}, score: big.NewInt(0)})
 		}
 
 		buckets[idx].score.Add(buckets[idx].score, tb.ScoreNative)
 		sb.TotalScore.Add(sb.TotalScore, tb.ScoreNative)
 
+		if err := validateTrade(tb); err != nil && sb.Invalid == nil {
+			sb.Invalid = fmt.Errorf("order %s: %w", tr.uid, err)
+		}
+		if repeated && sb.Invalid == nil {
+			sb.Invalid = fmt.Errorf("order %s: settled twice", tr.uid)
+		}
+
 		sb.Trades = append(sb.Trades, tb)
 		sb.TradePairIdx = append(sb.TradePairIdx, idx)
 	}
 
 	sb.PairsLen = len(buckets)
-	sb.PairKey = make([]*big.Int, sb.PairsLen)
+	sb.PairTokens = make([][2]*big.Int, sb.PairsLen)
 	sb.PairScore = make([]*big.Int, sb.PairsLen)
 	for i := 0; i < sb.PairsLen; i++ {
-		sb.PairKey[i] = buckets[i].key
+		sb.PairTokens[i] = buckets[i].tokens
 		sb.PairScore[i] = buckets[i].score
 	}
 
@@ -429,12 +534,48 @@ func buildOneSolution(s Solution, auctionIDBI *big.Int, rPair fr.Element) (SolnB
 	return sb, nil
 }
 
+// setPairKeys sets the key of each pair bucket, sell + rPair*buy
+func setPairKeys(sb *SolnBuilt, rPair fr.Element) {
+	sb.PairKey = make([]*big.Int, sb.PairsLen)
+	for i, tokens := range sb.PairTokens {
+		sb.PairKey[i] = frToBig(frAdd(frFromBig(tokens[0]), frMul(rPair, frFromBig(tokens[1]))))
+	}
+}
+
+// derivePairChallenge mirrors the circuit's pair key challenge
+func derivePairChallenge(bidsetRoot *big.Int) fr.Element {
+	return merkle.HashElems(frFromBig(bidsetRoot), frFromBig(big.NewInt(DS_RPAIR)))
+}
+
+type tradeIn struct {
+	uid string
+	tb  TradeBuilt
+}
+
+// buildTrades builds a solution's trades sorted by ID, as the circuit requires
+func buildTrades(in []Trade) ([]tradeIn, error) {
+	if len(in) > comb.TMax {
+		return nil, fmt.Errorf("trades=%d > TMax=%d; cannot prove (increase TMax or skip)", len(in), comb.TMax)
+	}
+	trades := make([]tradeIn, 0, len(in))
+	for _, tr := range in {
+		tb, err := buildTrade(tr)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, tradeIn{tr.OrderUID, tb})
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].tb.TradeID.Cmp(trades[j].tb.TradeID) < 0 })
+	return trades, nil
+}
+
 func buildTrade(t Trade) (TradeBuilt, error) {
 	tb := TradeBuilt{
 		TradeID:   frToBig(frFromKeccakHex(t.OrderUID)),
 		SellToken: mustAddrToBig(t.SellToken),
 		BuyToken:  mustAddrToBig(t.BuyToken),
 		Side:      t.Side,
+		Partial:   t.PartiallyFillable,
 	}
 
 	tb.LimitSell = mustDecToBig(t.LimitSell)
@@ -442,10 +583,39 @@ func buildTrade(t Trade) (TradeBuilt, error) {
 	tb.ExecSell = mustDecToBig(t.ExecSell)
 	tb.ExecBuy = mustDecToBig(t.ExecBuy)
 	tb.PriceE18 = mustDecToBig(t.BuyTokenPriceE18)
+	for _, a := range []struct {
+		name string
+		v    *big.Int
+		bits int
+	}{
+		{"limit_sell", tb.LimitSell, comb.AMT_BITS},
+		{"limit_buy", tb.LimitBuy, comb.AMT_BITS},
+		{"exec_sell", tb.ExecSell, comb.AMT_BITS},
+		{"exec_buy", tb.ExecBuy, comb.AMT_BITS},
+		{"buy_token_price_e18", tb.PriceE18, comb.PRICE_BITS},
+	} {
+		if a.v.BitLen() > a.bits {
+			return TradeBuilt{}, fmt.Errorf("order %s: %s has %d bits > %d", t.OrderUID, a.name, a.v.BitLen(), a.bits)
+		}
+	}
 
-	tb.ScoreNative = computeScoreNativeGo(
-		tb.LimitSell, tb.LimitBuy, tb.ExecSell, tb.ExecBuy, tb.Side, tb.PriceE18,
-	)
+	if tb.LimitSell.Sign() == 0 || tb.LimitBuy.Sign() == 0 {
+		return TradeBuilt{}, fmt.Errorf("order %s: zero limit amount", t.OrderUID)
+	}
+
+	fee, err := buildFee(t.FeePolicy)
+	if err != nil {
+		return TradeBuilt{}, fmt.Errorf("order %s: %w", t.OrderUID, err)
+	}
+	tb.Fee = fee
+
+	// an invalid trade scores 0, like in the circuit; the solution is excluded
+	tb.ScoreNative = big.NewInt(0)
+	if validateTrade(tb) == nil {
+		tb.ScoreNative = computeScoreNativeGo(
+			tb.LimitSell, tb.LimitBuy, tb.ExecSell, tb.ExecBuy, tb.Side, tb.PriceE18, tb.Fee,
+		)
+	}
 	return tb, nil
 }
 
@@ -455,7 +625,7 @@ func applyBaselineFilter(built []SolnBuilt) {
 
 	for i := range built {
 		sb := &built[i]
-		if sb.PairsLen == 1 {
+		if sb.PairsLen == 1 && sb.Invalid == nil {
 			k := sb.PairKey[0].String()
 			cur, ok := baseline[k]
 			if !ok || sb.PairScore[0].Cmp(cur) > 0 {
@@ -466,6 +636,10 @@ func applyBaselineFilter(built []SolnBuilt) {
 
 	for i := range built {
 		sb := &built[i]
+		if sb.Invalid != nil {
+			sb.Survives = false
+			continue
+		}
 		if sb.PairsLen <= 1 {
 			sb.Survives = true
 			continue
@@ -486,6 +660,17 @@ func applyBaselineFilter(built []SolnBuilt) {
 	}
 }
 
+// survivorsOf is built's survivors with pairs, the only ones greedyWinners can pick
+func survivorsOf(built []SolnBuilt) []SolnBuilt {
+	survivors := make([]SolnBuilt, 0, len(built))
+	for _, sb := range built {
+		if sb.Survives && sb.PairsLen > 0 {
+			survivors = append(survivors, sb)
+		}
+	}
+	return survivors
+}
+
 func greedyWinners(survivors []SolnBuilt, wmax int) []SolnBuilt {
 	used := map[string]bool{}
 	out := make([]SolnBuilt, 0, wmax)
@@ -512,162 +697,155 @@ func greedyWinners(survivors []SolnBuilt, wmax int) []SolnBuilt {
 	return out
 }
 
-func computeBidsetRoot(built []SolnBuilt, r fr.Element) (*big.Int, error) {
-	leaves := make([]fr.Element, 1<<comb.TreeDepth)
-	zero := fr.Element{}
-
-	for i := range leaves {
-		leaves[i] = zero
+// solverRewards mirrors the circuit: for each winner, the total score of the
+// winners selected without its solver's solutions, rerunning the baseline
+// filter without them, and the total score of the winners minus that
+// reference, floored at 0. built is in ByScore order.
+func solverRewards(built, winners []SolnBuilt) (reference, reward []*big.Int) {
+	observed := big.NewInt(0)
+	for _, w := range winners {
+		observed.Add(observed, w.TotalScore)
 	}
 
-	for i := 0; i < len(built) && i < comb.NMax; i++ {
-		sb := built[i]
-		trCommit := computeTradesCommitOffchain(sb, r)
-		leaf := mimcFrElems(
-			frFromBig(big.NewInt(999001)),
-			frFromBig(sb.SolverAddr),
-			frFromBig(sb.SolutionID),
-			frFromBig(big.NewInt(int64(len(sb.Trades)))),
-			trCommit,
-		)
-		leaves[i] = leaf
+	for _, w := range winners {
+		without := make([]SolnBuilt, 0, len(built))
+		for _, sb := range built {
+			if sb.SolverAddr.Cmp(w.SolverAddr) != 0 {
+				without = append(without, sb)
+			}
+		}
+		applyBaselineFilter(without)
+		ref := big.NewInt(0)
+		for _, r := range greedyWinners(survivorsOf(without), comb.WMax) {
+			ref.Add(ref, r.TotalScore)
+		}
+		rew := new(big.Int).Sub(observed, ref)
+		if rew.Sign() < 0 {
+			rew.SetInt64(0)
+		}
+		reference = append(reference, ref)
+		reward = append(reward, rew)
 	}
-
-	root := merkleRootMiMC(leaves, comb.TreeDepth)
-	return frToBig(root), nil
+	return reference, reward
 }
 
-func computeTradesCommitOffchain(sb SolnBuilt, r fr.Element) fr.Element {
-	var acc fr.Element
-	var pow fr.Element
-	pow.SetOne()
-
-	var tmp fr.Element
-
-	for t := 0; t < comb.TMax; t++ {
-		var (
-			f0, f1, f2, f3, f4, f5, f6, f7, f8 fr.Element
-		)
-
-		if t < len(sb.Trades) {
-			tr := sb.Trades[t]
-			f0 = frFromBig(tr.TradeID)
-			f1 = frFromBig(tr.SellToken)
-			f2 = frFromBig(tr.BuyToken)
-			f3 = frFromBig(tr.LimitSell)
-			f4 = frFromBig(tr.LimitBuy)
-			f5 = frFromBig(tr.ExecSell)
-			f6 = frFromBig(tr.ExecBuy)
-			f7 = frFromBig(big.NewInt(int64(tr.Side)))
-			f8 = frFromBig(tr.PriceE18)
-		} else {
-			// inactive => zeros
-			f0, f1, f2, f3, f4, f5, f6, f7, f8 =
-				fr.Element{}, fr.Element{}, fr.Element{}, fr.Element{}, fr.Element{},
-				fr.Element{}, fr.Element{}, fr.Element{}, fr.Element{}
-		}
-
-		// acc += f * pow; pow *= r  (fixed order)
-		tmp.Mul(&f0, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f1, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f2, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f3, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f4, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f5, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f6, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
-
-		tmp.Mul(&f7, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
+// computeBidsetRoot is the root the autopilot posts, H(DOMAIN_ROOT ||
+// auction_id || solutions_root || prices_commit): built must be in bidset
+// order, by leaf commit ascending, so the root does not depend on scores
+func computeBidsetRoot(auctionID *big.Int, built []SolnBuilt, prices priceVector) (*big.Int, error) {
+	leaves := make([]fr.Element, 1<<comb.TreeDepth)
 
-		tmp.Mul(&f8, &pow)
-		acc.Add(&acc, &tmp)
-		pow.Mul(&pow, &r)
+	for i := 0; i < len(built) && i < comb.NMax; i++ {
+		leaves[i] = frFromBig(built[i].Commit)
 	}
 
-	return acc
-}
-
-func merkleRootMiMC(leaves []fr.Element, depth int) fr.Element {
-	nodes := make([]fr.Element, len(leaves))
-	copy(nodes, leaves)
-	for level := 0; level < depth; level++ {
-		next := make([]fr.Element, len(nodes)/2)
-		for i := 0; i < len(next); i++ {
-			next[i] = mimcFrElems(nodes[2*i], nodes[2*i+1])
-		}
-		nodes = next
+	solutionsRoot, err := merkle.Root(leaves)
+	if err != nil {
+		return nil, err
 	}
-	return nodes[0]
+	return frToBig(merkle.HashElems(frFromBig(big.NewInt(999003)), frFromBig(auctionID), solutionsRoot, prices.Commit())), nil
 }
 
-func mimcFrElems(elems ...fr.Element) fr.Element {
-	h := frmimc.NewMiMC()
-	for _, e := range elems {
-		b := e.Marshal()
-		h.Write(b)
-	}
-	sum := h.Sum(nil)
-	var out fr.Element
-	_ = out.SetBytes(sum)
-	return out
+// solutionLeaf mirrors hashSolutionLeaf in the circuit
+func solutionLeaf(sb SolnBuilt) fr.Element {
+	return merkle.HashElems(
+		frFromBig(big.NewInt(999001)),
+		frFromBig(sb.SolverAddr),
+		frFromBig(sb.SolutionID),
+		frFromBig(big.NewInt(int64(len(sb.Trades)))),
+		frFromBig(sb.TradesCommit),
+	)
 }
 
 var oneE18 = big.NewInt(1_000_000_000_000_000_000)
 
-func computeScoreNativeGo(limitSell, limitBuy, execSell, execBuy *big.Int, side int, priceE18 *big.Int) *big.Int {
+// computeScoreNativeGo mirrors computeSolutionScore: the surplus over the
+// limit price plus the protocol fee, converted to the buy token, in native units
+func computeScoreNativeGo(limitSell, limitBuy, execSell, execBuy *big.Int, side int, priceE18 *big.Int, fee FeeBuilt) *big.Int {
 	if limitSell.Sign() == 0 || limitBuy.Sign() == 0 {
 		return big.NewInt(0)
 	}
 
+	var surplus *big.Int
 	if side == 0 {
+		// surplus_buy = exec_buy - ceil(limit_buy * exec_sell / limit_sell)
 		num := new(big.Int).Mul(limitBuy, execSell)
-		partial := divCeilBig(num, limitSell)
+		surplus = new(big.Int).Sub(execBuy, divCeilBig(num, limitSell))
+	} else {
+		// surplus_sell = floor(limit_sell * exec_buy / limit_buy) - exec_sell
+		num := new(big.Int).Mul(limitSell, execBuy)
+		surplus = new(big.Int).Sub(new(big.Int).Div(num, limitBuy), execSell)
+	}
+	if surplus.Sign() < 0 {
+		surplus.SetInt64(0)
+	}
 
-		surplusBuy := new(big.Int).Sub(execBuy, partial)
-		if surplusBuy.Sign() <= 0 {
-			return big.NewInt(0)
-		}
-		// floor(surplusBuy * price / 1e18)
-		num2 := new(big.Int).Mul(surplusBuy, priceE18)
-		return new(big.Int).Div(num2, oneE18)
+	// the executed amount on the order's side, clamped to the order amount
+	filled, amount := execSell, limitSell
+	if side == 1 {
+		filled, amount = execBuy, limitBuy
+	}
+	if filled.Cmp(amount) > 0 {
+		filled = amount
 	}
 
-	// buy
-	// partialLimitSell = floor(limitSell * execBuy / limitBuy)
-	num := new(big.Int).Mul(limitSell, execBuy)
-	partialSell := new(big.Int).Div(num, limitBuy)
+	// the fee is owed even without surplus (volume fee)
+	surplus.Add(surplus, protocolFeeGo(fee, execSell, execBuy, filled, side, surplus))
 
-	surplusSell := new(big.Int).Sub(partialSell, execSell)
-	if surplusSell.Sign() <= 0 {
-		return big.NewInt(0)
+	if side == 1 {
+		// surplusBuyEquiv = floor((surplus_sell + fee) * limit_buy / limit_sell)
+		num := new(big.Int).Mul(surplus, limitBuy)
+		surplus = new(big.Int).Div(num, limitSell)
 	}
-	// surplusBuyEquiv = floor(surplusSell * limitBuy / limitSell)
-	num2 := new(big.Int).Mul(surplusSell, limitBuy)
-	surplusBuyEq := new(big.Int).Div(num2, limitSell)
 
-	num3 := new(big.Int).Mul(surplusBuyEq, priceE18)
-	return new(big.Int).Div(num3, oneE18)
+	// floor(surplus_buy * price / 1e18)
+	num := new(big.Int).Mul(surplus, priceE18)
+	return new(big.Int).Div(num, oneE18)
+}
+
+// checkUniformPrices mirrors uniformPriceViolations in the circuit: trades on
+// the same directed pair clear at the same price, up to one unit of rounding
+// on each executed amount
+func checkUniformPrices(sb SolnBuilt) error {
+	for a := range sb.Trades {
+		for b := a + 1; b < len(sb.Trades); b++ {
+			if sb.TradePairIdx[a] != sb.TradePairIdx[b] {
+				continue
+			}
+			x, y := sb.Trades[a], sb.Trades[b]
+			diff := new(big.Int).Sub(new(big.Int).Mul(x.ExecSell, y.ExecBuy), new(big.Int).Mul(y.ExecSell, x.ExecBuy))
+			tol := new(big.Int).Add(x.ExecSell, y.ExecSell)
+			tol.Add(tol, x.ExecBuy).Add(tol, y.ExecBuy)
+			if diff.CmpAbs(tol) > 0 {
+				return fmt.Errorf("trades %d and %d on the same pair clear at %s/%s and %s/%s", a, b, x.ExecBuy, x.ExecSell, y.ExecBuy, y.ExecSell)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTrade mirrors the trade validity checks of computeSolutionScore
+func validateTrade(tb TradeBuilt) error {
+	filled, amount, name := tb.ExecSell, tb.LimitSell, "sell"
+	if tb.Side == 1 {
+		filled, amount, name = tb.ExecBuy, tb.LimitBuy, "buy"
+	}
+	if filled.Cmp(amount) > 0 {
+		return fmt.Errorf("executed %s %s exceeds the order's %s", name, filled, amount)
+	}
+	if !tb.Partial && filled.Cmp(amount) != 0 {
+		return fmt.Errorf("fill-or-kill order executed for %s of %s %s", filled, name, amount)
+	}
+	if tb.Side == 0 {
+		if minBuy := divCeilBig(new(big.Int).Mul(tb.LimitBuy, tb.ExecSell), tb.LimitSell); tb.ExecBuy.Cmp(minBuy) < 0 {
+			return fmt.Errorf("limit price violated: executed buy %s < %s", tb.ExecBuy, minBuy)
+		}
+	} else {
+		if maxSell := new(big.Int).Div(new(big.Int).Mul(tb.LimitSell, tb.ExecBuy), tb.LimitBuy); tb.ExecSell.Cmp(maxSell) > 0 {
+			return fmt.Errorf("limit price violated: executed sell %s > %s", tb.ExecSell, maxSell)
+		}
+	}
+	return nil
 }
 
 func divCeilBig(a, b *big.Int) *big.Int {
@@ -795,6 +973,13 @@ func mustAddrToBig(hexAddr string) *big.Int {
 	return new(big.Int).SetBytes(b) // 160-bit fits fine
 }
 
+func boolToBig(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return big.NewInt(0)
+}
+
 func frFromBig(bi *big.Int) fr.Element {
 	var e fr.Element
 	e.SetBigInt(bi)
@@ -817,7 +1002,7 @@ func frMul(a, b fr.Element) fr.Element {
 	return z
 }
 
-// trade ID = keccak(uidBytes) mod Fr
+// trade ID of a hex order uid, see comb.TradeID
 func frFromKeccakHex(uidHex string) fr.Element {
 	hx := strings.TrimPrefix(uidHex, "0x")
 	raw, err := hex.DecodeString(hx)
@@ -825,13 +1010,7 @@ func frFromKeccakHex(uidHex string) fr.Element {
 		// fallback: hash string bytes if not hex
 		raw = []byte(uidHex)
 	}
-	h := sha3.NewLegacyKeccak256()
-	h.Write(raw)
-	d := h.Sum(nil)
-	r := fr.Modulus()
-	bi := new(big.Int).SetBytes(d)
-	bi.Mod(bi, r)
-	return frFromBig(bi)
+	return frFromBig(comb.TradeID(raw))
 }
 
 func fileExists(path string) bool {
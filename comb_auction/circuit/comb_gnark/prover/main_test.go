@@ -4,12 +4,18 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/big"
 	"os"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/test"
 	comb "github.com/cowprotocol/Zk-benchmark/comb_auction/gnark"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
 )
 
 func TestIsSolved(t *testing.T) {
@@ -35,7 +41,7 @@ func TestIsSolved(t *testing.T) {
 		t.Fatal("auction 12310253 not found")
 	}
 
-	assignment, _, err := buildWitnessForAuction(auc)
+	assignment, _, err := buildWitnessForAuction(auc, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -45,3 +51,797 @@ func TestIsSolved(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+const (
+	tokenA = "0x00000000000000000000000000000000000000a1"
+	tokenB = "0x00000000000000000000000000000000000000b2"
+	tokenC = "0x00000000000000000000000000000000000000c3"
+)
+
+// sell order filled at limit price plus surplus (in the buy token)
+func sellTrade(uid, sell, buy string, limitSell, limitBuy, surplus int64) Trade {
+	e18 := func(x int64) string { return new(big.Int).Mul(big.NewInt(x), oneE18).String() }
+	return Trade{
+		OrderUID:         uid,
+		SellToken:        sell,
+		BuyToken:         buy,
+		LimitSell:        e18(limitSell),
+		LimitBuy:         e18(limitBuy),
+		ExecSell:         e18(limitSell),
+		ExecBuy:          e18(limitBuy + surplus),
+		Side:             0,
+		BuyTokenPriceE18: oneE18.String(),
+	}
+}
+
+// two solutions compete on A->B, a third settles B->C and C->A
+func syntheticAuction() Auction {
+	return Auction{
+		AuctionID: 42,
+		Solutions: []Solution{
+			{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{
+				sellTrade("0x01", tokenA, tokenB, 100, 90, 5),
+			}},
+			{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+				sellTrade("0x01", tokenA, tokenB, 100, 90, 8),
+			}},
+			{SolutionUID: 3, Solver: "0x0000000000000000000000000000000000000003", Trades: []Trade{
+				sellTrade("0x02", tokenB, tokenC, 50, 40, 1),
+				sellTrade("0x03", tokenC, tokenA, 10, 10, 2),
+			}},
+		},
+	}
+}
+
+func TestSyntheticAuction(t *testing.T) {
+	assignment, _, err := buildWitnessForAuction(syntheticAuction(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+
+	// solution 2 beats 1 on A->B; 3 has no conflict
+	want := []struct{ solver, score int64 }{{2, 8}, {3, 3}}
+	if assignment.WinnersLen.(*big.Int).Int64() != int64(len(want)) {
+		t.Fatalf("%v winners", assignment.WinnersLen)
+	}
+	for i, w := range want {
+		got := assignment.Winners[i]
+		score := new(big.Int).Mul(big.NewInt(w.score), oneE18)
+		if got.Solver.(*big.Int).Int64() != w.solver || got.Score.(*big.Int).Cmp(score) != 0 {
+			t.Errorf("winner %d: solver %v score %v", i, got.Solver, got.Score)
+		}
+	}
+}
+
+func TestAmountWidth(t *testing.T) {
+	tr := sellTrade("0x01", tokenA, tokenB, 100, 90, 5)
+	tr.LimitSell = new(big.Int).Lsh(big.NewInt(1), comb.AMT_BITS).String()
+	if _, err := buildTrade(tr); err == nil {
+		t.Fatalf("limit_sell of %d bits accepted", comb.AMT_BITS+1)
+	}
+}
+
+// buy order filled at limit price with surplus in the sell token
+func buyTrade(uid, sell, buy string, limitSell, limitBuy, surplus int64) Trade {
+	tr := sellTrade(uid, sell, buy, limitSell, limitBuy, 0)
+	tr.Side = 1
+	tr.ExecSell = new(big.Int).Mul(big.NewInt(limitSell-surplus), oneE18).String()
+	return tr
+}
+
+// Solution 1 leaves less surplus on A->B than solution 2 but its order pays a
+// protocol fee, which counts towards the score. Solution 3 settles a buy order
+// with a volume fee on an unrelated pair.
+func TestFeeChangesRanking(t *testing.T) {
+	e18 := func(x int64) string { return new(big.Int).Mul(big.NewInt(x), oneE18).String() }
+	cases := []struct {
+		name   string
+		policy *FeePolicy
+		winner int64
+		score  string // of solution 1, if it wins
+	}{
+		{"no fee", nil, 2, ""},
+		// 95 * 0.1/0.9
+		{"volume", &FeePolicy{Kind: "volume", Factor: 100_000}, 1, "15555555555555555555"},
+		// 5 * 0.5/0.5 capped at 95 * 0.01/0.99
+		{"surplus capped", &FeePolicy{Kind: "surplus", Factor: 500_000, Cap: 10_000}, 2, ""},
+		{"surplus", &FeePolicy{Kind: "surplus", Factor: 500_000, Cap: 100_000}, 1, "10000000000000000000"},
+		// improvement over a quote of 93 is 2
+		{"price improvement", &FeePolicy{Kind: "priceimprovement", Factor: 500_000, Cap: 100_000, QuoteSell: e18(100), QuoteBuy: e18(93)}, 2, ""},
+		// improvement over a quote of 80 is 15, capped at 95 * 0.1/0.9
+		{"price improvement capped", &FeePolicy{Kind: "priceimprovement", Factor: 500_000, Cap: 100_000, QuoteSell: e18(100), QuoteBuy: e18(80)}, 1, "15555555555555555555"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			feeTrade := sellTrade("0x01", tokenA, tokenB, 100, 90, 5)
+			feeTrade.FeePolicy = c.policy
+			buy := buyTrade("0x05", tokenC, tokenB, 20, 10, 2)
+			buy.FeePolicy = &FeePolicy{Kind: "volume", Factor: 10_000}
+			auc := Auction{
+				AuctionID: 42,
+				Solutions: []Solution{
+					{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{feeTrade}},
+					{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+						sellTrade("0x04", tokenA, tokenB, 100, 90, 8),
+					}},
+					{SolutionUID: 3, Solver: "0x0000000000000000000000000000000000000003", Trades: []Trade{buy}},
+				},
+			}
+
+			assignment, _, err := buildWitnessForAuction(auc, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+
+			if assignment.WinnersLen.(*big.Int).Int64() != 2 {
+				t.Fatalf("%v winners", assignment.WinnersLen)
+			}
+			got := assignment.Winners[0]
+			if got.Solver.(*big.Int).Int64() != c.winner {
+				t.Fatalf("winner: solver %v score %v, want solver %d", got.Solver, got.Score, c.winner)
+			}
+			if c.score != "" && got.Score.(*big.Int).String() != c.score {
+				t.Errorf("score %v, want %s", got.Score, c.score)
+			}
+		})
+	}
+}
+
+// feeRankingFixture holds mainnet auctions, exported by data/fetch.py, whose
+// first winner changes when the protocol fees are left out of the scores
+const feeRankingFixture = "testdata/fee_ranking_auctions.json"
+
+// The fee policies of real orders change the winner of the fixture's auctions.
+// Only the prover's selection runs: the full circuit does not fit a test.
+func TestFeeChangesRankingFixture(t *testing.T) {
+	data, err := os.ReadFile(feeRankingFixture)
+	if err != nil {
+		t.Fatalf("%v: export auctions with data/fetch.py and keep those this test accepts", err)
+	}
+	var af AuctionsFile
+	if err := json.Unmarshal(data, &af); err != nil {
+		t.Fatal(err)
+	}
+	if len(af.Auctions) == 0 {
+		t.Fatal("no auctions in the fixture")
+	}
+
+	winner := func(auc Auction) *big.Int {
+		assignment, _, err := buildWitnessForAuction(auc, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assignment.WinnersLen.(*big.Int).Sign() == 0 {
+			t.Fatal("no winner")
+		}
+		return assignment.Winners[0].SolutionID.(*big.Int)
+	}
+	for _, auc := range af.Auctions {
+		t.Run(fmt.Sprint(auc.AuctionID), func(t *testing.T) {
+			policies := 0
+			noFees := auc
+			noFees.Solutions = make([]Solution, len(auc.Solutions))
+			for i, s := range auc.Solutions {
+				s.Trades = append([]Trade(nil), s.Trades...)
+				for k := range s.Trades {
+					if s.Trades[k].FeePolicy != nil {
+						policies++
+					}
+					s.Trades[k].FeePolicy = nil
+				}
+				noFees.Solutions[i] = s
+			}
+			if policies == 0 {
+				t.Fatal("no fee policies")
+			}
+			if with, without := winner(auc), winner(noFees); with.Cmp(without) == 0 {
+				t.Fatalf("solution_uid %v wins with and without fees", with)
+			}
+		})
+	}
+}
+
+func TestFeePolicyValidation(t *testing.T) {
+	for _, p := range []*FeePolicy{
+		{Kind: "rebate", Factor: 1},
+		{Kind: "surplus", Factor: comb.FEE_ONE, Cap: 1},
+		{Kind: "surplus", Factor: 1, Cap: -1},
+		{Kind: "priceimprovement", Factor: 1, Cap: 1},
+		{Kind: "priceimprovement", Factor: 1, Cap: 1, QuoteSell: "0", QuoteBuy: "1"},
+		{Kind: "priceimprovement", Factor: 1, Cap: 1, QuoteSell: "1e18", QuoteBuy: "1"},
+		{Kind: "priceimprovement", Factor: 1, Cap: 1, QuoteSell: "1", QuoteBuy: "0x10"},
+	} {
+		tr := sellTrade("0x01", tokenA, tokenB, 100, 90, 5)
+		tr.FeePolicy = p
+		if _, err := buildTrade(tr); err == nil {
+			t.Errorf("fee policy %+v accepted", *p)
+		}
+	}
+}
+
+// The autopilot must be able to post the bidset root without running the
+// auction: it depends neither on the order solutions arrive in nor on their scores.
+func TestBidsetRootOrderIndependent(t *testing.T) {
+	auc := syntheticAuction()
+	want, _, err := buildWitnessForAuction(auc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rev := auc
+	rev.Solutions = nil
+	for i := len(auc.Solutions) - 1; i >= 0; i-- {
+		rev.Solutions = append(rev.Solutions, auc.Solutions[i])
+	}
+	got, _, err := buildWitnessForAuction(rev, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BidsetRoot.(*big.Int).Cmp(want.BidsetRoot.(*big.Int)) != 0 {
+		t.Fatalf("root %v depends on the input order, want %v", got.BidsetRoot, want.BidsetRoot)
+	}
+
+	// the leaves are the solutions in commit order, with their own UIDs
+	var leaves []SolnBuilt
+	for _, s := range auc.Solutions {
+		sb, err := buildOneSolution(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, sb)
+	}
+	for i := range leaves {
+		if leaves[i].TradesCommit, err = tradesCommit(leaves[i]); err != nil {
+			t.Fatal(err)
+		}
+		leaves[i].Commit = frToBig(solutionLeaf(leaves[i]))
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Commit.Cmp(leaves[j].Commit) < 0 })
+	prices, err := buildPriceVector(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := computeBidsetRoot(big.NewInt(int64(auc.AuctionID)), leaves, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Cmp(want.BidsetRoot.(*big.Int)) != 0 {
+		t.Fatalf("root %v, want %v", root, want.BidsetRoot)
+	}
+}
+
+// The bidset root hashes the auction id, so a proof for one auction does not
+// verify against another auction's id, even with the same solutions.
+func TestAuctionIDBound(t *testing.T) {
+	auc := syntheticAuction()
+	assignment, _, err := buildWitnessForAuction(auc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := auc
+	other.AuctionID++
+	otherAsn, _, err := buildWitnessForAuction(other, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherAsn.BidsetRoot.(*big.Int).Cmp(assignment.BidsetRoot.(*big.Int)) == 0 {
+		t.Fatal("bidset root does not depend on the auction id")
+	}
+
+	assignment.AuctionID = big.NewInt(int64(other.AuctionID))
+	if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("witness with another auction id accepted")
+	}
+}
+
+// ByScore must be a reordering of the committed solutions
+func TestByScorePermutation(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		tamper func(*comb.Circuit)
+	}{
+		{"duplicate", func(a *comb.Circuit) { a.ByScore[2] = a.ByScore[1] }},
+		{"score", func(a *comb.Circuit) {
+			a.ByScore[0].Score = new(big.Int).Add(a.ByScore[0].Score.(*big.Int), big.NewInt(1))
+		}},
+		{"pair key", func(a *comb.Circuit) { a.ByScore[2].PairKey[0] = big.NewInt(7) }},
+		{"bidset order", func(a *comb.Circuit) { a.Solutions[0], a.Solutions[1] = a.Solutions[1], a.Solutions[0] }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, _, err := buildWitnessForAuction(syntheticAuction(), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.tamper(assignment)
+			if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+				t.Fatal("tampered witness accepted")
+			}
+		})
+	}
+}
+
+// Three solutions with equal scores on the same pair: the circuit orders ties
+// by descending leaf commit, so the prover must too, whatever the input order.
+// The winner is reported with its autopilot solution_uid.
+func TestTiedScores(t *testing.T) {
+	auc := Auction{AuctionID: 42}
+	for i, uid := range []int{7001, 7002, 7003} {
+		auc.Solutions = append(auc.Solutions, Solution{
+			SolutionUID: uid,
+			Solver:      fmt.Sprintf("0x%040x", i+1),
+			Trades:      []Trade{sellTrade(fmt.Sprintf("0x%02x", i+1), tokenA, tokenB, 100, 90, 5)},
+		})
+	}
+
+	var wantUID int
+	var best *big.Int
+	for _, s := range auc.Solutions {
+		sb, err := buildOneSolution(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sb.TradesCommit, err = tradesCommit(sb); err != nil {
+			t.Fatal(err)
+		}
+		if c := frToBig(solutionLeaf(sb)); best == nil || c.Cmp(best) > 0 {
+			best, wantUID = c, s.SolutionUID
+		}
+	}
+
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}} {
+		shuffled := auc
+		shuffled.Solutions = nil
+		for _, i := range order {
+			shuffled.Solutions = append(shuffled.Solutions, auc.Solutions[i])
+		}
+		assignment, _, err := buildWitnessForAuction(shuffled, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("order %v: %v", order, err)
+		}
+		if assignment.WinnersLen.(*big.Int).Int64() != 1 {
+			t.Fatalf("order %v: %v winners", order, assignment.WinnersLen)
+		}
+		if got := assignment.Winners[0].SolutionID.(*big.Int); got.Int64() != int64(wantUID) {
+			t.Fatalf("order %v: winner solution_uid %v, want %d", order, got, wantUID)
+		}
+	}
+}
+
+// Solution 1 would win A->B with 8 over solution 2's 5, but one of its trades
+// does not respect its order: it must be excluded, not just score 0 on it.
+func TestInvalidTrades(t *testing.T) {
+	e18 := func(x int64) string { return new(big.Int).Mul(big.NewInt(x), oneE18).String() }
+	huge := new(big.Int).Lsh(big.NewInt(1), comb.AMT_BITS-1).String()
+	cases := []struct {
+		name   string
+		edit   func(*Trade)
+		reason string // "" if valid
+	}{
+		{"valid", func(*Trade) {}, ""},
+		{"over-filled sell", func(tr *Trade) { tr.ExecSell, tr.ExecBuy = e18(110), e18(110) }, "exceeds"},
+		{"over-filled by far", func(tr *Trade) { tr.ExecSell, tr.ExecBuy = huge, huge }, "exceeds"},
+		// the quote prices the buy token far above the limit: without clamping the
+		// executed amount the quoted amount would not fit AMT_BITS+1
+		{"over-filled price improvement", func(tr *Trade) {
+			tr.ExecSell, tr.ExecBuy = huge, huge
+			tr.FeePolicy = &FeePolicy{Kind: "priceimprovement", Factor: 500_000, Cap: 10_000, QuoteSell: "1000", QuoteBuy: e18(1)}
+		}, "exceeds"},
+		{"limit price", func(tr *Trade) { tr.ExecBuy = e18(89) }, "limit price"},
+		{"fill-or-kill", func(tr *Trade) { tr.ExecSell, tr.ExecBuy = e18(50), e18(50) }, "fill-or-kill"},
+		{"partially fillable", func(tr *Trade) { tr.ExecSell, tr.ExecBuy, tr.PartiallyFillable = e18(50), e18(50), true }, ""},
+		{"partial limit price", func(tr *Trade) { tr.ExecSell, tr.ExecBuy, tr.PartiallyFillable = e18(50), e18(44), true }, "limit price"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bad := sellTrade("0x01", tokenA, tokenB, 100, 90, 8)
+			c.edit(&bad)
+			auc := Auction{
+				AuctionID: 42,
+				Solutions: []Solution{
+					{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{
+						bad, sellTrade("0x05", tokenB, tokenC, 10, 10, 1),
+					}},
+					{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+						sellTrade("0x04", tokenA, tokenB, 100, 90, 5),
+					}},
+				},
+			}
+
+			sb, err := buildOneSolution(auc.Solutions[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.reason == "" && sb.Invalid != nil {
+				t.Fatalf("excluded: %v", sb.Invalid)
+			}
+			if c.reason != "" && (sb.Invalid == nil || !strings.Contains(sb.Invalid.Error(), c.reason)) {
+				t.Fatalf("reason %v, want %q", sb.Invalid, c.reason)
+			}
+
+			assignment, _, err := buildWitnessForAuction(auc, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+			want := int64(1)
+			if c.reason != "" {
+				want = 2
+			}
+			if got := assignment.Winners[0].SolutionID.(*big.Int).Int64(); got != want {
+				t.Fatalf("winner solution_uid %d, want %d", got, want)
+			}
+
+			// the validity flag is bound to the committed trades
+			if c.reason != "" {
+				for i := range assignment.ByScore {
+					if assignment.ByScore[i].SolutionID.(*big.Int).Int64() == 1 {
+						assignment.ByScore[i].Valid = big.NewInt(1)
+					}
+				}
+				if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+					t.Fatal("invalid solution accepted as valid")
+				}
+			}
+		})
+	}
+}
+
+// Every trade is scored at the auction's single price for its buy token
+func TestPriceVector(t *testing.T) {
+	auc := syntheticAuction()
+	auc.Solutions[0].Trades[0].BuyTokenPriceE18 = "2000000000000000000"
+	if _, _, err := buildWitnessForAuction(auc, Options{}); err == nil || !strings.Contains(err.Error(), "native prices") {
+		t.Fatalf("conflicting prices for one token: %v", err)
+	}
+
+	for _, c := range []struct {
+		name   string
+		tamper func(*comb.Circuit)
+	}{
+		{"price", func(a *comb.Circuit) { a.Prices[0].PriceE18 = big.NewInt(1) }},
+		{"other token's entry", func(a *comb.Circuit) {
+			idx := a.Solutions[0].TradePriceIdx[0].(*big.Int).Int64()
+			a.Solutions[0].TradePriceIdx[0] = big.NewInt((idx + 1) % 3)
+		}},
+		{"padding entry", func(a *comb.Circuit) { a.Solutions[0].TradePriceIdx[0] = big.NewInt(3) }},
+		{"unsorted", func(a *comb.Circuit) { a.Prices[0], a.Prices[1] = a.Prices[1], a.Prices[0] }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, _, err := buildWitnessForAuction(syntheticAuction(), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if assignment.PricesLen.(*big.Int).Int64() != 3 {
+				t.Fatalf("%v prices, want 3", assignment.PricesLen)
+			}
+			c.tamper(assignment)
+			if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+				t.Fatal("tampered prices accepted")
+			}
+		})
+	}
+}
+
+// Solution 1 settles two A->B orders. With uniform prices enforced it is only
+// valid if both clear at the same price, up to rounding; otherwise solution 2
+// wins the pair.
+func TestUniformPrices(t *testing.T) {
+	dec := func(x string) string { v, _ := new(big.Int).SetString(x, 10); return v.String() }
+	cases := []struct {
+		name      string
+		sell, buy string // executed amounts of the second trade
+		uniform   bool
+	}{
+		{"same price", "10000000000000000000", "9500000000000000000", true},
+		// floor((1e19+7) * 0.95)
+		{"rounded", "10000000000000000007", "9500000000000000006", true},
+		{"better price", "10000000000000000000", "9600000000000000000", false},
+	}
+	for _, c := range cases {
+		for _, enforce := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/enforce=%v", c.name, enforce), func(t *testing.T) {
+				second := sellTrade("0x02", tokenA, tokenB, 10, 9, 0)
+				second.LimitSell, second.ExecSell, second.ExecBuy = dec(c.sell), dec(c.sell), dec(c.buy)
+				auc := Auction{
+					AuctionID: 42,
+					Solutions: []Solution{
+						{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{
+							sellTrade("0x01", tokenA, tokenB, 100, 90, 5), second,
+						}},
+						{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+							sellTrade("0x03", tokenA, tokenB, 100, 90, 5),
+						}},
+					},
+				}
+				assignment, _, err := buildWitnessForAuction(auc, Options{UniformPrices: enforce})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := test.IsSolved(&comb.Circuit{UniformPrices: enforce}, assignment, ecc.BN254.ScalarField()); err != nil {
+					t.Fatal(err)
+				}
+				want := int64(1)
+				if enforce && !c.uniform {
+					want = 2
+				}
+				if got := assignment.Winners[0].SolutionID.(*big.Int).Int64(); got != want {
+					t.Fatalf("winner solution_uid %d, want %d", got, want)
+				}
+			})
+		}
+	}
+}
+
+// A solution listing the same order twice would double its surplus and beat
+// solution 2; it is excluded instead, and cannot be passed off as valid.
+func TestDuplicateTrade(t *testing.T) {
+	dup := sellTrade("0x01", tokenA, tokenB, 100, 90, 5)
+	auc := Auction{
+		AuctionID: 42,
+		Solutions: []Solution{
+			{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{
+				dup, sellTrade("0x07", tokenB, tokenC, 10, 10, 1), dup,
+			}},
+			{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+				sellTrade("0x04", tokenA, tokenB, 100, 90, 8),
+			}},
+		},
+	}
+
+	sb, err := buildOneSolution(auc.Solutions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.Invalid == nil || !strings.Contains(sb.Invalid.Error(), "settled twice") {
+		t.Fatalf("reason %v", sb.Invalid)
+	}
+	for i := 1; i < len(sb.Trades); i++ {
+		if sb.Trades[i-1].TradeID.Cmp(sb.Trades[i].TradeID) > 0 {
+			t.Fatalf("trades not sorted by ID")
+		}
+	}
+
+	assignment, _, err := buildWitnessForAuction(auc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	if got := assignment.Winners[0].SolutionID.(*big.Int).Int64(); got != 2 {
+		t.Fatalf("winner solution_uid %d, want 2", got)
+	}
+
+	for i := range assignment.ByScore {
+		if assignment.ByScore[i].SolutionID.(*big.Int).Int64() == 1 {
+			assignment.ByScore[i].Valid = big.NewInt(1)
+		}
+	}
+	if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("solution with a repeated trade accepted as valid")
+	}
+}
+
+// Solution 2 wins A->B over solution 1 and solution 3 settles B->C, C->A:
+// without solver 2, solution 1 wins A->B instead (8 = 5 + 3); without solver
+// 3, nothing replaces it (8). Both are paid 11 - 8.
+func TestSolverRewards(t *testing.T) {
+	assignment, _, err := buildWitnessForAuction(syntheticAuction(), Options{SolverRewards: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e18 := func(x int64) *big.Int { return new(big.Int).Mul(big.NewInt(x), oneE18) }
+	for w, want := range []struct{ solutionID, reference, reward *big.Int }{
+		{big.NewInt(2), e18(8), e18(3)},
+		{big.NewInt(3), e18(8), e18(3)},
+	} {
+		got := assignment.Winners[w]
+		if got.SolutionID.(*big.Int).Cmp(want.solutionID) != 0 ||
+			got.Reference.(*big.Int).Cmp(want.reference) != 0 ||
+			got.Reward.(*big.Int).Cmp(want.reward) != 0 {
+			t.Fatalf("winner %d: solution_uid %v reference %v reward %v", w, got.SolutionID, got.Reference, got.Reward)
+		}
+	}
+
+	circuit := &comb.Circuit{SolverRewards: true}
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	assignment.Winners[1].Reward = e18(4)
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("inflated reward accepted")
+	}
+}
+
+// Solver 1's single-pair solution sets the A->B baseline that filters out
+// solver 2's two-pair solution. Without solver 1 the filter is rerun: solver 2's
+// solution survives and beats solver 3's, so solver 1's reference is 12, not 5.
+func TestSolverRewardsBaselineRerun(t *testing.T) {
+	auc := Auction{
+		AuctionID: 42,
+		Solutions: []Solution{
+			{SolutionUID: 1, Solver: "0x0000000000000000000000000000000000000001", Trades: []Trade{
+				sellTrade("0x01", tokenA, tokenB, 100, 90, 10),
+			}},
+			{SolutionUID: 2, Solver: "0x0000000000000000000000000000000000000002", Trades: []Trade{
+				sellTrade("0x02", tokenA, tokenB, 100, 90, 6),
+				sellTrade("0x03", tokenB, tokenC, 100, 90, 6),
+			}},
+			{SolutionUID: 3, Solver: "0x0000000000000000000000000000000000000003", Trades: []Trade{
+				sellTrade("0x04", tokenB, tokenC, 100, 90, 5),
+			}},
+		},
+	}
+	assignment, _, err := buildWitnessForAuction(auc, Options{SolverRewards: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e18 := func(x int64) *big.Int { return new(big.Int).Mul(big.NewInt(x), oneE18) }
+	for w, want := range []struct{ solutionID, reference, reward *big.Int }{
+		{big.NewInt(1), e18(12), e18(3)},
+		{big.NewInt(3), e18(10), e18(5)},
+	} {
+		got := assignment.Winners[w]
+		if got.SolutionID.(*big.Int).Cmp(want.solutionID) != 0 ||
+			got.Reference.(*big.Int).Cmp(want.reference) != 0 ||
+			got.Reward.(*big.Int).Cmp(want.reward) != 0 {
+			t.Fatalf("winner %d: solution_uid %v reference %v reward %v", w, got.SolutionID, got.Reference, got.Reward)
+		}
+	}
+
+	circuit := &comb.Circuit{SolverRewards: true}
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	// the reference among the same survivors
+	assignment.Winners[0].Reference, assignment.Winners[0].Reward = e18(5), e18(10)
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("reference without the rerun filter accepted")
+	}
+}
+
+// The settlement of winner 3 matches its public trades commit whatever the
+// trade order; settling more than the solution proposed does not.
+func TestWinnerTradesCommit(t *testing.T) {
+	auc := syntheticAuction()
+	assignment, _, err := buildWitnessForAuction(auc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	winner := assignment.Winners[1]
+	if winner.SolutionID.(*big.Int).Int64() != 3 {
+		t.Fatalf("winner 1 is solution_uid %v", winner.SolutionID)
+	}
+
+	proposed := auc.Solutions[2].Trades
+	var settled []comb.SettledTrade
+	for _, tr := range []Trade{proposed[1], proposed[0]} {
+		tb, err := buildTrade(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settled = append(settled, settledTrade(tb))
+	}
+	got, err := comb.TradesCommitOf(settled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(winner.TradesCommit.(*big.Int)) != 0 {
+		t.Fatalf("settlement commit %v, proven %v", got, winner.TradesCommit)
+	}
+
+	settled[0].ExecutedSell = new(big.Int).Mul(big.NewInt(11), oneE18)
+	if got, _ := comb.TradesCommitOf(settled); got.Cmp(winner.TradesCommit.(*big.Int)) == 0 {
+		t.Fatal("different executed amounts, same commit")
+	}
+	settled[0].ExecutedSell = new(big.Int).Lsh(big.NewInt(1), comb.AMT_BITS)
+	if _, err := comb.TradesCommitOf(settled); err == nil {
+		t.Fatal("executed amount wider than AMT_BITS hashed")
+	}
+
+	// the commit is bound to the winning solution
+	assignment.Winners[1].TradesCommit = assignment.Winners[0].TradesCommit
+	if err := test.IsSolved(&comb.Circuit{}, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("winner with another solution's trades commit accepted")
+	}
+}
+
+// legacyTradesCommit is the trades commit bidset leaves used to hold:
+// Σ field_k·r^k over the trade fields, r = H(auction_id || 0xA11CE001)
+func legacyTradesCommit(auctionID int64, trades []TradeBuilt) fr.Element {
+	r := merkle.HashElems(frFromBig(big.NewInt(auctionID)), frFromBig(big.NewInt(0xA11CE001)))
+	var acc, pow, term fr.Element
+	pow.SetOne()
+	for t := 0; t < comb.TMax; t++ {
+		fields := make([]fr.Element, 15)
+		if t < len(trades) {
+			tr := trades[t]
+			for k, v := range []*big.Int{
+				tr.TradeID, tr.SellToken, tr.BuyToken,
+				tr.LimitSell, tr.LimitBuy, tr.ExecSell, tr.ExecBuy,
+				big.NewInt(int64(tr.Side)), tr.PriceE18,
+				big.NewInt(int64(tr.Fee.Kind)), tr.Fee.Factor, tr.Fee.Cap, tr.Fee.QuoteSell, tr.Fee.QuoteBuy,
+				boolToBig(tr.Partial),
+			} {
+				fields[k] = frFromBig(v)
+			}
+		}
+		for k := range fields {
+			term.Mul(&fields[k], &pow)
+			acc.Add(&acc, &term)
+			pow.Mul(&pow, &r)
+		}
+	}
+	return acc
+}
+
+// With r known from the auction ID alone, a prover could change a committed
+// trade without changing its leaf: here it raises the executed buy amount,
+// and so the score, and absorbs the difference in the trade ID, which only
+// has to fit in TRADE_ID_BITS.
+func TestTradesCommitCollision(t *testing.T) {
+	const auctionID = 42
+	sb, err := buildOneSolution(syntheticAuction().Solutions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	honest := sb.Trades[0]
+
+	// ExecBuy is field 6 of trade 0: ID' = ID - δ·r^6 keeps the sum
+	r := merkle.HashElems(frFromBig(big.NewInt(auctionID)), frFromBig(big.NewInt(0xA11CE001)))
+	var r6 fr.Element
+	r6.Exp(r, big.NewInt(6))
+	idBound := new(big.Int).Lsh(big.NewInt(1), comb.TRADE_ID_BITS)
+
+	crafted := honest
+	for k := int64(1); ; k++ {
+		if k > 10_000 {
+			t.Fatal("no ID in range")
+		}
+		delta := new(big.Int).Mul(big.NewInt(k), oneE18)
+		var d, id fr.Element
+		d.SetBigInt(delta)
+		d.Mul(&d, &r6)
+		id = frFromBig(honest.TradeID)
+		id.Sub(&id, &d)
+		if frToBig(id).Cmp(idBound) < 0 {
+			crafted.TradeID = frToBig(id)
+			crafted.ExecBuy = new(big.Int).Add(honest.ExecBuy, delta)
+			break
+		}
+	}
+	score := func(tr TradeBuilt) *big.Int {
+		return computeScoreNativeGo(tr.LimitSell, tr.LimitBuy, tr.ExecSell, tr.ExecBuy, tr.Side, tr.PriceE18, tr.Fee)
+	}
+	if score(crafted).Cmp(score(honest)) <= 0 {
+		t.Fatalf("crafted score %v, honest %v", score(crafted), score(honest))
+	}
+
+	forged := sb
+	forged.Trades = []TradeBuilt{crafted}
+	if a, b := legacyTradesCommit(auctionID, sb.Trades), legacyTradesCommit(auctionID, forged.Trades); !a.Equal(&b) {
+		t.Fatal("legacy commits differ")
+	}
+	a, err := tradesCommit(sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := tradesCommit(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Cmp(b) == 0 {
+		t.Fatal("crafted trades hash to the committed value")
+	}
+}
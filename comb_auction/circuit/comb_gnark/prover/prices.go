@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	comb "github.com/cowprotocol/Zk-benchmark/comb_auction/gnark"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
+)
+
+// priceVector is the auction's native price per buy token, tokens ascending.
+// It is derived from the trades, which must agree on each token's price.
+type priceVector struct {
+	Tokens []*big.Int
+	Prices []*big.Int
+	index  map[string]int
+}
+
+func buildPriceVector(built []SolnBuilt) (priceVector, error) {
+	byToken := map[string]*big.Int{}
+	tokens := map[string]*big.Int{}
+	for _, sb := range built {
+		for _, tr := range sb.Trades {
+			k := tr.BuyToken.String()
+			if p, ok := byToken[k]; ok && p.Cmp(tr.PriceE18) != 0 {
+				return priceVector{}, fmt.Errorf("token %#x has native prices %s and %s", tr.BuyToken, p, tr.PriceE18)
+			}
+			byToken[k] = tr.PriceE18
+			tokens[k] = tr.BuyToken
+		}
+	}
+	if len(byToken) > comb.PriceMax {
+		return priceVector{}, fmt.Errorf("auction prices %d tokens > PriceMax=%d; cannot prove", len(byToken), comb.PriceMax)
+	}
+
+	pv := priceVector{index: map[string]int{}}
+	for _, t := range tokens {
+		pv.Tokens = append(pv.Tokens, t)
+	}
+	sort.Slice(pv.Tokens, func(i, j int) bool { return pv.Tokens[i].Cmp(pv.Tokens[j]) < 0 })
+	for i, t := range pv.Tokens {
+		pv.Prices = append(pv.Prices, byToken[t.String()])
+		pv.index[t.String()] = i
+	}
+	return pv, nil
+}
+
+// Index returns the entry of token, which must be in the vector
+func (pv priceVector) Index(token *big.Int) int {
+	return pv.index[token.String()]
+}
+
+// Commit mirrors commitPrices in the circuit
+func (pv priceVector) Commit() fr.Element {
+	elems := []fr.Element{frFromBig(big.NewInt(999002)), frFromBig(big.NewInt(int64(len(pv.Tokens))))}
+	for k := 0; k < comb.PriceMax; k++ {
+		var tok, price fr.Element
+		if k < len(pv.Tokens) {
+			tok, price = frFromBig(pv.Tokens[k]), frFromBig(pv.Prices[k])
+		}
+		elems = append(elems, tok, price)
+	}
+	return merkle.HashElems(elems...)
+}
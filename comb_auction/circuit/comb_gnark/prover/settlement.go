@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/big"
+
+	comb "github.com/cowprotocol/Zk-benchmark/comb_auction/gnark"
+)
+
+// settledTrade is tb as comb.TradesCommitOf takes it
+func settledTrade(tb TradeBuilt) comb.SettledTrade {
+	return comb.SettledTrade{
+		ID:           tb.TradeID,
+		SellToken:    tb.SellToken,
+		BuyToken:     tb.BuyToken,
+		Side:         tb.Side,
+		ExecutedSell: tb.ExecSell,
+		ExecutedBuy:  tb.ExecBuy,
+		Order: comb.OrderTerms{
+			SellAmount:        tb.LimitSell,
+			BuyAmount:         tb.LimitBuy,
+			PartiallyFillable: tb.Partial,
+			NativePriceBuy:    tb.PriceE18,
+			FeeKind:           tb.Fee.Kind,
+			FeeFactor:         tb.Fee.Factor,
+			FeeCap:            tb.Fee.Cap,
+			QuoteSell:         tb.Fee.QuoteSell,
+			QuoteBuy:          tb.Fee.QuoteBuy,
+		},
+	}
+}
+
+// tradesCommit is the TradesCommit of sb's leaf, see hashTrades in the circuit
+func tradesCommit(sb SolnBuilt) (*big.Int, error) {
+	trades := make([]comb.SettledTrade, len(sb.Trades))
+	for i, tb := range sb.Trades {
+		trades[i] = settledTrade(tb)
+	}
+	return comb.TradesCommitOf(trades)
+}
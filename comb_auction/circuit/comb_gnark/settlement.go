@@ -0,0 +1,139 @@
+package comb_gnark
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
+)
+
+// SettledTrade is one trade of a winner's settlement, with the order terms the
+// circuit scored it on. TradesCommitOf hashes exactly these fields.
+type SettledTrade struct {
+	// from the settlement
+	ID           *big.Int // TradeID of the order uid
+	SellToken    *big.Int // token addresses
+	BuyToken     *big.Int
+	Side         int // order kind: 0 sell, 1 buy
+	ExecutedSell *big.Int
+	ExecutedBuy  *big.Int
+
+	// from the auction
+	Order OrderTerms
+}
+
+// OrderTerms are the fields of a settled trade that the settlement does not
+// carry: the order's limit amounts and fill mode, the native price the auction
+// scores the buy token at and the order's protocol fee policy.
+type OrderTerms struct {
+	SellAmount        *big.Int // limit amounts
+	BuyAmount         *big.Int
+	PartiallyFillable bool
+	NativePriceBuy    *big.Int // 1e18 per native token
+
+	FeeKind   int      // FeeNone, FeeSurplus, FeeVolume or FeePriceImprovement
+	FeeFactor *big.Int // parts per FEE_ONE, 0 without a policy
+	FeeCap    *big.Int
+	QuoteSell *big.Int // quoted amounts, price improvement only, else 0
+	QuoteBuy  *big.Int
+}
+
+// TradeID is the circuit's ID of an order: the low TRADE_ID_BITS bits of the
+// Keccak of its uid, which the circuit can compare
+func TradeID(orderUID []byte) *big.Int {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(orderUID)
+	id := new(big.Int).SetBytes(h.Sum(nil))
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), TRADE_ID_BITS), big.NewInt(1))
+	return id.And(id, mask)
+}
+
+// TradesCommitOf is the TradesCommit a winner exposes, computed from the trades
+// of its settlement in any order. A settlement contract or monitor compares it
+// to the proven winners' to check that exactly the winning solution's trades
+// were executed. It mirrors hashTrades.
+func TradesCommitOf(trades []SettledTrade) (*big.Int, error) {
+	if len(trades) > TMax {
+		return nil, fmt.Errorf("trades=%d > TMax=%d", len(trades), TMax)
+	}
+	for i, tr := range trades {
+		if err := tr.check(); err != nil {
+			return nil, fmt.Errorf("trade %d: %w", i, err)
+		}
+	}
+	sorted := append([]SettledTrade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID.Cmp(sorted[j].ID) < 0 })
+
+	pack := func(lo, hi *big.Int, shift uint) fr.Element {
+		return frOf(new(big.Int).Add(lo, new(big.Int).Lsh(hi, shift)))
+	}
+	elems := make([]fr.Element, 0, 7*TMax)
+	for t := 0; t < TMax; t++ {
+		if t >= len(sorted) {
+			elems = append(elems, make([]fr.Element, 7)...)
+			continue
+		}
+		tr, o := sorted[t], sorted[t].Order
+		flags := big.NewInt(int64(tr.Side))
+		if o.PartiallyFillable {
+			flags.SetBit(flags, 1, 1)
+		}
+		flags.Or(flags, new(big.Int).Lsh(big.NewInt(int64(o.FeeKind)), 2))
+		flags.Or(flags, new(big.Int).Lsh(o.FeeFactor, 4))
+		flags.Or(flags, new(big.Int).Lsh(o.FeeCap, 4+FEE_BITS))
+		elems = append(elems,
+			frOf(tr.ID),
+			pack(tr.SellToken, flags, 160),
+			frOf(tr.BuyToken),
+			frOf(o.NativePriceBuy),
+			pack(o.SellAmount, o.BuyAmount, AMT_BITS),
+			pack(tr.ExecutedSell, tr.ExecutedBuy, AMT_BITS),
+			pack(o.QuoteSell, o.QuoteBuy, AMT_BITS),
+		)
+	}
+	h := merkle.HashElems(elems...)
+	return h.BigInt(new(big.Int)), nil
+}
+
+// check enforces the widths hashTrades range checks, which make the packing injective
+func (tr SettledTrade) check() error {
+	if tr.Side != 0 && tr.Side != 1 {
+		return fmt.Errorf("side %d not 0 or 1", tr.Side)
+	}
+	if tr.Order.FeeKind < FeeNone || tr.Order.FeeKind > FeePriceImprovement {
+		return fmt.Errorf("unknown fee kind %d", tr.Order.FeeKind)
+	}
+	for _, f := range []struct {
+		name string
+		v    *big.Int
+		bits int
+	}{
+		{"id", tr.ID, TRADE_ID_BITS},
+		{"sell_token", tr.SellToken, 160},
+		{"buy_token", tr.BuyToken, 160},
+		{"executed_sell", tr.ExecutedSell, AMT_BITS},
+		{"executed_buy", tr.ExecutedBuy, AMT_BITS},
+		{"sell_amount", tr.Order.SellAmount, AMT_BITS},
+		{"buy_amount", tr.Order.BuyAmount, AMT_BITS},
+		{"native_price_buy", tr.Order.NativePriceBuy, PRICE_BITS},
+		{"fee_factor", tr.Order.FeeFactor, FEE_BITS},
+		{"fee_cap", tr.Order.FeeCap, FEE_BITS},
+		{"quote_sell", tr.Order.QuoteSell, AMT_BITS},
+		{"quote_buy", tr.Order.QuoteBuy, AMT_BITS},
+	} {
+		if f.v == nil || f.v.Sign() < 0 || f.v.BitLen() > f.bits {
+			return fmt.Errorf("%s %v not in [0, 2^%d)", f.name, f.v, f.bits)
+		}
+	}
+	return nil
+}
+
+func frOf(v *big.Int) fr.Element {
+	var e fr.Element
+	e.SetBigInt(v)
+	return e
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -17,13 +18,16 @@ import (
 )
 
 func main() {
-	if err := run(); err != nil {
+	uniformPrices := flag.Bool("uniform_prices", false, "enforce uniform clearing prices per directed pair")
+	solverRewards := flag.Bool("solver_rewards", false, "prove per-winner reference scores and rewards")
+	flag.Parse()
+
+	if err := run(comb_auction.Circuit{UniformPrices: *uniformPrices, SolverRewards: *solverRewards}); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run() error {
-	var circuit comb_auction.Circuit
+func run(circuit comb_auction.Circuit) error {
 	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit)
 	if err != nil {
 		return fmt.Errorf("compile: %w", err)
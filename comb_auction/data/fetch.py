@@ -45,6 +45,89 @@ def _hex_uid(raw: bytes) -> str:
     return "0x" + raw.hex()
 
 
+FEE_ONE = 1_000_000  # fee factors are parts per million, as in the circuit
+
+
+def _ppm(factor: Any) -> int:
+    """Fee factor in [0, 1) as parts per million, rounded down."""
+    return math.floor(Fraction(str(factor)) * FEE_ONE)
+
+
+def fee_policy_of(r: Any) -> Dict[str, Any] | None:
+    """
+    The trade's fee policy in the prover's JSON form, or None without one.
+    Price improvement quotes include the quote's network fee in the sell amount,
+    like the autopilot.
+    """
+    if r.fee_kind is None:
+        return None
+    kind = str(r.fee_kind)
+    if kind == "surplus":
+        return {
+            "kind": kind,
+            "factor": _ppm(r.surplus_factor),
+            "cap": _ppm(r.surplus_max_volume_factor),
+        }
+    if kind == "volume":
+        return {"kind": kind, "factor": _ppm(r.volume_factor)}
+    if kind == "priceimprovement":
+        if r.quote_sell_amount is None:
+            raise ValueError("price improvement fee policy without an order quote")
+        quote_fee = math.floor(
+            Fraction(str(r.quote_gas_amount)) * Fraction(str(r.quote_gas_price)) / Fraction(str(r.quote_sell_token_price))
+        )
+        return {
+            "kind": kind,
+            "factor": _ppm(r.price_improvement_factor),
+            "cap": _ppm(r.price_improvement_max_volume_factor),
+            "quote_sell": str(int(r.quote_sell_amount) + quote_fee),
+            "quote_buy": str(int(r.quote_buy_amount)),
+        }
+    raise ValueError(f"Unexpected fee_policies.kind={kind!r}")
+
+
+def protocol_fee(
+    *,
+    exec_sell: int,
+    exec_buy: int,
+    kind: str,
+    surplus: int,
+    fee_policy: Dict[str, Any] | None,
+) -> int:
+    """
+    Protocol fee in the surplus token (buy token for sell orders, sell token for
+    buy orders), as in the circuit's computeProtocolFee:
+
+      volume:            volume * f/(1 -+ f)
+      surplus:           min(surplus * f/(1-f), volume * cap/(1 -+ cap))
+      price improvement: same as surplus, on the surplus over the quote
+
+    volume is exec_buy for sell orders (-) and exec_sell for buy orders (+).
+    """
+    if fee_policy is None:
+        return 0
+    is_sell = kind == "sell"
+    volume = exec_buy if is_sell else exec_sell
+    fee_kind = fee_policy["kind"]
+    factor = fee_policy["factor"]
+    vf = factor if fee_kind == "volume" else fee_policy.get("cap", 0)
+    volume_fee = volume * vf // (FEE_ONE - vf if is_sell else FEE_ONE + vf)
+    if fee_kind == "volume":
+        return volume_fee
+
+    base = surplus
+    if fee_kind == "priceimprovement":
+        quote_sell = int(fee_policy["quote_sell"])
+        quote_buy = int(fee_policy["quote_buy"])
+        if is_sell:
+            base = exec_buy - math.ceil(Fraction(quote_buy * exec_sell, quote_sell))
+        else:
+            base = quote_sell * exec_buy // quote_buy - exec_sell
+        base = max(base, 0)
+    surplus_fee = base * factor // (FEE_ONE - factor)
+    return min(surplus_fee, volume_fee)
+
+
 def compute_score_native(
     *,
     limit_sell: int,
@@ -53,37 +136,41 @@ def compute_score_native(
     exec_buy: int,
     kind: str,  # must be "sell" or "buy"
     buy_token_price_e18: int,  # price in e18 (as stored in auction_prices)
+    fee_policy: Dict[str, Any] | None = None,
 ) -> int:
     """
     Mirrors your Python scoring logic AND circuit math.
 
     Sell:
       partial_limit_buy = ceil(limit_buy * exec_sell / limit_sell)
-      surplus_buy = exec_buy - partial_limit_buy
-      score_native = floor(surplus_buy * buy_price)
+      surplus_buy = max(exec_buy - partial_limit_buy, 0)
+      score_native = floor((surplus_buy + fee) * buy_price)
 
     Buy:
       partial_limit_sell = floor(limit_sell * exec_buy / limit_buy)
-      surplus_sell = partial_limit_sell - exec_sell
-      score_native = floor(surplus_sell * (limit_buy/limit_sell) * buy_price)
+      surplus_sell = max(partial_limit_sell - exec_sell, 0)
+      score_native = floor(floor((surplus_sell + fee) * limit_buy / limit_sell) * buy_price)
 
-    Where buy_price = buy_token_price_e18 / 1e18.
+    Where buy_price = buy_token_price_e18 / 1e18 and fee is protocol_fee.
     """
     buy_price = Fraction(buy_token_price_e18, 10**18)
 
     if kind == "sell":
         partial_limit_buy = math.ceil(Fraction(limit_buy * exec_sell, limit_sell))
-        surplus_buy = exec_buy - partial_limit_buy
-        if surplus_buy <= 0:
-            return 0
+        surplus_buy = max(exec_buy - partial_limit_buy, 0)
+        surplus_buy += protocol_fee(
+            exec_sell=exec_sell, exec_buy=exec_buy, kind=kind, surplus=surplus_buy, fee_policy=fee_policy
+        )
         return math.floor(Fraction(surplus_buy) * buy_price)
 
     if kind == "buy":
         partial_limit_sell = math.floor(Fraction(limit_sell * exec_buy, limit_buy))
-        surplus_sell = partial_limit_sell - exec_sell
-        if surplus_sell <= 0:
-            return 0
-        return math.floor(Fraction(surplus_sell) * Fraction(limit_buy, limit_sell) * buy_price)
+        surplus_sell = max(partial_limit_sell - exec_sell, 0)
+        surplus_sell += protocol_fee(
+            exec_sell=exec_sell, exec_buy=exec_buy, kind=kind, surplus=surplus_sell, fee_policy=fee_policy
+        )
+        surplus_buy_equiv = surplus_sell * limit_buy // limit_sell
+        return math.floor(Fraction(surplus_buy_equiv) * buy_price)
 
     raise ValueError(f"Unexpected kind={kind!r}. Expected 'sell' or 'buy'.")
 
@@ -135,6 +222,7 @@ WITH trade_data AS (
         o.sell_amount               AS limit_sell_amount,
         o.buy_amount                AS limit_buy_amount,
         o.kind                      AS order_kind,
+        o.partially_fillable        AS partially_fillable,
 
         pte.executed_sell           AS executed_sell_amount,
         pte.executed_buy            AS executed_buy_amount
@@ -146,13 +234,44 @@ WITH trade_data AS (
            ON pte.order_uid = o.uid
     WHERE ps.auction_id BETWEEN :start_id AND :end_id
 ),
+fee_data AS (
+    SELECT
+        fp.auction_id,
+        fp.order_uid,
+        fp.kind                     AS fee_kind,
+        fp.surplus_factor,
+        fp.surplus_max_volume_factor,
+        fp.volume_factor,
+        fp.price_improvement_factor,
+        fp.price_improvement_max_volume_factor,
+        COUNT(*) OVER (PARTITION BY fp.auction_id, fp.order_uid) AS fee_policy_count,
+        ROW_NUMBER() OVER (PARTITION BY fp.auction_id, fp.order_uid ORDER BY fp.application_order) AS fee_rn
+    FROM fee_policies fp
+    WHERE fp.auction_id BETWEEN :start_id AND :end_id
+),
 trade_data_with_prices AS (
     SELECT
         td.*,
-        ap_buy.price AS buy_token_price_e18
+        ap_buy.price AS buy_token_price_e18,
+        fd.fee_kind,
+        fd.surplus_factor,
+        fd.surplus_max_volume_factor,
+        fd.volume_factor,
+        fd.price_improvement_factor,
+        fd.price_improvement_max_volume_factor,
+        COALESCE(fd.fee_policy_count, 0) AS fee_policy_count,
+        oq.sell_amount              AS quote_sell_amount,
+        oq.buy_amount               AS quote_buy_amount,
+        oq.gas_amount               AS quote_gas_amount,
+        oq.gas_price                AS quote_gas_price,
+        oq.sell_token_price         AS quote_sell_token_price
     FROM trade_data td
     JOIN auction_prices ap_buy
       ON td.auction_id = ap_buy.auction_id AND td.buy_token = ap_buy.token
+    LEFT JOIN fee_data fd
+           ON td.auction_id = fd.auction_id AND td.order_uid = fd.order_uid AND fd.fee_rn = 1
+    LEFT JOIN order_quotes oq
+           ON td.order_uid = oq.order_uid
 )
 SELECT *
 FROM trade_data_with_prices
@@ -172,6 +291,7 @@ WITH trade_data AS (
         o.sell_amount               AS limit_sell_amount,
         o.buy_amount                AS limit_buy_amount,
         o.kind                      AS order_kind,
+        o.partially_fillable        AS partially_fillable,
 
         pte.executed_sell           AS executed_sell_amount,
         pte.executed_buy            AS executed_buy_amount
@@ -183,13 +303,44 @@ WITH trade_data AS (
            ON pte.order_uid = o.uid
     WHERE ps.auction_id = :auction_id
 ),
+fee_data AS (
+    SELECT
+        fp.auction_id,
+        fp.order_uid,
+        fp.kind                     AS fee_kind,
+        fp.surplus_factor,
+        fp.surplus_max_volume_factor,
+        fp.volume_factor,
+        fp.price_improvement_factor,
+        fp.price_improvement_max_volume_factor,
+        COUNT(*) OVER (PARTITION BY fp.auction_id, fp.order_uid) AS fee_policy_count,
+        ROW_NUMBER() OVER (PARTITION BY fp.auction_id, fp.order_uid ORDER BY fp.application_order) AS fee_rn
+    FROM fee_policies fp
+    WHERE fp.auction_id = :auction_id
+),
 trade_data_with_prices AS (
     SELECT
         td.*,
-        ap_buy.price AS buy_token_price_e18
+        ap_buy.price AS buy_token_price_e18,
+        fd.fee_kind,
+        fd.surplus_factor,
+        fd.surplus_max_volume_factor,
+        fd.volume_factor,
+        fd.price_improvement_factor,
+        fd.price_improvement_max_volume_factor,
+        COALESCE(fd.fee_policy_count, 0) AS fee_policy_count,
+        oq.sell_amount              AS quote_sell_amount,
+        oq.buy_amount               AS quote_buy_amount,
+        oq.gas_amount               AS quote_gas_amount,
+        oq.gas_price                AS quote_gas_price,
+        oq.sell_token_price         AS quote_sell_token_price
     FROM trade_data td
     JOIN auction_prices ap_buy
       ON td.auction_id = ap_buy.auction_id AND td.buy_token = ap_buy.token
+    LEFT JOIN fee_data fd
+           ON td.auction_id = fd.auction_id AND td.order_uid = fd.order_uid AND fd.fee_rn = 1
+    LEFT JOIN order_quotes oq
+           ON td.order_uid = oq.order_uid
 )
 SELECT *
 FROM trade_data_with_prices
@@ -223,6 +374,16 @@ def fetch_auctions_circuit_json(session: Session, start_id: int, end_id: int) ->
             )
             continue
 
+        # the circuit applies a single fee policy per trade
+        if any(int(r.fee_policy_count) > 1 for r in auction_rows if r.order_uid is not None):
+            skipped_auctions.append(
+                {
+                    "auction_id": auction_id,
+                    "reason": "an order has more than one fee policy; skipping auction",
+                }
+            )
+            continue
+
         solutions_map: Dict[int, Dict[str, Any]] = {}
 
         for r in auction_rows:
@@ -245,6 +406,7 @@ def fetch_auctions_circuit_json(session: Session, start_id: int, end_id: int) ->
             exec_buy = int(r.executed_buy_amount)
 
             buy_price_e18 = int(r.buy_token_price_e18)
+            fee_policy = fee_policy_of(r)
 
             score_native = compute_score_native(
                 limit_sell=limit_sell,
@@ -253,6 +415,7 @@ def fetch_auctions_circuit_json(session: Session, start_id: int, end_id: int) ->
                 exec_buy=exec_buy,
                 kind=kind,
                 buy_token_price_e18=buy_price_e18,
+                fee_policy=fee_policy,
             )
 
             trade_obj = {
@@ -265,8 +428,12 @@ def fetch_auctions_circuit_json(session: Session, start_id: int, end_id: int) ->
                 "exec_buy": str(exec_buy),
                 "side": side,
                 "buy_token_price_e18": str(buy_price_e18),
+                # the circuit checks fills against the full order amounts
+                "partially_fillable": bool(r.partially_fillable),
                 "score_native": str(score_native),
             }
+            if fee_policy is not None:
+                trade_obj["fee_policy"] = fee_policy
 
             trades = solutions_map[sol_uid]["trades"]
             if len(trades) < TMAX + 1:
@@ -0,0 +1,118 @@
+// Package gadgets holds hint-backed integer gadgets for native BN254 circuits:
+// floor/ceil division, strict comparison and bounded lookups.
+//
+// A hint's output is chosen by the prover, so each gadget constrains it
+// completely, under the bit-width contract stated on the gadget. The widths
+// that make the check itself sound are verified when the circuit is compiled
+// (the gadget panics); bounds on the inputs are the caller's to enforce, the
+// gadget only assumes them. A hint that is given the wrong arity returns an
+// error instead of silently leaving its outputs at zero.
+package gadgets
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/cmp"
+)
+
+func init() {
+	solver.RegisterHint(IsLessHint, DivModHint)
+}
+
+// DivFloor returns floor(a/b).
+//
+// Contract: 0 < b < 2^bBits and floor(a/b) < 2^qBits, otherwise no witness
+// satisfies the constraints (b = 0 included). Needs bBits+qBits+2 < field
+// bits: the checks bound b*q + r by 2^(bBits+qBits+1) + 2^bBits, which must
+// not wrap around the modulus, or q + ⌊p/b⌋ would pass as a quotient.
+func DivFloor(api frontend.API, rc frontend.Rangechecker, a, b frontend.Variable, bBits, qBits int) frontend.Variable {
+	if bBits < 1 || qBits < 1 || bBits+qBits+2 >= api.Compiler().FieldBitLen() {
+		panic(fmt.Sprintf("gadgets.DivFloor: bBits=%d, qBits=%d do not fit in a %d-bit field", bBits, qBits, api.Compiler().FieldBitLen()))
+	}
+	out, err := api.NewHint(DivModHint, 2, a, b)
+	if err != nil {
+		panic(err)
+	}
+	q, r := out[0], out[1]
+
+	rc.Check(q, qBits)
+	rc.Check(r, bBits)
+	api.AssertIsEqual(a, api.Add(api.Mul(b, q), r))
+
+	// r < b without a comparator: b - 1 - r underflows to a huge element when r >= b
+	rc.Check(api.Sub(api.Sub(b, 1), r), bBits)
+
+	return q
+}
+
+// DivCeil returns ceil(a/b) = floor((a + b - 1)/b), under the DivFloor contract
+// with ceil(a/b) < 2^qBits
+func DivCeil(api frontend.API, rc frontend.Rangechecker, a, b frontend.Variable, bBits, qBits int) frontend.Variable {
+	return DivFloor(api, rc, api.Add(a, api.Sub(b, 1)), b, bBits, qBits)
+}
+
+// IsLessIf returns 1 if a < b and 0 otherwise when cond = 1, and 0 when cond = 0.
+//
+// Contract: cond is boolean and a, b < 2^bits; needs bits+1 < field bits, so
+// that b - a - 1 and a - b cannot both be below 2^bits.
+func IsLessIf(api frontend.API, rc frontend.Rangechecker, a, b frontend.Variable, bits int, cond frontend.Variable) frontend.Variable {
+	if bits < 1 || bits+1 >= api.Compiler().FieldBitLen() {
+		panic(fmt.Sprintf("gadgets.IsLessIf: bits=%d does not fit in a %d-bit field", bits, api.Compiler().FieldBitLen()))
+	}
+	out, err := api.NewHint(IsLessHint, 1, a, b)
+	if err != nil {
+		panic(err)
+	}
+	lt := out[0]
+	AssertBoolIf(api, lt, cond)
+
+	// lt = 1: b - a - 1 >= 0
+	// lt = 0: a - b >= 0
+	dPos := api.Sub(api.Sub(b, a), 1)
+	dNeg := api.Sub(a, b)
+	rc.Check(api.Select(cond, api.Select(lt, dPos, dNeg), 0), bits)
+
+	// with cond = 0 the hint is only known to be boolean (Select asserts it)
+	return api.Mul(cond, lt)
+}
+
+// AssertBoolIf enforces x ∈ {0, 1} when cond = 1
+func AssertBoolIf(api frontend.API, x, cond frontend.Variable) {
+	api.AssertIsEqual(api.Mul(cond, api.Mul(x, api.Sub(1, x))), 0)
+}
+
+// AssertLeqConstIf enforces x <= c when cond = 1.
+//
+// Contract: x and c+1 are within bc's absolute bound, as for bc.IsLess.
+func AssertLeqConstIf(api frontend.API, bc *cmp.BoundedComparator, x frontend.Variable, c int, cond frontend.Variable) {
+	ok := bc.IsLess(x, frontend.Variable(c+1))
+	api.AssertIsEqual(api.Mul(cond, api.Sub(1, ok)), 0)
+}
+
+// IsLessHint: results[0] = 1 if inputs[0] < inputs[1] else 0
+func IsLessHint(_ *big.Int, inputs []*big.Int, results []*big.Int) error {
+	if len(inputs) != 2 || len(results) != 1 {
+		return fmt.Errorf("IsLessHint: want 2 inputs and 1 result, got %d and %d", len(inputs), len(results))
+	}
+	if inputs[0].Cmp(inputs[1]) < 0 {
+		results[0].SetInt64(1)
+	} else {
+		results[0].SetInt64(0)
+	}
+	return nil
+}
+
+// DivModHint: results = (q, r) with inputs[0] = q*inputs[1] + r, 0 <= r < inputs[1]
+func DivModHint(_ *big.Int, inputs []*big.Int, results []*big.Int) error {
+	if len(inputs) != 2 || len(results) != 2 {
+		return fmt.Errorf("DivModHint: want 2 inputs and 2 results, got %d and %d", len(inputs), len(results))
+	}
+	if inputs[1].Sign() == 0 {
+		return fmt.Errorf("DivModHint: division by zero")
+	}
+	results[0].QuoRem(inputs[0], inputs[1], results[1])
+	return nil
+}
@@ -0,0 +1,294 @@
+package gadgets
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/math/cmp"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/consensys/gnark/test"
+)
+
+// Each gadget is solved with its honest hint, then with hints that lie in the
+// ways a prover could (off by one, wrapped around the modulus, not boolean);
+// every lie must leave the constraints unsatisfied.
+
+const (
+	testBBits = 16
+	testQBits = 16
+)
+
+type divCircuit struct {
+	A, B  frontend.Variable
+	Floor frontend.Variable `gnark:",public"`
+	Ceil  frontend.Variable `gnark:",public"`
+}
+
+func (c *divCircuit) Define(api frontend.API) error {
+	rc := rangecheck.New(api)
+	api.AssertIsEqual(DivFloor(api, rc, c.A, c.B, testBBits, testQBits), c.Floor)
+	api.AssertIsEqual(DivCeil(api, rc, c.A, c.B, testBBits, testQBits), c.Ceil)
+	return nil
+}
+
+type lessCircuit struct {
+	A, B, Cond frontend.Variable
+	Lt         frontend.Variable `gnark:",public"`
+}
+
+func (c *lessCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(IsLessIf(api, rangecheck.New(api), c.A, c.B, 32, c.Cond), c.Lt)
+	return nil
+}
+
+// solve compiles circuit to R1CS and runs the solver, with hint overrides
+func solve(t *testing.T, circuit, assignment frontend.Circuit, opts ...solver.Option) error {
+	t.Helper()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ccs.Solve(w, opts...)
+	return err
+}
+
+// evilDivMod runs DivModHint and lets edit rewrite (q, r)
+func evilDivMod(edit func(mod, a, b, q, r *big.Int)) solver.Option {
+	return solver.OverrideHint(solver.GetHintID(DivModHint), func(mod *big.Int, in, out []*big.Int) error {
+		if err := DivModHint(mod, in, out); err != nil {
+			return err
+		}
+		edit(mod, in[0], in[1], out[0], out[1])
+		out[0].Mod(out[0], mod)
+		out[1].Mod(out[1], mod)
+		return nil
+	})
+}
+
+func TestDivFloorCeil(t *testing.T) {
+	for _, c := range []struct{ a, b, floor, ceil int64 }{
+		{0, 1, 0, 0},
+		{7, 2, 3, 4},
+		{8, 2, 4, 4},
+		{65535 * 3, 3, 65535, 65535},
+		{100, 65535, 0, 1},
+	} {
+		a := &divCircuit{A: c.a, B: c.b, Floor: c.floor, Ceil: c.ceil}
+		if err := test.IsSolved(&divCircuit{}, a, ecc.BN254.ScalarField()); err != nil {
+			t.Errorf("%d/%d: %v", c.a, c.b, err)
+		}
+		if err := solve(t, &divCircuit{}, a); err != nil {
+			t.Errorf("%d/%d: %v", c.a, c.b, err)
+		}
+	}
+
+	// quotient beyond qBits: honest hint, unsatisfiable
+	if err := solve(t, &divCircuit{}, &divCircuit{A: 1 << 20, B: 2, Floor: 1 << 19, Ceil: 1 << 19}); err == nil {
+		t.Error("quotient of 19 bits accepted with qBits = 16")
+	}
+	// division by zero: the hint refuses, and a hint claiming (0, a) fails r < b
+	if err := solve(t, &divCircuit{}, &divCircuit{A: 5, B: 0, Floor: 0, Ceil: 0}); err == nil {
+		t.Error("division by zero accepted")
+	}
+	zero := evilDivMod(func(_, a, _, q, r *big.Int) { q.SetInt64(0); r.Set(a) })
+	if err := solve(t, &divCircuit{}, &divCircuit{A: 5, B: 0, Floor: 0, Ceil: 0}, zero); err == nil {
+		t.Error("division by zero accepted with q = 0, r = a")
+	}
+}
+
+type floorCircuit struct {
+	A, B, Q frontend.Variable
+}
+
+func (c *floorCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(DivFloor(api, rangecheck.New(api), c.A, c.B, testBBits, testQBits), c.Q)
+	return nil
+}
+
+func TestDivFloorMaliciousHint(t *testing.T) {
+	mod := ecc.BN254.ScalarField()
+	a, b := big.NewInt(1000), big.NewInt(7)
+	for name, edit := range map[string]func(mod, a, b, q, r *big.Int){
+		"q + 1, r - b":  func(_, _, b, q, r *big.Int) { q.Add(q, big.NewInt(1)); r.Sub(r, b) },
+		"q - 1, r + b":  func(_, _, b, q, r *big.Int) { q.Sub(q, big.NewInt(1)); r.Add(r, b) },
+		"q + 1, r kept": func(_, _, _, q, _ *big.Int) { q.Add(q, big.NewInt(1)) },
+		"q - 1, r kept": func(_, _, _, q, _ *big.Int) { q.Sub(q, big.NewInt(1)) },
+		// b*q' + r' = a + p: the other solution of the field equation with r' < b
+		"wrapped": func(mod, a, b, q, r *big.Int) { q.QuoRem(new(big.Int).Add(a, mod), b, r) },
+	} {
+		// Q is set to the lie, so only the gadget's own checks can reject it
+		q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+		edit(mod, a, b, q, r)
+		q.Mod(q, mod)
+		err := solve(t, &floorCircuit{}, &floorCircuit{A: a, B: b, Q: q}, evilDivMod(edit))
+		if err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if err := solve(t, &floorCircuit{}, &floorCircuit{A: a, B: b, Q: 142}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// widths whose checks could wrap are refused when compiling
+func TestDivFloorWidthContract(t *testing.T) {
+	for _, w := range [][2]int{{128, 129}, {60, 224}, {0, 8}} {
+		c := &widthCircuit{bBits: w[0], qBits: w[1]}
+		if _, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, c); err == nil {
+			t.Errorf("bBits=%d qBits=%d compiled", w[0], w[1])
+		}
+	}
+	if _, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &widthCircuit{bBits: 120, qBits: 121}); err != nil {
+		t.Errorf("bBits=120 qBits=121: %v", err)
+	}
+}
+
+type widthCircuit struct {
+	A, B         frontend.Variable
+	bBits, qBits int
+}
+
+func (c *widthCircuit) Define(api frontend.API) error {
+	DivFloor(api, rangecheck.New(api), c.A, c.B, c.bBits, c.qBits)
+	return nil
+}
+
+func TestIsLessIf(t *testing.T) {
+	for _, c := range []struct{ a, b, cond, lt int64 }{
+		{1, 2, 1, 1},
+		{2, 2, 1, 0},
+		{3, 2, 1, 0},
+		{0, 1<<32 - 1, 1, 1},
+		{1, 2, 0, 0},
+		{5, 2, 0, 0},
+	} {
+		a := &lessCircuit{A: c.a, B: c.b, Cond: c.cond, Lt: c.lt}
+		if err := test.IsSolved(&lessCircuit{}, a, ecc.BN254.ScalarField()); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+		if err := solve(t, &lessCircuit{}, a); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+	}
+}
+
+func TestIsLessIfMaliciousHint(t *testing.T) {
+	override := func(v int64) solver.Option {
+		return solver.OverrideHint(solver.GetHintID(IsLessHint), func(_ *big.Int, _, out []*big.Int) error {
+			out[0].SetInt64(v)
+			return nil
+		})
+	}
+	for _, c := range []struct {
+		name          string
+		a, b, lie, lt int64
+		cond          int64
+		ok            bool
+	}{
+		{"1 < 2 claimed false", 1, 2, 0, 0, 1, false},
+		{"2 < 2 claimed true", 2, 2, 1, 1, 1, false},
+		{"3 < 2 claimed true", 3, 2, 1, 1, 1, false},
+		{"non-boolean 2", 1, 2, 2, 2, 1, false},
+		// when cond = 0 the comparison is not checked but the result is pinned to 0
+		{"cond 0, lie 1 returned", 3, 2, 1, 1, 0, false},
+		{"cond 0, lie 1 dropped", 3, 2, 1, 0, 0, true},
+		{"cond 0, non-boolean 7", 3, 2, 7, 0, 0, false},
+	} {
+		err := solve(t, &lessCircuit{}, &lessCircuit{A: c.a, B: c.b, Cond: c.cond, Lt: c.lt}, override(c.lie))
+		if c.ok && err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s: accepted", c.name)
+		}
+	}
+}
+
+func TestHintArity(t *testing.T) {
+	one, two := []*big.Int{big.NewInt(1)}, []*big.Int{big.NewInt(1), big.NewInt(2)}
+	mod := ecc.BN254.ScalarField()
+	if IsLessHint(mod, one, []*big.Int{new(big.Int)}) == nil {
+		t.Error("IsLessHint with 1 input")
+	}
+	if IsLessHint(mod, two, []*big.Int{new(big.Int), new(big.Int)}) == nil {
+		t.Error("IsLessHint with 2 results")
+	}
+	if DivModHint(mod, one, []*big.Int{new(big.Int), new(big.Int)}) == nil {
+		t.Error("DivModHint with 1 input")
+	}
+	if DivModHint(mod, two, []*big.Int{new(big.Int)}) == nil {
+		t.Error("DivModHint with 1 result")
+	}
+}
+
+type leqCircuit struct {
+	X, Cond frontend.Variable
+}
+
+func (c *leqCircuit) Define(api frontend.API) error {
+	AssertLeqConstIf(api, cmp.NewBoundedComparator(api, big.NewInt(1<<8), false), c.X, 10, c.Cond)
+	return nil
+}
+
+func TestAssertLeqConstIf(t *testing.T) {
+	for _, c := range []struct {
+		x, cond int64
+		ok      bool
+	}{
+		{10, 1, true},
+		{0, 1, true},
+		{11, 1, false},
+		{200, 1, false},
+		{11, 0, true},
+	} {
+		err := test.IsSolved(&leqCircuit{}, &leqCircuit{X: c.x, Cond: c.cond}, ecc.BN254.ScalarField())
+		if c.ok != (err == nil) {
+			t.Errorf("x=%d cond=%d: %v", c.x, c.cond, err)
+		}
+	}
+}
+
+type selectCircuit struct {
+	Arr      [4]frontend.Variable
+	Idx, Out frontend.Variable
+	Key      frontend.Variable
+	Cond     frontend.Variable
+}
+
+func (c *selectCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(SelectFromSmallArray(api, c.Arr[:], c.Idx), c.Out)
+	AssertKeyAtIf(api, c.Arr[:], c.Idx, c.Key, c.Cond)
+	return nil
+}
+
+func TestSelectAndKeyAt(t *testing.T) {
+	arr := [4]frontend.Variable{10, 20, 30, 40}
+	for _, c := range []struct {
+		name                string
+		idx, out, key, cond int64
+		ok                  bool
+	}{
+		{"idx 2", 2, 30, 30, 1, true},
+		{"idx 0", 0, 10, 10, 1, true},
+		{"wrong key", 2, 30, 20, 1, false},
+		{"wrong key, cond 0", 2, 30, 20, 0, true},
+		{"idx out of range selects 0", 4, 0, 99, 0, true},
+		// used to pass: no slot matched, so no key was enforced
+		{"idx out of range, cond 1", 4, 0, 99, 1, false},
+		{"idx -1, cond 1", -1, 0, 99, 1, false},
+	} {
+		a := &selectCircuit{Arr: arr, Idx: c.idx, Out: c.out, Key: c.key, Cond: c.cond}
+		err := test.IsSolved(&selectCircuit{}, a, ecc.BN254.ScalarField())
+		if c.ok != (err == nil) {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+}
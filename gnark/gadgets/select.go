@@ -0,0 +1,30 @@
+package gadgets
+
+import "github.com/consensys/gnark/frontend"
+
+// Lookups at a variable index into a short array, one IsZero per slot.
+// Neither uses a hint; idx is any field element.
+
+// SelectFromSmallArray returns arr[idx], or 0 when idx is not in [0, len(arr)):
+// bound idx first if 0 is a meaningful entry.
+func SelectFromSmallArray(api frontend.API, arr []frontend.Variable, idx frontend.Variable) frontend.Variable {
+	out := frontend.Variable(0)
+	for k := range arr {
+		isK := api.IsZero(api.Sub(idx, k))
+		out = api.Add(out, api.Mul(isK, arr[k]))
+	}
+	return out
+}
+
+// AssertKeyAtIf enforces idx ∈ [0, len(keys)) and keys[idx] == key when cond = 1
+func AssertKeyAtIf(api frontend.API, keys []frontend.Variable, idx, key, cond frontend.Variable) {
+	hit := frontend.Variable(0)
+	for k := range keys {
+		isK := api.IsZero(api.Sub(idx, k))
+		hit = api.Add(hit, isK)
+		enf := api.Mul(cond, isK)
+		api.AssertIsEqual(api.Mul(enf, api.Sub(keys[k], key)), 0)
+	}
+	// without it an out-of-range idx matches no slot and enforces nothing
+	api.AssertIsEqual(api.Mul(cond, api.Sub(1, hit)), 0)
+}
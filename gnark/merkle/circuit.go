@@ -0,0 +1,64 @@
+package merkle
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash"
+)
+
+// In-circuit counterparts of Root, Verify, Tree.Update and Sparse. h is reset
+// before every node; pass a gnark std/hash/mimc hasher to match the Go side.
+
+// RootFromLeaves hashes a power-of-two number of leaves up to the root
+func RootFromLeaves(api frontend.API, h hash.FieldHasher, leaves []frontend.Variable) frontend.Variable {
+	cur := leaves
+	for len(cur) > 1 {
+		next := make([]frontend.Variable, len(cur)/2)
+		for i := range next {
+			next[i] = hashNode(h, cur[2*i], cur[2*i+1])
+		}
+		cur = next
+	}
+	return cur[0]
+}
+
+// RootFromPath recomputes the root from leaf and path. indexBits[d] selects the
+// side at level d and must already be constrained boolean (IndexBits does that).
+func RootFromPath(api frontend.API, h hash.FieldHasher, leaf frontend.Variable, indexBits, path []frontend.Variable) frontend.Variable {
+	cur := leaf
+	for d, sib := range path {
+		// swap when the bit is set: one multiplication instead of two selects
+		delta := api.Mul(indexBits[d], api.Sub(sib, cur))
+		cur = hashNode(h, api.Add(cur, delta), api.Sub(sib, delta))
+	}
+	return cur
+}
+
+// IndexBits decomposes index into depth boolean bits, failing if it does not fit
+func IndexBits(api frontend.API, index frontend.Variable, depth int) []frontend.Variable {
+	return api.ToBinary(index, depth)
+}
+
+// AssertProof enforces that leaf sits at index under root (Verify)
+func AssertProof(api frontend.API, h hash.FieldHasher, root, leaf, index frontend.Variable, path []frontend.Variable) {
+	bits := IndexBits(api, index, len(path))
+	api.AssertIsEqual(RootFromPath(api, h, leaf, bits, path), root)
+}
+
+// Update enforces that oldLeaf sits at index under root and returns the root
+// with newLeaf in its place; both sides share the path (Tree.Update).
+func Update(api frontend.API, h hash.FieldHasher, root, index, oldLeaf, newLeaf frontend.Variable, path []frontend.Variable) frontend.Variable {
+	bits := IndexBits(api, index, len(path))
+	api.AssertIsEqual(RootFromPath(api, h, oldLeaf, bits, path), root)
+	return RootFromPath(api, h, newLeaf, bits, path)
+}
+
+// AssertEmpty enforces that index is unset in a Sparse tree with this root
+func AssertEmpty(api frontend.API, h hash.FieldHasher, root, index frontend.Variable, path []frontend.Variable) {
+	AssertProof(api, h, root, 0, index, path)
+}
+
+func hashNode(h hash.FieldHasher, left, right frontend.Variable) frontend.Variable {
+	h.Reset()
+	h.Write(left, right)
+	return h.Sum()
+}
@@ -0,0 +1,177 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/test"
+)
+
+const circuitDepth = 3
+
+type rootCircuit struct {
+	Leaves [1 << circuitDepth]frontend.Variable
+	Root   frontend.Variable `gnark:",public"`
+}
+
+func (c *rootCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(RootFromLeaves(api, &h, c.Leaves[:]), c.Root)
+	return nil
+}
+
+type proofCircuit struct {
+	Root  frontend.Variable `gnark:",public"`
+	Leaf  frontend.Variable
+	Index frontend.Variable
+	Path  [circuitDepth]frontend.Variable
+}
+
+func (c *proofCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	AssertProof(api, &h, c.Root, c.Leaf, c.Index, c.Path[:])
+	return nil
+}
+
+type updateCircuit struct {
+	Root, NewRoot    frontend.Variable `gnark:",public"`
+	Index            frontend.Variable
+	OldLeaf, NewLeaf frontend.Variable
+	Path             [circuitDepth]frontend.Variable
+}
+
+func (c *updateCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(Update(api, &h, c.Root, c.Index, c.OldLeaf, c.NewLeaf, c.Path[:]), c.NewRoot)
+	return nil
+}
+
+const sparseDepth = 20
+
+type emptyCircuit struct {
+	Root  frontend.Variable `gnark:",public"`
+	Index frontend.Variable
+	Path  [sparseDepth]frontend.Variable
+}
+
+func (c *emptyCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	AssertEmpty(api, &h, c.Root, c.Index, c.Path[:])
+	return nil
+}
+
+func checkSolved(t *testing.T, name string, circuit, assignment frontend.Circuit, ok bool) {
+	t.Helper()
+	err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField())
+	if ok && err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+	if !ok && err == nil {
+		t.Errorf("%s: accepted", name)
+	}
+}
+
+func pathVars(path []fr.Element) [circuitDepth]frontend.Variable {
+	var out [circuitDepth]frontend.Variable
+	for i := range out {
+		out[i] = path[i]
+	}
+	return out
+}
+
+func TestRootFromLeavesGadget(t *testing.T) {
+	leaves := testLeaves(1 << circuitDepth)
+	root, err := Root(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a rootCircuit
+	for i := range leaves {
+		a.Leaves[i] = leaves[i]
+	}
+	a.Root = root
+	checkSolved(t, "root", &rootCircuit{}, &a, true)
+	a.Leaves[2], a.Leaves[3] = leaves[3], leaves[2]
+	checkSolved(t, "swapped leaves", &rootCircuit{}, &a, false)
+}
+
+func TestProofGadget(t *testing.T) {
+	leaves := testLeaves(1 << circuitDepth)
+	tr, _ := New(leaves)
+	for i := range leaves {
+		path, _ := tr.Proof(i)
+		ok := proofCircuit{Root: tr.Root(), Leaf: leaves[i], Index: i, Path: pathVars(path)}
+		checkSolved(t, "proof", &proofCircuit{}, &ok, true)
+
+		bad := ok
+		bad.Index = i ^ 1
+		checkSolved(t, "wrong index", &proofCircuit{}, &bad, false)
+		bad = ok
+		bad.Index = i + 1<<circuitDepth
+		checkSolved(t, "index above depth", &proofCircuit{}, &bad, false)
+		bad = ok
+		bad.Leaf = leaves[(i+1)%len(leaves)]
+		checkSolved(t, "wrong leaf", &proofCircuit{}, &bad, false)
+	}
+}
+
+func TestUpdateGadget(t *testing.T) {
+	leaves := testLeaves(1 << circuitDepth)
+	tr, _ := New(leaves)
+	root := tr.Root()
+	path, _ := tr.Proof(6)
+	var newLeaf fr.Element
+	newLeaf.SetUint64(42)
+	if err := tr.Update(6, newLeaf); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := updateCircuit{Root: root, NewRoot: tr.Root(), Index: 6, OldLeaf: leaves[6], NewLeaf: newLeaf, Path: pathVars(path)}
+	checkSolved(t, "update", &updateCircuit{}, &ok, true)
+
+	bad := ok
+	bad.OldLeaf = 0
+	checkSolved(t, "wrong old leaf", &updateCircuit{}, &bad, false)
+	bad = ok
+	bad.NewLeaf = 43
+	checkSolved(t, "wrong new root", &updateCircuit{}, &bad, false)
+	bad = ok
+	bad.Index = 7
+	checkSolved(t, "wrong index", &updateCircuit{}, &bad, false)
+}
+
+func TestEmptyGadget(t *testing.T) {
+	s, _ := NewSparse(sparseDepth)
+	for _, k := range []uint64{3, 1 << 19, 77777} {
+		_ = s.Update(k, testLeaves(1)[0])
+	}
+	assign := func(index uint64) *emptyCircuit {
+		path, err := s.Proof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := &emptyCircuit{Root: s.Root(), Index: index}
+		for i := range a.Path {
+			a.Path[i] = path[i]
+		}
+		return a
+	}
+	checkSolved(t, "empty key", &emptyCircuit{}, assign(2), true)
+	checkSolved(t, "empty key next to a set one", &emptyCircuit{}, assign(77776), true)
+	checkSolved(t, "set key", &emptyCircuit{}, assign(77777), false)
+}
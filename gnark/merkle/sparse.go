@@ -0,0 +1,102 @@
+package merkle
+
+import (
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Sparse is a tree of 2^depth leaves keyed by index where unset leaves are 0,
+// the pad leaf of the dense trees: a Sparse with leaves 0..n-1 set has the same
+// root as New over those leaves padded with zeros. Only non-empty nodes are
+// stored, empty subtrees hash to Zero(level).
+type Sparse struct {
+	depth int
+	nodes []map[uint64]fr.Element // nodes[d][i]: node i at level d, leaves at d = 0
+	zeros []fr.Element
+}
+
+// NewSparse returns an empty tree of the given depth (at most 64)
+func NewSparse(depth int) (*Sparse, error) {
+	if depth < 1 || depth > 64 {
+		return nil, fmt.Errorf("sparse depth must be in [1,64], got %d", depth)
+	}
+	s := &Sparse{
+		depth: depth,
+		nodes: make([]map[uint64]fr.Element, depth+1),
+		zeros: zeroHashes(depth),
+	}
+	for d := range s.nodes {
+		s.nodes[d] = make(map[uint64]fr.Element)
+	}
+	return s, nil
+}
+
+// Zero returns the root of an empty subtree of height level (0 for a leaf)
+func Zero(level int) fr.Element {
+	return zeroHashes(level)[level]
+}
+
+func zeroHashes(depth int) []fr.Element {
+	z := make([]fr.Element, depth+1)
+	for d := 1; d <= depth; d++ {
+		z[d] = Hash(z[d-1], z[d-1])
+	}
+	return z
+}
+
+func (s *Sparse) Depth() int { return s.depth }
+
+func (s *Sparse) Root() fr.Element { return s.node(s.depth, 0) }
+
+func (s *Sparse) Leaf(index uint64) fr.Element { return s.node(0, index) }
+
+func (s *Sparse) node(level int, i uint64) fr.Element {
+	if n, ok := s.nodes[level][i]; ok {
+		return n
+	}
+	return s.zeros[level]
+}
+
+func (s *Sparse) checkIndex(index uint64) error {
+	if s.depth < 64 && index>>uint(s.depth) != 0 {
+		return fmt.Errorf("leaf index %d out of range for depth %d", index, s.depth)
+	}
+	return nil
+}
+
+// Update sets the leaf at index (0 deletes it) and rehashes its path to the root
+func (s *Sparse) Update(index uint64, leaf fr.Element) error {
+	if err := s.checkIndex(index); err != nil {
+		return err
+	}
+	s.set(0, index, leaf)
+	for d := 0; d < s.depth; d++ {
+		index >>= 1
+		s.set(d+1, index, Hash(s.node(d, 2*index), s.node(d, 2*index+1)))
+	}
+	return nil
+}
+
+// empty nodes are dropped so the maps only hold the populated paths
+func (s *Sparse) set(level int, i uint64, n fr.Element) {
+	if n.Equal(&s.zeros[level]) {
+		delete(s.nodes[level], i)
+		return
+	}
+	s.nodes[level][i] = n
+}
+
+// Proof returns the sibling hashes of index, set or not; with Verify and a
+// zero leaf it proves that index is empty
+func (s *Sparse) Proof(index uint64) ([]fr.Element, error) {
+	if err := s.checkIndex(index); err != nil {
+		return nil, err
+	}
+	path := make([]fr.Element, s.depth)
+	for d := range path {
+		path[d] = s.node(d, index^1)
+		index >>= 1
+	}
+	return path, nil
+}
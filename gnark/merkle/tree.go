@@ -0,0 +1,128 @@
+// Package merkle is the binary MiMC Merkle tree shared by the multi-schnorr
+// registry and the comb auction bidset, off-circuit (this file and sparse.go)
+// and in-circuit (circuit.go).
+//
+// A node is MiMC(left || right) over BN254's scalar field, with both children
+// written as 32-byte big-endian elements. In a circuit that is
+// h.Write(left, right) on gnark's std/hash/mimc, so roots and paths computed
+// here are accepted by the gadgets unchanged.
+package merkle
+
+import (
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+// Hash is the node hash MiMC(left || right)
+func Hash(left, right fr.Element) fr.Element {
+	return HashElems(left, right)
+}
+
+// HashElems is MiMC over the 32-byte encodings of elems, used for leaves
+func HashElems(elems ...fr.Element) fr.Element {
+	h := mimc.NewMiMC()
+	for _, e := range elems {
+		b := e.Marshal()
+		h.Write(b)
+	}
+	var out fr.Element
+	_ = out.SetBytes(h.Sum(nil))
+	return out
+}
+
+// Tree keeps every level, levels[0] the leaves and levels[depth] the root,
+// so that Proof and Update only touch one node per level.
+type Tree struct {
+	levels [][]fr.Element
+}
+
+// New builds the tree over leaves, whose number must be a power of two
+// (pad with zero leaves, or use Sparse)
+func New(leaves []fr.Element) (*Tree, error) {
+	if len(leaves) == 0 || len(leaves)&(len(leaves)-1) != 0 {
+		return nil, fmt.Errorf("number of leaves must be a power of two, got %d", len(leaves))
+	}
+	cur := make([]fr.Element, len(leaves))
+	copy(cur, leaves)
+	t := &Tree{levels: [][]fr.Element{cur}}
+	for len(cur) > 1 {
+		next := make([]fr.Element, len(cur)/2)
+		for i := range next {
+			next[i] = Hash(cur[2*i], cur[2*i+1])
+		}
+		t.levels = append(t.levels, next)
+		cur = next
+	}
+	return t, nil
+}
+
+// Root returns the root of leaves, see New
+func Root(leaves []fr.Element) (fr.Element, error) {
+	t, err := New(leaves)
+	if err != nil {
+		return fr.Element{}, err
+	}
+	return t.Root(), nil
+}
+
+func (t *Tree) Root() fr.Element { return t.levels[len(t.levels)-1][0] }
+
+// Depth is the number of hashes from a leaf to the root (log2 of the leaf count)
+func (t *Tree) Depth() int { return len(t.levels) - 1 }
+
+func (t *Tree) Len() int { return len(t.levels[0]) }
+
+func (t *Tree) Leaf(index int) fr.Element { return t.levels[0][index] }
+
+// Proof returns the sibling hashes from the leaf at index up to (excluding) the root
+func (t *Tree) Proof(index int) ([]fr.Element, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, fmt.Errorf("leaf index %d out of range [0,%d)", index, t.Len())
+	}
+	path := make([]fr.Element, t.Depth())
+	for d := range path {
+		path[d] = t.levels[d][index^1]
+		index >>= 1
+	}
+	return path, nil
+}
+
+// Update sets the leaf at index and rehashes its path to the root
+func (t *Tree) Update(index int, leaf fr.Element) error {
+	if index < 0 || index >= t.Len() {
+		return fmt.Errorf("leaf index %d out of range [0,%d)", index, t.Len())
+	}
+	t.levels[0][index] = leaf
+	for d := 0; d < t.Depth(); d++ {
+		index >>= 1
+		t.levels[d+1][index] = Hash(t.levels[d][2*index], t.levels[d][2*index+1])
+	}
+	return nil
+}
+
+// rootFromPath recomputes the root from leaf and path; bit d of index
+// selects the side at level d (0: leaf on the left)
+func rootFromPath(leaf fr.Element, index uint64, path []fr.Element) fr.Element {
+	cur := leaf
+	for _, sib := range path {
+		if index&1 == 0 {
+			cur = Hash(cur, sib)
+		} else {
+			cur = Hash(sib, cur)
+		}
+		index >>= 1
+	}
+	return cur
+}
+
+// Verify checks that leaf sits at index under root. index must fit in len(path)
+// bits, otherwise two indices would share a path.
+func Verify(root, leaf fr.Element, index uint64, path []fr.Element) bool {
+	if len(path) < 64 && index>>uint(len(path)) != 0 {
+		return false
+	}
+	got := rootFromPath(leaf, index, path)
+	return got.Equal(&root)
+}
@@ -0,0 +1,155 @@
+package merkle
+
+import (
+	"testing"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+func testLeaves(n int) []fr.Element {
+	leaves := make([]fr.Element, n)
+	for i := range leaves {
+		leaves[i].SetUint64(uint64(1000 + i))
+	}
+	return leaves
+}
+
+// level by level, as utils.BuildRoot and the comb prover used to
+func naiveRoot(leaves []fr.Element) fr.Element {
+	cur := append([]fr.Element(nil), leaves...)
+	for len(cur) > 1 {
+		next := make([]fr.Element, len(cur)/2)
+		for i := range next {
+			next[i] = HashElems(cur[2*i], cur[2*i+1])
+		}
+		cur = next
+	}
+	return cur[0]
+}
+
+func TestTree(t *testing.T) {
+	leaves := testLeaves(8)
+	tr, err := New(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tr.Root()
+	if want := naiveRoot(leaves); !root.Equal(&want) || tr.Depth() != 3 {
+		t.Fatalf("root %v, want %v", root, want)
+	}
+	for i := range leaves {
+		path, err := tr.Proof(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(root, leaves[i], uint64(i), path) {
+			t.Fatalf("proof of %d rejected", i)
+		}
+		if Verify(root, leaves[i], uint64(i^1), path) || Verify(root, leaves[i^1], uint64(i), path) {
+			t.Fatalf("proof of %d accepted for another leaf", i)
+		}
+		// an index with bits above the depth would otherwise alias i
+		if Verify(root, leaves[i], uint64(i+8), path) {
+			t.Fatalf("index %d accepted", i+8)
+		}
+	}
+
+	if _, err := New(testLeaves(6)); err == nil {
+		t.Fatal("6 leaves accepted")
+	}
+	if _, err := tr.Proof(8); err == nil {
+		t.Fatal("proof of leaf 8 of 8")
+	}
+	if err := tr.Update(-1, fr.Element{}); err == nil {
+		t.Fatal("update of leaf -1")
+	}
+}
+
+func TestTreeUpdate(t *testing.T) {
+	leaves := testLeaves(16)
+	tr, err := New(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 5, 15, 5} {
+		leaves[i].SetUint64(uint64(7 * (i + 1)))
+		if err := tr.Update(i, leaves[i]); err != nil {
+			t.Fatal(err)
+		}
+		root, want := tr.Root(), naiveRoot(leaves)
+		if !root.Equal(&want) {
+			t.Fatalf("root after updating %d differs from a rebuild", i)
+		}
+		path, _ := tr.Proof(i)
+		if !Verify(root, leaves[i], uint64(i), path) {
+			t.Fatalf("proof of updated leaf %d rejected", i)
+		}
+	}
+}
+
+func TestSparse(t *testing.T) {
+	// the same leaves as a zero-padded dense tree
+	s, err := NewSparse(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense := make([]fr.Element, 16)
+	for i, leaf := range testLeaves(5) {
+		dense[i] = leaf
+		if err := s.Update(uint64(i), leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, want := s.Root(), naiveRoot(dense)
+	if !root.Equal(&want) {
+		t.Fatal("sparse root differs from the padded dense root")
+	}
+	if empty, _ := NewSparse(4); empty.Root() != Zero(4) {
+		t.Fatal("empty root is not Zero(depth)")
+	}
+
+	// deep tree keyed by large indices; deleting restores the empty root
+	s, _ = NewSparse(64)
+	keys := []uint64{0, 1, 1 << 40, ^uint64(0)}
+	for i, k := range keys {
+		leaf := testLeaves(i + 1)[i]
+		if err := s.Update(k, leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root = s.Root()
+	for i, k := range keys {
+		path, err := s.Proof(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(root, testLeaves(i + 1)[i], k, path) {
+			t.Fatalf("proof of key %d rejected", k)
+		}
+	}
+	path, _ := s.Proof(2)
+	if !Verify(root, fr.Element{}, 2, path) {
+		t.Fatal("proof that key 2 is empty rejected")
+	}
+	for _, k := range keys {
+		if err := s.Update(k, fr.Element{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Root() != Zero(64) {
+		t.Fatal("root after deleting every key is not empty")
+	}
+	for d, level := range s.nodes {
+		if len(level) != 0 {
+			t.Fatalf("%d nodes left at level %d", len(level), d)
+		}
+	}
+
+	if _, err := NewSparse(65); err == nil {
+		t.Fatal("depth 65 accepted")
+	}
+	s, _ = NewSparse(3)
+	if err := s.Update(8, fr.Element{}); err == nil {
+		t.Fatal("key 8 accepted at depth 3")
+	}
+}
@@ -0,0 +1,207 @@
+package multischnorr
+
+import (
+	"fmt"
+	"math/big"
+
+	tebn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+const (
+	BatchWeightBits = 128 // random weights z_i are odd and in [1, 2^BatchWeightBits)
+	batchLimbBits   = 64  // S_i is split in limbs so that sum z_i*limb does not wrap around Fr
+)
+
+// Same inputs and semantics as Circuit, but the per-candidate equations
+// [S_i]G == R_i + [e_i]A_i are checked at once as
+//
+//	[sum z_i*S_i]G == sum [z_i](R_i + [e_i]A_i)
+//
+// with weights z_i derived from a commitment (api.Commit) to all candidates.
+// The G side is a single fixed-base multiplication for the whole batch, each
+// candidate only pays [e_i]A_i and its share of a multi-scalar multiplication
+// by the BatchWeightBits weights z_i (the doublings are shared).
+//
+// Soundness: the weights are fixed only after the whole witness is committed,
+// so offsetting errors between candidates hold with probability about
+// 2^-(BatchWeightBits-1). A single bad signature is always rejected: z_i is odd
+// and < order, so [z_i]D != 0 for any nonzero error point D, including the small
+// order ones from the cofactor. Two or more errors of small order only (which
+// needs a forged R with a torsion component, i.e. a malicious key holder) can
+// cancel out, unlike in Circuit; see batch_test.go.
+//
+// The Groth16 proof carries the commitment, so this circuit does not fit the
+// uint256[8] proof of the MultiSchnorrVerifier wrapper.
+type BatchCircuit struct {
+	Root     frontend.Variable `gnark:",public"` // Merkle root of valid public keys
+	S        [MaxK]Candidate   // K candidates
+	Message  frontend.Variable `gnark:",public"`
+	SumValid frontend.Variable `gnark:",public"` // number of valid signatures found
+}
+
+func (c *BatchCircuit) Define(api frontend.API) error {
+	return defineBatch(api, c.Root, c.S[:], c.Message, c.SumValid)
+}
+
+// BatchCircuit with len(S) = 2^depth candidates
+type SizedBatchCircuit struct {
+	Root     frontend.Variable `gnark:",public"`
+	S        []Candidate
+	Message  frontend.Variable `gnark:",public"`
+	SumValid frontend.Variable `gnark:",public"`
+}
+
+func NewSizedBatchCircuit(depth int) *SizedBatchCircuit {
+	return &SizedBatchCircuit{S: make([]Candidate, 1<<depth)}
+}
+
+func (c *SizedBatchCircuit) Define(api frontend.API) error {
+	return defineBatch(api, c.Root, c.S, c.Message, c.SumValid)
+}
+
+func defineBatch(
+	api frontend.API,
+	root frontend.Variable,
+	cands []Candidate,
+	message frontend.Variable,
+	sumValidPub frontend.Variable,
+) error {
+	committer, ok := api.(frontend.Committer)
+	if !ok {
+		return fmt.Errorf("builder does not support commitments")
+	}
+
+	// Curve parameters (BabyJubJub over BN254 Fr)
+	E, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	params, err := twistededwards.GetCurveParams(tedwards.BN254)
+	if err != nil {
+		return err
+	}
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	axs := make([]frontend.Variable, len(cands))
+	ays := make([]frontend.Variable, len(cands))
+	for i := range cands {
+		axs[i] = cands[i].Ax
+		ays[i] = cands[i].Ay
+	}
+	api.AssertIsEqual(buildKeyRoot(api, &h, axs, ays), root)
+
+	// weights are bound to everything the batch equation depends on
+	committed := []frontend.Variable{message}
+	for _, c := range cands {
+		committed = append(committed, c.Ax, c.Ay, c.Sig.Rx, c.Sig.Ry, c.Sig.S, c.IsIgnore)
+	}
+	seed, err := committer.Commit(committed...)
+	if err != nil {
+		return err
+	}
+
+	orderBits := params.Order.BitLen()
+	nbLimbs := (orderBits + batchLimbBits - 1) / batchLimbBits
+	limbSums := make([]frontend.Variable, nbLimbs)
+	for j := range limbSums {
+		limbSums[j] = 0
+	}
+
+	points := make([]twistededwards.Point, len(cands))
+	weights := make([][]frontend.Variable, len(cands))
+	var sumValid frontend.Variable = 0
+
+	for i := range cands {
+		wi := cands[i]
+
+		A := twistededwards.Point{X: wi.Ax, Y: wi.Ay}
+		R := twistededwards.Point{X: wi.Sig.Rx, Y: wi.Sig.Ry}
+
+		api.AssertIsBoolean(wi.IsIgnore)
+		active := api.Sub(1, wi.IsIgnore)
+		assertOnCurveIf(api, params, A, active)
+		assertOnCurveIf(api, params, R, active)
+
+		// Schnorr challenge e = H(Rx, Ry, Ax, Ay, msg)
+		h.Reset()
+		h.Write(R.X, R.Y, A.X, A.Y, message)
+		e := h.Sum()
+
+		// z_i = H(seed, i) truncated, lowest bit forced to 1
+		h.Reset()
+		h.Write(seed, i)
+		zBits := api.ToBinary(h.Sum())[:BatchWeightBits]
+		zBits[0] = 1
+		z := api.FromBinary(zBits...)
+
+		// ignored candidates contribute the identity on both sides
+		P := E.Add(R, E.ScalarMul(A, e))
+		P.X = api.Select(active, P.X, 0)
+		P.Y = api.Select(active, P.Y, 1)
+		points[i], weights[i] = P, zBits
+
+		sBits := api.ToBinary(api.Select(active, wi.Sig.S, 0), orderBits)
+		for j := range limbSums {
+			hi := min((j+1)*batchLimbBits, orderBits)
+			limb := api.FromBinary(sBits[j*batchLimbBits : hi]...)
+			limbSums[j] = api.Add(limbSums[j], api.Mul(z, limb))
+		}
+
+		sumValid = api.Add(sumValid, active)
+	}
+
+	// sum z_i*S_i = sum_j limbSums[j] * 2^(batchLimbBits*j), each limbSums[j] is
+	// an exact integer below 2^(BatchWeightBits+batchLimbBits+log2(len(cands)))
+	var lhs twistededwards.Point
+	for j := range limbSums {
+		tables := batchLimbTables(j, log2(len(cands)))
+		P := fixedBaseMul(api, E, tables, limbSums[j])
+		if j == 0 {
+			lhs = P
+		} else {
+			lhs = E.Add(lhs, P)
+		}
+	}
+	rhs := multiScalarMulBits(api, E, points, weights)
+	api.AssertIsEqual(lhs.X, rhs.X)
+	api.AssertIsEqual(lhs.Y, rhs.Y)
+
+	// every active candidate is checked by the batch equation
+	api.AssertIsEqual(sumValid, sumValidPub)
+	return nil
+}
+
+// window tables of [2^(batchLimbBits*j)]G for the limb sums of 2^logN candidates
+func batchLimbTables(j, logN int) [][][2]*big.Int {
+	params := tebn254.GetEdwardsCurve()
+	var base tebn254.PointAffine
+	base.ScalarMultiplication(&params.Base, new(big.Int).Lsh(big.NewInt(1), uint(batchLimbBits*j)))
+	limbBits := min(batchLimbBits, params.Order.BitLen()-batchLimbBits*j)
+	return precomputeBaseTables(base, BatchWeightBits+limbBits+logN)
+}
+
+// sum [z_i]P_i with each z_i given by BatchWeightBits little-endian bits,
+// double-and-add from the top bit with the doublings shared by all points
+func multiScalarMulBits(api frontend.API, E twistededwards.Curve, points []twistededwards.Point, bits [][]frontend.Variable) twistededwards.Point {
+	acc := twistededwards.Point{X: 0, Y: 1}
+	for k := BatchWeightBits - 1; k >= 0; k-- {
+		if k != BatchWeightBits-1 {
+			acc = E.Double(acc)
+		}
+		for i, P := range points {
+			sum := E.Add(acc, P)
+			acc.X = api.Select(bits[i][k], sum.X, acc.X)
+			acc.Y = api.Select(bits[i][k], sum.Y, acc.Y)
+		}
+	}
+	return acc
+}
@@ -0,0 +1,194 @@
+package multischnorr_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	tebn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/test"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// The batch circuit replaces the per-candidate checks [S_i]G == R_i + [e_i]A_i by
+// one random linear combination. These tests try to get a bad signature through it.
+
+const batchDepth = 2 // 4 slots: 3 signers and one ignored slot
+
+type batchFixture struct {
+	keys  []utils.KeyPair
+	msg   fr.Element
+	root  fr.Element
+	cands []utils.Candidate
+	sum   int
+}
+
+func newBatchFixture(t *testing.T) *batchFixture {
+	t.Helper()
+	keys, err := utils.GeneratePaddedKeyPairs(3, batchDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg fr.Element
+	msg.SetUint64(7)
+	root, _, err := utils.BuildRoot(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands, sum, err := utils.BuildCandidates(keys, []int{0, 1, 2}, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &batchFixture{keys: keys, msg: msg, root: root, cands: cands, sum: sum}
+}
+
+func (f *batchFixture) assignment() *multischnorr.SizedBatchCircuit {
+	a := multischnorr.NewSizedBatchCircuit(batchDepth)
+	a.Root = f.root.BigInt(new(big.Int))
+	a.Message = f.msg.BigInt(new(big.Int))
+	a.SumValid = f.sum
+	for i, c := range f.cands {
+		a.S[i] = multischnorr.Candidate{
+			Ax: c.Ax, Ay: c.Ay,
+			Sig:      multischnorr.SchnorrSignature{Rx: c.Sig.Rx, Ry: c.Sig.Ry, S: c.Sig.S},
+			IsIgnore: int(c.IsIgnore),
+		}
+	}
+	return a
+}
+
+func isSolvedBatch(a *multischnorr.SizedBatchCircuit) error {
+	return test.IsSolved(multischnorr.NewSizedBatchCircuit(batchDepth), a, ecc.BN254.ScalarField())
+}
+
+func isSolvedSingle(a *multischnorr.SizedBatchCircuit) error {
+	s := multischnorr.NewSizedCircuit(batchDepth, false)
+	s.Root, s.Message, s.SumValid = a.Root, a.Message, a.SumValid
+	copy(s.S, a.S)
+	return test.IsSolved(multischnorr.NewSizedCircuit(batchDepth, false), s, ecc.BN254.ScalarField())
+}
+
+func TestBatchHonest(t *testing.T) {
+	f := newBatchFixture(t)
+	if err := isSolvedBatch(f.assignment()); err != nil {
+		t.Fatalf("honest batch rejected: %v", err)
+	}
+}
+
+func TestBatchGarbageInIgnoredSlot(t *testing.T) {
+	f := newBatchFixture(t)
+	a := f.assignment()
+	a.S[3].Sig = multischnorr.SchnorrSignature{Rx: 12345, Ry: 678, S: new(big.Int).Lsh(big.NewInt(1), 253)}
+	if err := isSolvedBatch(a); err != nil {
+		t.Fatalf("garbage in ignored slot rejected: %v", err)
+	}
+}
+
+func TestBatchWrongSumValid(t *testing.T) {
+	f := newBatchFixture(t)
+	a := f.assignment()
+	a.SumValid = f.sum + 1
+	if isSolvedBatch(a) == nil {
+		t.Fatal("SumValid off by one accepted")
+	}
+}
+
+// a single wrong S_i leaves D_i = [S_i]G - R_i - [e_i]A_i != 0, and [z_i]D_i != 0
+// for odd z_i < order, whatever the weights are
+func TestBatchForgeOneBadSignature(t *testing.T) {
+	f := newBatchFixture(t)
+	for i := 0; i < 3; i++ {
+		a := f.assignment()
+		a.S[i].Sig.S = new(big.Int).Add(f.cands[i].Sig.S, big.NewInt(1))
+		if isSolvedBatch(a) == nil {
+			t.Fatalf("bad S in slot %d accepted", i)
+		}
+	}
+
+	// claiming the key-less padding slot as a signer
+	a := f.assignment()
+	a.S[3].IsIgnore = 0
+	a.SumValid = f.sum + 1
+	if isSolvedBatch(a) == nil {
+		t.Fatal("padding slot counted as a signer")
+	}
+}
+
+// with unit weights, S_0 + d and S_1 - d would pass: the errors cancel in the sum.
+// the committed weights make this hold only if z_0 == z_1
+func TestBatchForgeOffsettingPair(t *testing.T) {
+	f := newBatchFixture(t)
+	d := big.NewInt(1000003)
+
+	a := f.assignment()
+	a.S[0].Sig.S = new(big.Int).Add(f.cands[0].Sig.S, d)
+	a.S[1].Sig.S = new(big.Int).Sub(f.cands[1].Sig.S, d)
+
+	// the unweighted check [sum S_i]G == sum R_i + [e_i]A_i still holds
+	params := tebn254.GetEdwardsCurve()
+	sumS := new(big.Int)
+	var rhs tebn254.PointAffine
+	rhs.X.SetZero()
+	rhs.Y.SetOne()
+	for i := 0; i < 3; i++ {
+		c := a.S[i]
+		sumS.Add(sumS, c.Sig.S.(*big.Int))
+
+		var R, A, eA tebn254.PointAffine
+		R.X.SetBigInt(c.Sig.Rx.(*big.Int))
+		R.Y.SetBigInt(c.Sig.Ry.(*big.Int))
+		A.X.SetBigInt(c.Ax.(*big.Int))
+		A.Y.SetBigInt(c.Ay.(*big.Int))
+		e := utils.Challenge(f.cands[i].Sig.Rx, f.cands[i].Sig.Ry, f.keys[i].Pub, f.msg)
+		eA.ScalarMultiplication(&A, e.BigInt(new(big.Int)))
+		rhs.Add(&rhs, &R)
+		rhs.Add(&rhs, &eA)
+	}
+	var lhs tebn254.PointAffine
+	lhs.ScalarMultiplication(&params.Base, sumS)
+	if !lhs.Equal(&rhs) {
+		t.Fatal("offsetting pair should pass the unweighted check")
+	}
+
+	if isSolvedBatch(a) == nil {
+		t.Fatal("offsetting pair accepted by the batch circuit")
+	}
+}
+
+// known gap: R_i = [k]G + T with T of order 2 gives D_i = -T. Two such signatures
+// give -(z_0 + z_1)T = 0 since both weights are odd. Circuit rejects them one by one.
+// Producing them needs the secret keys (e depends on R), so it only lets key holders
+// count non-canonical signatures for messages they signed anyway.
+func TestBatchTorsionPair(t *testing.T) {
+	f := newBatchFixture(t)
+	params := tebn254.GetEdwardsCurve()
+	var T tebn254.PointAffine // (0, -1), order 2
+	T.X.SetZero()
+	T.Y.SetOne()
+	T.Y.Neg(&T.Y)
+
+	a := f.assignment()
+	for i := 0; i < 2; i++ {
+		k := big.NewInt(int64(1000 + i))
+		var R tebn254.PointAffine
+		R.ScalarMultiplication(&params.Base, k)
+		R.Add(&R, &T)
+		rx, ry := R.X.BigInt(new(big.Int)), R.Y.BigInt(new(big.Int))
+
+		e := utils.Challenge(rx, ry, f.keys[i].Pub, f.msg)
+		S := new(big.Int).Mul(e.BigInt(new(big.Int)), f.keys[i].Priv.Sk)
+		S.Add(S, k)
+		S.Mod(S, &params.Order)
+		a.S[i].Sig = multischnorr.SchnorrSignature{Rx: rx, Ry: ry, S: S}
+	}
+
+	if isSolvedSingle(a) == nil {
+		t.Fatal("torsion signatures accepted by Circuit")
+	}
+	if err := isSolvedBatch(a); err != nil {
+		t.Fatalf("expected the torsion pair to pass the batch check: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package multischnorr_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// current vs fixed-base verification, side by side
+// run with: go test -run XXX -bench BenchmarkVerify -benchtime 1x
+// (depth 8 needs a few GB of RAM for the setup)
+func BenchmarkVerify(b *testing.B) {
+	for _, depth := range []int{6, 7, 8} {
+		k := 1 << depth
+		for _, optimized := range []bool{false, true} {
+			name := "current"
+			if optimized {
+				name = "optimized"
+			}
+			b.Run(fmt.Sprintf("MaxK=%d/%s", k, name), func(b *testing.B) {
+				benchmarkProve(b, depth, optimized)
+			})
+		}
+	}
+}
+
+func benchmarkProve(b *testing.B, depth int, optimized bool) {
+	k := 1 << depth
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, multischnorr.NewSizedCircuit(depth, optimized))
+	if err != nil {
+		b.Fatalf("compile: %v", err)
+	}
+	pk, _, err := groth16.Setup(cs)
+	if err != nil {
+		b.Fatalf("setup: %v", err)
+	}
+
+	// two thirds of the keys sign, the rest are ignored
+	keys, err := utils.GeneratePaddedKeyPairs(k, depth)
+	if err != nil {
+		b.Fatalf("keygen: %v", err)
+	}
+	signers := make([]int, 0, k)
+	for i := 0; i < k; i++ {
+		if i%3 != 2 {
+			signers = append(signers, i)
+		}
+	}
+	var msg fr.Element
+	msg.SetUint64(42)
+	root, _, err := utils.BuildRoot(keys)
+	if err != nil {
+		b.Fatalf("root: %v", err)
+	}
+	cands, sumValid, err := utils.BuildCandidates(keys, signers, msg, nil, nil)
+	if err != nil {
+		b.Fatalf("candidates: %v", err)
+	}
+
+	assignment := multischnorr.NewSizedCircuit(depth, optimized)
+	assignment.Root = root.BigInt(new(big.Int))
+	assignment.Message = msg.BigInt(new(big.Int))
+	assignment.SumValid = sumValid
+	for i, c := range cands {
+		assignment.S[i].Ax = c.Ax
+		assignment.S[i].Ay = c.Ay
+		assignment.S[i].Sig.Rx = c.Sig.Rx
+		assignment.S[i].Sig.Ry = c.Sig.Ry
+		assignment.S[i].Sig.S = c.Sig.S
+		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+	}
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		b.Fatalf("witness: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := groth16.Prove(cs, pk, w); err != nil {
+			b.Fatalf("prove: %v", err)
+		}
+	}
+	b.ReportMetric(float64(cs.GetNbConstraints()), "constraints")
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Result is one entry of multi-zkvm/benchmark/benchmark_results.json. The first six
+// fields are the schema shared with the zkVM hosts (read by plot_benchmarks.py):
+// for a circuit, "cycles" are R1CS constraints and "execution" is witness
+// generation plus solving, the work a zkVM does when it executes the program.
+type Result struct {
+	ZkVM              string  `json:"zkvm"`
+	NumSigners        int     `json:"num_signers"`
+	ExecutionCycles   int     `json:"execution_cycles"`
+	ExecutionDuration float64 `json:"execution_duration"`
+	ProvingDuration   float64 `json:"proving_duration"`
+	Timestamp         string  `json:"timestamp"`
+
+	// gnark only
+	Depth           int     `json:"depth,omitempty"`
+	Optimized       bool    `json:"optimized,omitempty"`
+	CompileDuration float64 `json:"compile_duration,omitempty"`
+	SetupDuration   float64 `json:"setup_duration,omitempty"`
+	WitnessDuration float64 `json:"witness_duration,omitempty"`
+	PeakMemoryBytes uint64  `json:"peak_memory_bytes,omitempty"`
+}
+
+type resultsFile struct {
+	Results []Result `json:"results"`
+}
+
+func main() {
+	signersStr := flag.String("signers", "12,48,64", "comma-separated signer counts")
+	depthsStr := flag.String("depth", "", "comma-separated Merkle depths (default: smallest depth holding all signers)")
+	optimized := flag.Bool("optimized", false, "benchmark OptimizedCircuit's fixed-base verification")
+	label := flag.String("label", "gnark", "zkvm name written to the results")
+	out := flag.String("out", utils.RepoPath("../../../multi-zkvm/benchmark/benchmark_results.json"), "results file, appended to (empty: print only)")
+	flag.Parse()
+
+	signers, err := parseList(*signersStr)
+	if err != nil {
+		log.Fatalf("--signers: %v", err)
+	}
+	var depths []int
+	if *depthsStr != "" {
+		if depths, err = parseList(*depthsStr); err != nil {
+			log.Fatalf("--depth: %v", err)
+		}
+	}
+
+	var results []Result
+	for _, n := range signers {
+		ds := depths
+		if ds == nil {
+			ds = []int{minDepth(n)}
+		}
+		for _, d := range ds {
+			if n > 1<<d {
+				fmt.Printf(">> skipping %d signers at depth %d (max %d)\n", n, d, 1<<d)
+				continue
+			}
+			name := *label
+			if len(depths) > 1 {
+				// plot_benchmarks.py keys on (zkvm, num_signers)
+				name = fmt.Sprintf("%s-d%d", *label, d)
+			}
+			fmt.Printf(">> %s: %d signers, depth %d\n", name, n, d)
+			r, err := run(n, d, *optimized)
+			if err != nil {
+				log.Fatalf("%d signers, depth %d: %v", n, d, err)
+			}
+			r.ZkVM = name
+			fmt.Printf("   constraints %d | compile %.2fs | setup %.2fs | witness %.2fs | solve+witness %.2fs | prove %.2fs | peak %d MiB\n",
+				r.ExecutionCycles, r.CompileDuration, r.SetupDuration, r.WitnessDuration,
+				r.ExecutionDuration, r.ProvingDuration, r.PeakMemoryBytes>>20)
+			results = append(results, r)
+		}
+	}
+
+	if *out == "" {
+		return
+	}
+	if err := appendResults(*out, results); err != nil {
+		log.Fatalf("write %s: %v", *out, err)
+	}
+	fmt.Printf("Results appended to %s\n", *out)
+}
+
+// n signers in a registry of 2^depth keys, padded with identity leaves
+func run(n, depth int, optimized bool) (Result, error) {
+	runtime.GC()
+	debug.FreeOSMemory()
+	mem := startPeakMemory()
+	defer mem.stop()
+
+	r := Result{
+		NumSigners: n,
+		Depth:      depth,
+		Optimized:  optimized,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	start := time.Now()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, multischnorr.NewSizedCircuit(depth, optimized))
+	if err != nil {
+		return r, fmt.Errorf("compile: %w", err)
+	}
+	r.CompileDuration = time.Since(start).Seconds()
+	r.ExecutionCycles = ccs.GetNbConstraints()
+
+	start = time.Now()
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		return r, fmt.Errorf("setup: %w", err)
+	}
+	r.SetupDuration = time.Since(start).Seconds()
+
+	// keys are not part of the measurement, the zkVM hosts also read them from a file
+	keys, err := utils.GeneratePaddedKeyPairs(n, depth)
+	if err != nil {
+		return r, fmt.Errorf("keygen: %w", err)
+	}
+	signerIdx := make([]int, n)
+	for i := range signerIdx {
+		signerIdx[i] = i
+	}
+	msg := utils.KeccakToFr([]byte("benchmark"))
+
+	// witness: Merkle root, signatures and assignment
+	start = time.Now()
+	root, _, err := utils.BuildRoot(keys)
+	if err != nil {
+		return r, fmt.Errorf("root: %w", err)
+	}
+	cands, sumValid, err := utils.BuildCandidates(keys, signerIdx, msg, nil, nil)
+	if err != nil {
+		return r, fmt.Errorf("candidates: %w", err)
+	}
+	assignment := multischnorr.NewSizedCircuit(depth, optimized)
+	assignment.Root = root.BigInt(new(big.Int))
+	assignment.Message = msg.BigInt(new(big.Int))
+	assignment.SumValid = sumValid
+	for i, c := range cands {
+		assignment.S[i].Ax = c.Ax
+		assignment.S[i].Ay = c.Ay
+		assignment.S[i].Sig.Rx = c.Sig.Rx
+		assignment.S[i].Sig.Ry = c.Sig.Ry
+		assignment.S[i].Sig.S = c.Sig.S
+		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+	}
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return r, fmt.Errorf("witness: %w", err)
+	}
+	r.WitnessDuration = time.Since(start).Seconds()
+
+	start = time.Now()
+	if _, err := ccs.Solve(w); err != nil {
+		return r, fmt.Errorf("solve: %w", err)
+	}
+	r.ExecutionDuration = r.WitnessDuration + time.Since(start).Seconds()
+
+	// groth16.Prove solves again, as the zkVM provers re-execute
+	start = time.Now()
+	proof, err := groth16.Prove(ccs, pk, w)
+	if err != nil {
+		return r, fmt.Errorf("prove: %w", err)
+	}
+	r.ProvingDuration = time.Since(start).Seconds()
+
+	pubW, err := w.Public()
+	if err != nil {
+		return r, err
+	}
+	if err := groth16.Verify(proof, vk, pubW); err != nil {
+		return r, fmt.Errorf("verify: %w", err)
+	}
+
+	r.PeakMemoryBytes = mem.stop()
+	return r, nil
+}
+
+func minDepth(n int) int {
+	d := 1 // GeneratePaddedKeyPairs needs depth > 0
+	for 1<<d < n {
+		d++
+	}
+	return d
+}
+
+// peakMemory samples the memory the Go runtime holds from the OS
+// (mapped minus released) until stop, which returns the maximum seen
+type peakMemory struct {
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+	peak uint64
+}
+
+var memSamples = []metrics.Sample{
+	{Name: "/memory/classes/total:bytes"},
+	{Name: "/memory/classes/heap/released:bytes"},
+}
+
+func startPeakMemory() *peakMemory {
+	m := &peakMemory{done: make(chan struct{})}
+	m.sample()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		t := time.NewTicker(5 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-t.C:
+				m.sample()
+			}
+		}
+	}()
+	return m
+}
+
+func (m *peakMemory) sample() {
+	s := make([]metrics.Sample, len(memSamples))
+	copy(s, memSamples)
+	metrics.Read(s)
+	if held := s[0].Value.Uint64() - s[1].Value.Uint64(); held > m.peak {
+		m.peak = held
+	}
+}
+
+func (m *peakMemory) stop() uint64 {
+	m.once.Do(func() {
+		close(m.done)
+		m.wg.Wait()
+		m.sample()
+	})
+	return m.peak
+}
+
+// keeps the entries already in path (the zkVM runs), plot_benchmarks.py
+// picks the latest timestamp per (zkvm, num_signers)
+func appendResults(path string, results []Result) error {
+	var f resultsFile
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	f.Results = append(f.Results, results...)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func parseList(s string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("%d is not positive", n)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
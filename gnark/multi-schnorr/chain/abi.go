@@ -0,0 +1,142 @@
+package chain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	VerifySignature           = "verify(uint256[8],bytes,uint256,uint256)"
+	VerifyCompressedSignature = "verifyCompressed(uint256[4],bytes,uint256,uint256)"
+)
+
+// Rejections of MultiSchnorrVerifier.verify, in the order the contract checks them.
+// The last two are raised by the Groth16 Verifier it calls.
+var (
+	ErrInsufficientSignatures = errors.New("InsufficientSignatures")
+	ErrInvalidMerkleRoot      = errors.New("InvalidMerkleRoot")
+	ErrPublicInputNotInField  = errors.New("PublicInputNotInField")
+	ErrProofInvalid           = errors.New("ProofInvalid")
+)
+
+var contractErrors = []error{ErrInsufficientSignatures, ErrInvalidMerkleRoot, ErrPublicInputNotInField, ErrProofInvalid}
+
+var uint256Max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Selector is the first 4 bytes of keccak256(signature).
+func Selector(signature string) [4]byte {
+	var s [4]byte
+	copy(s[:], Keccak256([]byte(signature)))
+	return s
+}
+
+func word(n *big.Int) ([]byte, error) {
+	if n == nil || n.Sign() < 0 || n.Cmp(uint256Max) > 0 {
+		return nil, fmt.Errorf("value %v does not fit uint256", n)
+	}
+	w := make([]byte, 32)
+	n.FillBytes(w)
+	return w, nil
+}
+
+func wordUint(n uint64) []byte {
+	w := make([]byte, 32)
+	binary.BigEndian.PutUint64(w[24:], n)
+	return w
+}
+
+func wordAddress(a Address) []byte {
+	w := make([]byte, 32)
+	copy(w[12:], a[:])
+	return w
+}
+
+// EncodeVerify is the calldata of MultiSchnorrVerifier.verify(proof, message, merkleRoot, sumValid).
+func EncodeVerify(proof [8]*big.Int, message []byte, merkleRoot, sumValid *big.Int) ([]byte, error) {
+	return encodeVerify(VerifySignature, proof[:], message, merkleRoot, sumValid)
+}
+
+// EncodeVerifyCompressed is the calldata of MultiSchnorrVerifier.verifyCompressed,
+// taking the proof from proving.CompressSolidityProof.
+func EncodeVerifyCompressed(proof [4]*big.Int, message []byte, merkleRoot, sumValid *big.Int) ([]byte, error) {
+	return encodeVerify(VerifyCompressedSignature, proof[:], message, merkleRoot, sumValid)
+}
+
+func encodeVerify(signature string, proof []*big.Int, message []byte, merkleRoot, sumValid *big.Int) ([]byte, error) {
+	sel := Selector(signature)
+	out := append([]byte{}, sel[:]...)
+
+	// head: uint256[N] inline, offset of bytes, merkleRoot, sumValid
+	for i, p := range proof {
+		w, err := word(p)
+		if err != nil {
+			return nil, fmt.Errorf("proof[%d]: %w", i, err)
+		}
+		out = append(out, w...)
+	}
+	headWords := len(proof) + 3
+	out = append(out, wordUint(uint64(headWords*32))...)
+	for _, v := range []struct {
+		name string
+		n    *big.Int
+	}{{"merkleRoot", merkleRoot}, {"sumValid", sumValid}} {
+		w, err := word(v.n)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.name, err)
+		}
+		out = append(out, w...)
+	}
+
+	// tail: length-prefixed message, right-padded to a word
+	out = append(out, wordUint(uint64(len(message)))...)
+	out = append(out, message...)
+	if pad := len(message) % 32; pad != 0 {
+		out = append(out, make([]byte, 32-pad)...)
+	}
+	return out, nil
+}
+
+// EncodeConstructor is the constructor argument block appended to the
+// MultiSchnorrVerifier creation code: (Verifier _verifier, uint256 _threshold, uint256 _root, address _owner).
+func EncodeConstructor(verifier Address, threshold, root *big.Int, owner Address) ([]byte, error) {
+	t, err := word(threshold)
+	if err != nil {
+		return nil, fmt.Errorf("threshold: %w", err)
+	}
+	r, err := word(root)
+	if err != nil {
+		return nil, fmt.Errorf("root: %w", err)
+	}
+	out := wordAddress(verifier)
+	out = append(out, t...)
+	out = append(out, r...)
+	return append(out, wordAddress(owner)...), nil
+}
+
+// DecodeUint256 reads a single uint256 return value (e.g. threshold() or merkleRoot()).
+func DecodeUint256(ret []byte) (*big.Int, error) {
+	if len(ret) != 32 {
+		return nil, fmt.Errorf("want 32 bytes of return data, got %d", len(ret))
+	}
+	return new(big.Int).SetBytes(ret), nil
+}
+
+// ErrReverted is wrapped when a call or transaction reverts.
+var ErrReverted = errors.New("execution reverted")
+
+// RevertReason maps revert data to one of the contract errors above, or nil if unknown.
+func RevertReason(data []byte) error {
+	if len(data) < 4 {
+		return nil
+	}
+	var sel [4]byte
+	copy(sel[:], data)
+	for _, e := range contractErrors {
+		if Selector(e.Error()+"()") == sel {
+			return e
+		}
+	}
+	return nil
+}
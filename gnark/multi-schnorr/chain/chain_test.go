@@ -0,0 +1,352 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
+)
+
+const testKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestSelector(t *testing.T) {
+	if got := Selector("transfer(address,uint256)"); hex.EncodeToString(got[:]) != "a9059cbb" {
+		t.Fatalf("transfer selector = %x", got)
+	}
+}
+
+func TestSignerAddress(t *testing.T) {
+	s, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Address().Hex(); got != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatalf("address = %s", got)
+	}
+	if _, err := ParseAddress("0x2c7536e3605D9C16a7a3D7b1898e529396a65c23"); err == nil {
+		t.Fatal("bad checksum accepted")
+	}
+}
+
+// addresses and constructor arguments of the Sepolia deployment in contract/broadcast
+func TestBroadcastVectors(t *testing.T) {
+	from, _ := ParseAddress("0xbE2BB973378e08b42C780A1b5a5a92F89a260Fc8")
+	verifier := CreateAddress(from, 0x21)
+	if verifier.Hex() != "0xC68eB7495ea6903f251c72f08BfAFaDc5086Eb07" {
+		t.Fatalf("Verifier address = %s", verifier)
+	}
+	if got := CreateAddress(from, 0x22).Hex(); !strings.EqualFold(got, "0x3d807990d9f32e4e99c5046210c03116b2be6e88") {
+		t.Fatalf("MultiSchnorrVerifier address = %s", got)
+	}
+
+	root, _ := new(big.Int).SetString("21617503940289871558147625055307690841067887483820893516385878055972886947720", 10)
+	args, err := EncodeConstructor(verifier, big.NewInt(43), root, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "000000000000000000000000c68eb7495ea6903f251c72f08bfafadc5086eb07" +
+		"000000000000000000000000000000000000000000000000000000000000002b" +
+		"2fcb12d9c7407a1382347efc71105e153c13634b12e19c062810a29349ba2788" +
+		"000000000000000000000000be2bb973378e08b42c780a1b5a5a92f89a260fc8"
+	if hex.EncodeToString(args) != want {
+		t.Fatalf("constructor args:\n got %x\nwant %s", args, want)
+	}
+}
+
+func TestEncodeVerify(t *testing.T) {
+	var proof [8]*big.Int
+	for i := range proof {
+		proof[i] = big.NewInt(int64(i + 1))
+	}
+	msg := []byte("hello, multi-schnorr: a message longer than one word")
+	data, err := EncodeVerify(proof, msg, big.NewInt(7), big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel := Selector(VerifySignature)
+	if !bytes.Equal(data[:4], sel[:]) {
+		t.Fatalf("selector %x", data[:4])
+	}
+	words := data[4:]
+	if len(words)%32 != 0 || len(words) != (11+1+2)*32 {
+		t.Fatalf("calldata length %d", len(data))
+	}
+	at := func(i int) *big.Int { return new(big.Int).SetBytes(words[32*i : 32*(i+1)]) }
+	for i := 0; i < 8; i++ {
+		if at(i).Int64() != int64(i+1) {
+			t.Fatalf("proof[%d] = %v", i, at(i))
+		}
+	}
+	if at(8).Int64() != 11*32 || at(9).Int64() != 7 || at(10).Int64() != 3 || at(11).Int64() != int64(len(msg)) {
+		t.Fatalf("head/length words wrong: %v %v %v %v", at(8), at(9), at(10), at(11))
+	}
+	if !bytes.Equal(words[12*32:12*32+len(msg)], msg) {
+		t.Fatal("message bytes")
+	}
+
+	if _, err := EncodeVerify(proof, msg, new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(3)); err == nil {
+		t.Fatal("2^256 accepted as uint256")
+	}
+}
+
+// verifyCompressed: same layout with a 4-word proof, 128 bytes shorter
+func TestEncodeVerifyCompressed(t *testing.T) {
+	var proof [8]*big.Int
+	var compressed [4]*big.Int
+	for i := range proof {
+		proof[i] = big.NewInt(int64(i + 1))
+	}
+	for i := range compressed {
+		compressed[i] = big.NewInt(int64(i + 1))
+	}
+	msg := []byte("hello")
+	full, err := EncodeVerify(proof, msg, big.NewInt(7), big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeVerifyCompressed(compressed, msg, big.NewInt(7), big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := Selector(VerifyCompressedSignature)
+	if !bytes.Equal(data[:4], sel[:]) {
+		t.Fatalf("selector %x", data[:4])
+	}
+	if len(full)-len(data) != 4*32 {
+		t.Fatalf("compressed calldata %d bytes, uncompressed %d", len(data), len(full))
+	}
+	words := data[4:]
+	at := func(i int) *big.Int { return new(big.Int).SetBytes(words[32*i : 32*(i+1)]) }
+	if at(3).Int64() != 4 || at(4).Int64() != 7*32 || at(5).Int64() != 7 || at(6).Int64() != 3 || at(7).Int64() != int64(len(msg)) {
+		t.Fatalf("head/length words wrong: %v %v %v %v %v", at(3), at(4), at(5), at(6), at(7))
+	}
+	if !bytes.Equal(words[8*32:8*32+len(msg)], msg) {
+		t.Fatal("message bytes")
+	}
+}
+
+func TestSignTxRecoversSender(t *testing.T) {
+	s, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := s.Address()
+	tx := &DynamicFeeTx{
+		ChainID: big.NewInt(31337), Nonce: 5, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(3e9),
+		Gas: 21000, To: &to, Value: big.NewInt(1), Data: []byte{0xde, 0xad},
+	}
+	halfN := new(big.Int).Rsh(secp256k1N, 1)
+	for i := 0; i < 16; i++ {
+		v, r, sig, err := s.sign(tx.SigningHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Cmp(halfN) > 0 {
+			t.Fatal("high-s signature")
+		}
+		var pub ecdsa.PublicKey
+		if err := pub.RecoverFrom(tx.SigningHash(), uint(v), r, sig); err != nil {
+			t.Fatal(err)
+		}
+		xy := pub.A.RawBytes()
+		var got Address
+		copy(got[:], Keccak256(xy[:])[12:])
+		if got != s.Address() {
+			t.Fatalf("recovered %s, want %s", got, s.Address())
+		}
+	}
+
+	raw, _, err := s.SignTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[0] != 0x02 || raw[1] < 0xc0 {
+		t.Fatalf("not a type-2 envelope: %x", raw[:2])
+	}
+}
+
+// mockNode is an in-memory JSON-RPC endpoint that mines every transaction immediately.
+type mockNode struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	from     Address
+	calldata [][]byte // data of every eth_estimateGas, in order
+	sent     []Hash
+	revert   []byte // if set, eth_estimateGas reverts with this data
+}
+
+func (m *mockNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		m.t.Errorf("mock: %v", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, rpcErr := m.handle(req.Method, req.Params)
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (m *mockNode) handle(method string, params []json.RawMessage) (any, *RPCError) {
+	switch method {
+	case "eth_chainId":
+		return "0x7a69", nil
+	case "eth_getTransactionCount":
+		return fmt.Sprintf("0x%x", len(m.sent)), nil
+	case "eth_getBlockByNumber":
+		return map[string]string{"baseFeePerGas": "0x3b9aca00"}, nil
+	case "eth_maxPriorityFeePerGas":
+		return "0x1", nil
+	case "eth_estimateGas":
+		var arg struct{ Data string }
+		_ = json.Unmarshal(params[0], &arg)
+		if m.revert != nil {
+			data, _ := json.Marshal("0x" + hex.EncodeToString(m.revert))
+			return nil, &RPCError{Code: 3, Message: "execution reverted", Data: data}
+		}
+		b, _ := decodeHex(arg.Data)
+		m.calldata = append(m.calldata, b)
+		return "0x5208", nil
+	case "eth_sendRawTransaction":
+		var s string
+		_ = json.Unmarshal(params[0], &s)
+		raw, _ := decodeHex(s)
+		var h Hash
+		copy(h[:], Keccak256(raw))
+		m.sent = append(m.sent, h)
+		return h.Hex(), nil
+	case "eth_getTransactionReceipt":
+		var s string
+		_ = json.Unmarshal(params[0], &s)
+		for nonce, h := range m.sent {
+			if h.Hex() == s {
+				return map[string]any{
+					"transactionHash": s,
+					"status":          "0x1",
+					"blockNumber":     fmt.Sprintf("0x%x", 100+nonce),
+					"gasUsed":         "0x5208",
+					"contractAddress": CreateAddress(m.from, uint64(nonce)).Hex(),
+				}, nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, &RPCError{Code: -32601, Message: "method not found: " + method}
+}
+
+func newMock(t *testing.T) (*mockNode, *Transactor) {
+	signer, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockNode{t: t, from: signer.Address()}
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	tr, err := NewTransactor(context.Background(), NewClient(srv.URL), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.PollInterval = time.Millisecond
+	return m, tr
+}
+
+func writeArtifact(t *testing.T, dir, file, contract, code string) {
+	t.Helper()
+	p := filepath.Join(dir, file)
+	if err := os.MkdirAll(p, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`{"abi":[],"bytecode":{"object":"%s"}}`, code)
+	if err := os.WriteFile(filepath.Join(p, contract+".json"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	m, tr := newMock(t)
+	out := t.TempDir()
+	writeArtifact(t, out, "Verifier.sol", "Verifier", "0x6001")
+	writeArtifact(t, out, "MultiSchnorrVerifier.sol", "MultiSchnorrVerifier", "0x6002")
+
+	d, err := Deploy(context.Background(), tr, DeployConfig{OutDir: out, Threshold: big.NewInt(43), MerkleRoot: big.NewInt(99)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Network != "anvil" || d.ChainID != 31337 || d.Owner != tr.From() {
+		t.Fatalf("deployment %+v", d)
+	}
+	if d.Verifier != CreateAddress(tr.From(), 0) || d.MultiSchnorrVerifier != CreateAddress(tr.From(), 1) {
+		t.Fatalf("addresses %s %s", d.Verifier, d.MultiSchnorrVerifier)
+	}
+
+	// second creation code is MultiSchnorrVerifier bytecode || constructor(verifier, 43, 99, deployer)
+	args, _ := EncodeConstructor(d.Verifier, big.NewInt(43), big.NewInt(99), tr.From())
+	if len(m.calldata) != 2 || !bytes.Equal(m.calldata[1], append([]byte{0x60, 0x02}, args...)) {
+		t.Fatalf("creation code %x", m.calldata)
+	}
+
+	path := filepath.Join(t.TempDir(), "deployment.json")
+	if err := WriteDeployment(path, d); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadDeployment(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *back != *d {
+		t.Fatalf("round trip:\n got %+v\nwant %+v", back, d)
+	}
+}
+
+func TestLoadArtifactUnlinked(t *testing.T) {
+	out := t.TempDir()
+	writeArtifact(t, out, "Verifier.sol", "Verifier", "0x73__$abc$__")
+	if _, err := LoadArtifact(out, "Verifier.sol", "Verifier"); err == nil {
+		t.Fatal("unlinked bytecode accepted")
+	}
+}
+
+func TestSubmitProofRevert(t *testing.T) {
+	m, tr := newMock(t)
+	sel := Selector("InsufficientSignatures()")
+	m.revert = sel[:]
+
+	p := &ProofFile{Input: []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}, MessageHex: "0x68656c6c6f"}
+	for i := range p.Proof {
+		p.Proof[i] = big.NewInt(0)
+	}
+	_, err := SubmitProof(context.Background(), tr, CreateAddress(tr.From(), 1), p, ProofUncompressed)
+	if !errors.Is(err, ErrReverted) || !errors.Is(err, ErrInsufficientSignatures) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(m.sent) != 0 {
+		t.Fatal("reverting call was broadcast")
+	}
+}
@@ -0,0 +1,348 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
+)
+
+// Artifact is the part of a Foundry build artifact (out/<File>.sol/<Contract>.json) needed to deploy.
+type Artifact struct {
+	Bytecode struct {
+		Object string `json:"object"`
+	} `json:"bytecode"`
+}
+
+// LoadArtifact reads the creation code of contract from a Foundry out directory.
+func LoadArtifact(outDir, file, contract string) ([]byte, error) {
+	path := filepath.Join(outDir, file, contract+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: %w (run forge build)", err)
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("artifact %s: %w", path, err)
+	}
+	if strings.Contains(a.Bytecode.Object, "__$") {
+		return nil, fmt.Errorf("artifact %s: bytecode has unlinked libraries", path)
+	}
+	code, err := decodeHex(a.Bytecode.Object)
+	if err != nil {
+		return nil, fmt.Errorf("artifact %s: %w", path, err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("artifact %s: empty bytecode (abstract contract or interface?)", path)
+	}
+	return code, nil
+}
+
+// Transactor signs and sends transactions from one account and waits for them to be mined.
+type Transactor struct {
+	Client  *Client
+	Signer  *Signer
+	ChainID *big.Int
+
+	// PollInterval is how often receipts are polled (default 2s).
+	PollInterval time.Duration
+	// GasMargin is added to eth_estimateGas, in percent (default 20).
+	GasMargin uint64
+}
+
+func NewTransactor(ctx context.Context, client *Client, signer *Signer) (*Transactor, error) {
+	id, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("chain id: %w", err)
+	}
+	return &Transactor{Client: client, Signer: signer, ChainID: id, PollInterval: 2 * time.Second, GasMargin: 20}, nil
+}
+
+func (t *Transactor) From() Address { return t.Signer.Address() }
+
+// Send signs data to `to` (nil deploys data as creation code), broadcasts it and
+// waits for the receipt. Calls that would revert are rejected before broadcast
+// with an error wrapping ErrReverted.
+func (t *Transactor) Send(ctx context.Context, to *Address, data []byte) (*Receipt, error) {
+	from := t.From()
+	gas, err := t.Client.EstimateGas(ctx, CallMsg{From: from, To: to, Data: data})
+	if err != nil {
+		return nil, revertError(err)
+	}
+	gas += gas * t.GasMargin / 100
+
+	nonce, err := t.Client.PendingNonce(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	baseFee, err := t.Client.BaseFee(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("base fee: %w", err)
+	}
+	tip, err := t.Client.MaxPriorityFee(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("priority fee: %w", err)
+	}
+	// survives a few full blocks of base fee increase
+	feeCap := new(big.Int).Add(new(big.Int).Lsh(baseFee, 1), tip)
+
+	raw, hash, err := t.Signer.SignTx(&DynamicFeeTx{
+		ChainID:   t.ChainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        to,
+		Value:     new(big.Int),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sent, err := t.Client.SendRawTransaction(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("send %s: %w", hash, err)
+	}
+	if sent != hash {
+		return nil, fmt.Errorf("node returned tx hash %s, expected %s", sent, hash)
+	}
+
+	poll := t.PollInterval
+	if poll <= 0 {
+		poll = 2 * time.Second
+	}
+	rcpt, err := t.Client.WaitMined(ctx, hash, poll)
+	if err != nil {
+		return nil, fmt.Errorf("wait %s: %w", hash, err)
+	}
+	if rcpt.Status != 1 {
+		return rcpt, fmt.Errorf("tx %s: %w", hash, ErrReverted)
+	}
+	return rcpt, nil
+}
+
+func revertError(err error) error {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && (rpcErr.Code == 3 || strings.Contains(rpcErr.Message, "revert")) {
+		data := rpcErr.RevertData()
+		if reason := RevertReason(data); reason != nil {
+			return fmt.Errorf("%w: %w", ErrReverted, reason)
+		}
+		if len(data) > 0 {
+			return fmt.Errorf("%w: 0x%x", ErrReverted, data)
+		}
+		return fmt.Errorf("%w: %s", ErrReverted, rpcErr.Message)
+	}
+	return fmt.Errorf("estimate gas: %w", err)
+}
+
+// Deployment is written to deployment.json by Deploy and read back by the prove/submit tooling.
+type Deployment struct {
+	Network              string      `json:"network"`
+	ChainID              uint64      `json:"chainId"`
+	Verifier             Address     `json:"verifier"`
+	MultiSchnorrVerifier Address     `json:"multiSchnorrVerifier"`
+	Owner                Address     `json:"owner"`
+	Threshold            string      `json:"threshold"`
+	MerkleRoot           string      `json:"merkleRoot"`
+	VerifierTx           Hash        `json:"verifierTx"`
+	MultiSchnorrTx       Hash        `json:"multiSchnorrVerifierTx"`
+	Block                uint64      `json:"block"`
+	ProofFormat          ProofFormat `json:"proofFormat,omitempty"` // entry point used by submit
+	DeployedAt           time.Time   `json:"deployedAt"`
+}
+
+// DeployConfig are the constructor arguments of MultiSchnorrVerifier; the
+// Groth16 Verifier is deployed first and passed as _verifier.
+type DeployConfig struct {
+	OutDir      string // Foundry out directory holding Verifier.sol/ and MultiSchnorrVerifier.sol/
+	Threshold   *big.Int
+	MerkleRoot  *big.Int
+	Owner       *Address    // default: the deployer
+	Network     string      // default: derived from the chain id
+	ProofFormat ProofFormat // recorded in deployment.json; both entry points are deployed
+}
+
+// Deploy mirrors script/DeployMultiSchnorrVerifier.s.sol.
+func Deploy(ctx context.Context, t *Transactor, cfg DeployConfig) (*Deployment, error) {
+	verifierCode, err := LoadArtifact(cfg.OutDir, "Verifier.sol", "Verifier")
+	if err != nil {
+		return nil, err
+	}
+	msvCode, err := LoadArtifact(cfg.OutDir, "MultiSchnorrVerifier.sol", "MultiSchnorrVerifier")
+	if err != nil {
+		return nil, err
+	}
+	owner := t.From()
+	if cfg.Owner != nil {
+		owner = *cfg.Owner
+	}
+
+	vRcpt, err := t.Send(ctx, nil, verifierCode)
+	if err != nil {
+		return nil, fmt.Errorf("deploy Verifier: %w", err)
+	}
+	if vRcpt.ContractAddress == nil {
+		return nil, fmt.Errorf("deploy Verifier: receipt %s has no contract address", vRcpt.TxHash)
+	}
+
+	args, err := EncodeConstructor(*vRcpt.ContractAddress, cfg.Threshold, cfg.MerkleRoot, owner)
+	if err != nil {
+		return nil, fmt.Errorf("constructor: %w", err)
+	}
+	mRcpt, err := t.Send(ctx, nil, append(msvCode, args...))
+	if err != nil {
+		return nil, fmt.Errorf("deploy MultiSchnorrVerifier: %w", err)
+	}
+	if mRcpt.ContractAddress == nil {
+		return nil, fmt.Errorf("deploy MultiSchnorrVerifier: receipt %s has no contract address", mRcpt.TxHash)
+	}
+
+	network := cfg.Network
+	if network == "" {
+		network = NetworkName(t.ChainID)
+	}
+	return &Deployment{
+		Network:              network,
+		ChainID:              t.ChainID.Uint64(),
+		Verifier:             *vRcpt.ContractAddress,
+		MultiSchnorrVerifier: *mRcpt.ContractAddress,
+		Owner:                owner,
+		Threshold:            cfg.Threshold.String(),
+		MerkleRoot:           cfg.MerkleRoot.String(),
+		VerifierTx:           vRcpt.TxHash,
+		MultiSchnorrTx:       mRcpt.TxHash,
+		Block:                mRcpt.BlockNumber,
+		ProofFormat:          cfg.ProofFormat,
+		DeployedAt:           time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+func NetworkName(chainID *big.Int) string {
+	switch chainID.Uint64() {
+	case 1:
+		return "mainnet"
+	case 11155111:
+		return "sepolia"
+	case 31337:
+		return "anvil"
+	}
+	return "chain-" + chainID.String()
+}
+
+func WriteDeployment(path string, d *Deployment) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func ReadDeployment(path string) (*Deployment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Deployment
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// ProofFormat selects the MultiSchnorrVerifier entry point: verify (8-word
+// proof) or verifyCompressed (4 words, half the proof calldata, more gas to decompress).
+type ProofFormat string
+
+const (
+	ProofUncompressed ProofFormat = "uncompressed"
+	ProofCompressed   ProofFormat = "compressed"
+)
+
+func ParseProofFormat(s string) (ProofFormat, error) {
+	switch f := ProofFormat(s); f {
+	case ProofUncompressed, ProofCompressed:
+		return f, nil
+	case "":
+		return ProofUncompressed, nil
+	}
+	return "", fmt.Errorf("unknown proof format %q (uncompressed | compressed)", s)
+}
+
+// ProofFile is proof.json as written by the prover.
+type ProofFile struct {
+	Proof           [8]*big.Int  `json:"proof"`
+	CompressedProof *[4]*big.Int `json:"compressedProof,omitempty"` // absent in proofs from older provers
+	Input           []*big.Int   `json:"input"`                     // Root, Message (keccak mod r), SumValid
+	MessageHex      string       `json:"messageHex"`
+}
+
+func ReadProofFile(path string) (*ProofFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p ProofFile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, v := range p.Proof {
+		if v == nil {
+			return nil, fmt.Errorf("%s: proof[%d] missing", path, i)
+		}
+	}
+	if p.CompressedProof != nil {
+		for i, v := range p.CompressedProof {
+			if v == nil {
+				return nil, fmt.Errorf("%s: compressedProof[%d] missing", path, i)
+			}
+		}
+	}
+	if len(p.Input) != 3 {
+		return nil, fmt.Errorf("%s: want 3 public inputs, got %d", path, len(p.Input))
+	}
+	return &p, nil
+}
+
+// Compressed returns compressedProof, or compresses proof if the file has none.
+func (p *ProofFile) Compressed() ([4]*big.Int, error) {
+	if p.CompressedProof != nil {
+		return *p.CompressedProof, nil
+	}
+	return proving.CompressSolidityProof(p.Proof)
+}
+
+// VerifyCalldata encodes p for MultiSchnorrVerifier.verify or verifyCompressed.
+func (p *ProofFile) VerifyCalldata(format ProofFormat) ([]byte, error) {
+	msg, err := decodeHex(p.MessageHex)
+	if err != nil {
+		return nil, fmt.Errorf("messageHex: %w", err)
+	}
+	switch format {
+	case ProofUncompressed:
+		return EncodeVerify(p.Proof, msg, p.Input[0], p.Input[2])
+	case ProofCompressed:
+		c, err := p.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("compress proof: %w", err)
+		}
+		return EncodeVerifyCompressed(c, msg, p.Input[0], p.Input[2])
+	}
+	return nil, fmt.Errorf("unknown proof format %q", format)
+}
+
+// SubmitProof sends verify (or verifyCompressed) to the MultiSchnorrVerifier at addr.
+func SubmitProof(ctx context.Context, t *Transactor, addr Address, p *ProofFile, format ProofFormat) (*Receipt, error) {
+	data, err := p.VerifyCalldata(format)
+	if err != nil {
+		return nil, err
+	}
+	return t.Send(ctx, &addr, data)
+}
@@ -0,0 +1,160 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/witness"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// ContractState is the MultiSchnorrVerifier storage read by verify.
+type ContractState struct {
+	Threshold  *big.Int
+	MerkleRoot *big.Int
+}
+
+// StateFromDeployment reads the constructor values recorded by Deploy. They
+// are stale if updateThreshold / updateMerkleRoot was called since; use ReadState then.
+func StateFromDeployment(d *Deployment) (ContractState, error) {
+	t, ok := new(big.Int).SetString(d.Threshold, 10)
+	if !ok {
+		return ContractState{}, fmt.Errorf("deployment: no threshold recorded (%q)", d.Threshold)
+	}
+	r, ok := new(big.Int).SetString(d.MerkleRoot, 10)
+	if !ok {
+		return ContractState{}, fmt.Errorf("deployment: no merkleRoot recorded (%q)", d.MerkleRoot)
+	}
+	return ContractState{Threshold: t, MerkleRoot: r}, nil
+}
+
+// ReadState calls the public threshold() and merkleRoot() getters at addr.
+func ReadState(ctx context.Context, c *Client, addr Address) (ContractState, error) {
+	get := func(sig string) (*big.Int, error) {
+		sel := Selector(sig)
+		ret, err := c.CallContract(ctx, CallMsg{To: &addr, Data: sel[:]})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sig, err)
+		}
+		return DecodeUint256(ret)
+	}
+	t, err := get("threshold()")
+	if err != nil {
+		return ContractState{}, err
+	}
+	r, err := get("merkleRoot()")
+	if err != nil {
+		return ContractState{}, err
+	}
+	return ContractState{Threshold: t, MerkleRoot: r}, nil
+}
+
+// CheckVerify runs MultiSchnorrVerifier.verify (or verifyCompressed) offline. It
+// returns nil if the call would succeed, or an error wrapping the contract error
+// it would revert with (ErrInsufficientSignatures, ErrInvalidMerkleRoot,
+// ErrPublicInputNotInField, ErrProofInvalid), checked in the same order as the contract.
+func CheckVerify(vk groth16.VerifyingKey, st ContractState, p *ProofFile, format ProofFormat) error {
+	if len(p.Input) != 3 {
+		return fmt.Errorf("want 3 public inputs, got %d", len(p.Input))
+	}
+	message, err := decodeHex(p.MessageHex)
+	if err != nil {
+		return fmt.Errorf("messageHex: %w", err)
+	}
+	root, sumValid := p.Input[0], p.Input[2]
+
+	if sumValid.Cmp(st.Threshold) < 0 {
+		return fmt.Errorf("%w: sumValid %v < threshold %v", ErrInsufficientSignatures, sumValid, st.Threshold)
+	}
+	if root.Cmp(st.MerkleRoot) != 0 {
+		return fmt.Errorf("%w: proof root %v, contract root %v", ErrInvalidMerkleRoot, root, st.MerkleRoot)
+	}
+
+	// the contract ignores input[1] and recomputes it from the message
+	m := utils.KeccakToFr(message)
+	messageFr := m.BigInt(new(big.Int))
+	input := []*big.Int{st.MerkleRoot, messageFr, sumValid}
+	r := ecc.BN254.ScalarField()
+	for i, x := range input {
+		if x.Cmp(r) >= 0 {
+			return fmt.Errorf("%w: input[%d] = %v >= r", ErrPublicInputNotInField, i, x)
+		}
+	}
+
+	words := p.Proof
+	if format == ProofCompressed {
+		c, err := p.Compressed()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrProofInvalid, err)
+		}
+		if words, err = proving.DecompressSolidityProof(c); err != nil {
+			return fmt.Errorf("%w: %v", ErrProofInvalid, err)
+		}
+	}
+	proof, err := proofFromSolidity(words)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProofInvalid, err)
+	}
+	pubW, err := publicWitness(input)
+	if err != nil {
+		return err
+	}
+	if err := groth16.Verify(proof, vk, pubW); err != nil {
+		if p.Input[1].Cmp(messageFr) != 0 {
+			return fmt.Errorf("%w: proof.json input[1] %v is not keccakToFr(message) %v", ErrProofInvalid, p.Input[1], messageFr)
+		}
+		return fmt.Errorf("%w: %v", ErrProofInvalid, err)
+	}
+	return nil
+}
+
+// proofFromSolidity is the inverse of proving.SolidityProof: (A, B, C) in EIP-197 order.
+// Like the pairing precompile, it rejects coordinates >= p and points off the curve.
+func proofFromSolidity(words [8]*big.Int) (*groth16_bn254.Proof, error) {
+	var el [8]fp.Element
+	for i, w := range words {
+		if w.Cmp(fp.Modulus()) >= 0 {
+			return nil, fmt.Errorf("proof[%d] not in the base field", i)
+		}
+		el[i].SetBigInt(w)
+	}
+
+	proof := new(groth16_bn254.Proof)
+	proof.Ar = bn254.G1Affine{X: el[0], Y: el[1]}
+	proof.Bs.X.A1, proof.Bs.X.A0 = el[2], el[3]
+	proof.Bs.Y.A1, proof.Bs.Y.A0 = el[4], el[5]
+	proof.Krs = bn254.G1Affine{X: el[6], Y: el[7]}
+
+	if !proof.Ar.IsOnCurve() || !proof.Krs.IsOnCurve() {
+		return nil, errors.New("G1 point not on curve")
+	}
+	if !proof.Bs.IsOnCurve() || !proof.Bs.IsInSubGroup() {
+		return nil, errors.New("G2 point not on curve or not in subgroup")
+	}
+	return proof, nil
+}
+
+func publicWitness(input []*big.Int) (witness.Witness, error) {
+	w, err := witness.New(ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	values := make(chan any, len(input))
+	for _, x := range input {
+		values <- x
+	}
+	close(values)
+	if err := w.Fill(len(input), 0, values); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,135 @@
+package chain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// same public inputs as multischnorr.Circuit; SumValid is bound to a secret count
+type publicLayout struct {
+	Root     frontend.Variable `gnark:",public"`
+	Message  frontend.Variable `gnark:",public"`
+	SumValid frontend.Variable `gnark:",public"`
+	Count    frontend.Variable
+}
+
+func (c *publicLayout) Define(api frontend.API) error {
+	api.AssertIsEqual(c.SumValid, c.Count)
+	api.AssertIsDifferent(c.Root, 0)
+	api.AssertIsDifferent(c.Message, 0)
+	return nil
+}
+
+func TestCheckVerify(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, new(publicLayout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("epoch 7")
+	m := utils.KeccakToFr(msg)
+	messageFr := m.BigInt(new(big.Int))
+	root := big.NewInt(12345)
+	w, err := frontend.NewWitness(&publicLayout{Root: root, Message: messageFr, SumValid: 5, Count: 5}, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, err := proving.SolidityProof(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() *ProofFile {
+		p := &ProofFile{Proof: words, MessageHex: "0x65706f63682037"}
+		p.Input = []*big.Int{new(big.Int).Set(root), new(big.Int).Set(messageFr), big.NewInt(5)}
+		return p
+	}
+	state := ContractState{Threshold: big.NewInt(5), MerkleRoot: root}
+
+	for _, format := range []ProofFormat{ProofUncompressed, ProofCompressed} {
+		if err := CheckVerify(vk, state, valid(), format); err != nil {
+			t.Fatalf("valid %s proof rejected: %v", format, err)
+		}
+	}
+	compressed, err := proving.CompressSolidityProof(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := ecc.BN254.ScalarField()
+	type verifyCase struct {
+		name  string
+		state ContractState
+		edit  func(*ProofFile)
+		want  error
+	}
+	cases := []verifyCase{
+		{"threshold above sumValid", ContractState{big.NewInt(6), root}, nil, ErrInsufficientSignatures},
+		{"threshold checked before root", ContractState{big.NewInt(6), big.NewInt(1)}, nil, ErrInsufficientSignatures},
+		{"root rotated", ContractState{big.NewInt(5), big.NewInt(1)}, nil, ErrInvalidMerkleRoot},
+		{"other message", state, func(p *ProofFile) { p.MessageHex = "0x65706f63682038" }, ErrProofInvalid},
+		{"input[1] ignored by contract", state, func(p *ProofFile) { p.Input[1] = big.NewInt(0) }, nil},
+		{"sumValid inflated", ContractState{big.NewInt(5), root}, func(p *ProofFile) { p.Input[2] = big.NewInt(6) }, ErrProofInvalid},
+		{"sumValid not in field", ContractState{big.NewInt(5), root}, func(p *ProofFile) { p.Input[2] = new(big.Int).Add(r, big.NewInt(5)) }, ErrPublicInputNotInField},
+		{"A off curve", state, func(p *ProofFile) { p.Proof[1] = new(big.Int).Add(p.Proof[1], big.NewInt(1)) }, ErrProofInvalid},
+		{"C swapped for A", state, func(p *ProofFile) { p.Proof[6], p.Proof[7] = p.Proof[0], p.Proof[1] }, ErrProofInvalid},
+	}
+	// every case must fail the same way through verifyCompressed
+	run := func(format ProofFormat, cases []verifyCase) {
+		for _, tc := range cases {
+			t.Run(string(format)+"/"+tc.name, func(t *testing.T) {
+				p := valid()
+				if tc.edit != nil {
+					tc.edit(p)
+				}
+				err := CheckVerify(vk, tc.state, p, format)
+				if tc.want == nil {
+					if err != nil {
+						t.Fatalf("rejected: %v", err)
+					}
+					return
+				}
+				if !errors.Is(err, tc.want) {
+					t.Fatalf("got %v, want %v", err, tc.want)
+				}
+			})
+		}
+	}
+	run(ProofUncompressed, cases)
+	run(ProofCompressed, cases)
+
+	run(ProofCompressed, []verifyCase{
+		{"compressedProof preferred over proof", state, func(p *ProofFile) {
+			p.Proof[6], p.Proof[7] = p.Proof[0], p.Proof[1]
+			c := compressed
+			p.CompressedProof = &c
+		}, nil},
+		{"compressed A sign flipped", state, func(p *ProofFile) {
+			c := compressed
+			c[0] = new(big.Int).Xor(c[0], big.NewInt(1))
+			p.CompressedProof = &c
+		}, ErrProofInvalid},
+		{"compressed B hint flipped", state, func(p *ProofFile) {
+			c := compressed
+			c[2] = new(big.Int).Xor(c[2], big.NewInt(2))
+			p.CompressedProof = &c
+		}, ErrProofInvalid},
+	})
+}
@@ -0,0 +1,269 @@
+// Package chain deploys MultiSchnorrVerifier and submits proofs over plain
+// Ethereum JSON-RPC, without shelling out to forge or cast.
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RPCError is an error object returned by the node.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("rpc error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// RevertData returns the revert payload of a failed eth_call / eth_estimateGas, if any.
+func (e *RPCError) RevertData() []byte {
+	var s string
+	if json.Unmarshal(e.Data, &s) != nil {
+		return nil
+	}
+	b, err := decodeHex(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+type Client struct {
+	url  string
+	http *http.Client
+	id   atomic.Uint64
+}
+
+func NewClient(url string) *Client {
+	return &Client{url: url, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Call runs one JSON-RPC method and decodes its result into out (if not nil).
+func (c *Client) Call(ctx context.Context, out any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.id.Add(1), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: http status %s", method, resp.Status)
+	}
+
+	var r rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return fmt.Errorf("%s: decode result: %w", method, err)
+	}
+	return nil
+}
+
+func (c *Client) callQuantity(ctx context.Context, method string, params ...any) (*big.Int, error) {
+	var s string
+	if err := c.Call(ctx, &s, method, params...); err != nil {
+		return nil, err
+	}
+	return decodeQuantity(s)
+}
+
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	return c.callQuantity(ctx, "eth_chainId")
+}
+
+// PendingNonce is the next nonce of addr, including pending transactions.
+func (c *Client) PendingNonce(ctx context.Context, addr Address) (uint64, error) {
+	n, err := c.callQuantity(ctx, "eth_getTransactionCount", addr.Hex(), "pending")
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+func (c *Client) BaseFee(ctx context.Context) (*big.Int, error) {
+	var block struct {
+		BaseFeePerGas string `json:"baseFeePerGas"`
+	}
+	if err := c.Call(ctx, &block, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, err
+	}
+	if block.BaseFeePerGas == "" {
+		return nil, errors.New("latest block has no base fee (pre-London chain)")
+	}
+	return decodeQuantity(block.BaseFeePerGas)
+}
+
+func (c *Client) MaxPriorityFee(ctx context.Context) (*big.Int, error) {
+	return c.callQuantity(ctx, "eth_maxPriorityFeePerGas")
+}
+
+// CallMsg is the transaction object of eth_call and eth_estimateGas.
+type CallMsg struct {
+	From Address
+	To   *Address // nil for contract creation
+	Data []byte
+}
+
+func (m CallMsg) toArg() map[string]string {
+	arg := map[string]string{
+		"from": m.From.Hex(),
+		"data": "0x" + hex.EncodeToString(m.Data),
+	}
+	if m.To != nil {
+		arg["to"] = m.To.Hex()
+	}
+	return arg
+}
+
+func (c *Client) EstimateGas(ctx context.Context, msg CallMsg) (uint64, error) {
+	n, err := c.callQuantity(ctx, "eth_estimateGas", msg.toArg())
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+// CallContract runs eth_call against the latest block.
+func (c *Client) CallContract(ctx context.Context, msg CallMsg) ([]byte, error) {
+	var s string
+	if err := c.Call(ctx, &s, "eth_call", msg.toArg(), "latest"); err != nil {
+		return nil, err
+	}
+	return decodeHex(s)
+}
+
+// SendRawTransaction broadcasts a signed transaction and returns its hash.
+func (c *Client) SendRawTransaction(ctx context.Context, raw []byte) (Hash, error) {
+	var s string
+	if err := c.Call(ctx, &s, "eth_sendRawTransaction", "0x"+hex.EncodeToString(raw)); err != nil {
+		return Hash{}, err
+	}
+	return ParseHash(s)
+}
+
+type Receipt struct {
+	TxHash          Hash
+	Status          uint64 // 1 success, 0 reverted
+	BlockNumber     uint64
+	GasUsed         uint64
+	ContractAddress *Address
+}
+
+// TransactionReceipt returns nil, nil while the transaction is pending.
+func (c *Client) TransactionReceipt(ctx context.Context, tx Hash) (*Receipt, error) {
+	var raw *struct {
+		TransactionHash string  `json:"transactionHash"`
+		Status          string  `json:"status"`
+		BlockNumber     string  `json:"blockNumber"`
+		GasUsed         string  `json:"gasUsed"`
+		ContractAddress *string `json:"contractAddress"`
+	}
+	if err := c.Call(ctx, &raw, "eth_getTransactionReceipt", tx.Hex()); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	r := &Receipt{TxHash: tx}
+	for _, f := range []struct {
+		s   string
+		dst *uint64
+	}{{raw.Status, &r.Status}, {raw.BlockNumber, &r.BlockNumber}, {raw.GasUsed, &r.GasUsed}} {
+		n, err := decodeQuantity(f.s)
+		if err != nil {
+			return nil, fmt.Errorf("receipt: %w", err)
+		}
+		*f.dst = n.Uint64()
+	}
+	if raw.ContractAddress != nil && *raw.ContractAddress != "" {
+		a, err := ParseAddress(*raw.ContractAddress)
+		if err != nil {
+			return nil, fmt.Errorf("receipt: %w", err)
+		}
+		r.ContractAddress = &a
+	}
+	return r, nil
+}
+
+// WaitMined polls for the receipt of tx until it is mined or ctx is done.
+func (c *Client) WaitMined(ctx context.Context, tx Hash, every time.Duration) (*Receipt, error) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		r, err := c.TransactionReceipt(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			return r, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func decodeQuantity(s string) (*big.Int, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("quantity %q: missing 0x prefix", s)
+	}
+	n, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil, fmt.Errorf("quantity %q: not hex", s)
+	}
+	return n, nil
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
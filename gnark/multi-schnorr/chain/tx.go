@@ -0,0 +1,240 @@
+package chain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+type Address [20]byte
+
+type Hash [32]byte
+
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// ParseAddress accepts 0x-prefixed hex; mixed-case input must carry a valid EIP-55 checksum.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	if !strings.HasPrefix(s, "0x") || len(s) != 42 {
+		return a, fmt.Errorf("address %q: want 0x + 40 hex digits", s)
+	}
+	if _, err := hex.Decode(a[:], []byte(s[2:])); err != nil {
+		return a, fmt.Errorf("address %q: %w", s, err)
+	}
+	if s[2:] != strings.ToLower(s[2:]) && s[2:] != strings.ToUpper(s[2:]) && s != a.Hex() {
+		return a, fmt.Errorf("address %q: bad EIP-55 checksum", s)
+	}
+	return a, nil
+}
+
+// Hex returns the EIP-55 checksummed form.
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	sum := Keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := sum[i/2] >> 4
+		if i%2 == 1 {
+			nibble = sum[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+func (a Address) String() string { return a.Hex() }
+
+func (a Address) MarshalText() ([]byte, error) { return []byte(a.Hex()), nil }
+
+func (a *Address) UnmarshalText(b []byte) error {
+	p, err := ParseAddress(string(b))
+	if err != nil {
+		return err
+	}
+	*a = p
+	return nil
+}
+
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if !strings.HasPrefix(s, "0x") || len(s) != 66 {
+		return h, fmt.Errorf("hash %q: want 0x + 64 hex digits", s)
+	}
+	if _, err := hex.Decode(h[:], []byte(s[2:])); err != nil {
+		return h, fmt.Errorf("hash %q: %w", s, err)
+	}
+	return h, nil
+}
+
+func (h Hash) Hex() string { return "0x" + hex.EncodeToString(h[:]) }
+
+func (h Hash) String() string { return h.Hex() }
+
+func (h Hash) MarshalText() ([]byte, error) { return []byte(h.Hex()), nil }
+
+func (h *Hash) UnmarshalText(b []byte) error {
+	p, err := ParseHash(string(b))
+	if err != nil {
+		return err
+	}
+	*h = p
+	return nil
+}
+
+// CreateAddress is the address of the contract deployed by from at nonce.
+func CreateAddress(from Address, nonce uint64) Address {
+	enc := rlpList(rlpBytes(from[:]), rlpUint(new(big.Int).SetUint64(nonce)))
+	var a Address
+	copy(a[:], Keccak256(enc)[12:])
+	return a
+}
+
+var secp256k1N = ecc.SECP256K1.ScalarField()
+
+// Signer holds an Ethereum account key.
+type Signer struct {
+	key  ecdsa.PrivateKey
+	addr Address
+}
+
+// NewSigner parses a 32-byte hex private key, with or without 0x prefix
+// (the --private-key format of forge and cast).
+func NewSigner(hexKey string) (*Signer, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("private key: want 32 bytes of hex")
+	}
+	d := new(big.Int).SetBytes(raw)
+	if d.Sign() == 0 || d.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("private key: out of range")
+	}
+
+	var pub secp256k1.G1Affine
+	pub.ScalarMultiplicationBase(d)
+	xy := pub.RawBytes()
+
+	s := new(Signer)
+	if _, err := s.key.SetBytes(append(xy[:], raw...)); err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	copy(s.addr[:], Keccak256(xy[:])[12:])
+	return s, nil
+}
+
+func (s *Signer) Address() Address { return s.addr }
+
+// sign returns (yParity, r, s) over a 32-byte digest, normalised to low-s as Ethereum requires.
+func (s *Signer) sign(digest []byte) (uint64, *big.Int, *big.Int, error) {
+	for {
+		v, r, sig, err := s.key.SignForRecover(digest, nil)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if v&2 != 0 {
+			continue // R.x >= n, not representable in Ethereum signatures
+		}
+		parity := uint64(v & 1)
+		if sig.Cmp(new(big.Int).Rsh(secp256k1N, 1)) > 0 {
+			sig.Sub(secp256k1N, sig)
+			parity ^= 1
+		}
+		return parity, r, sig, nil
+	}
+}
+
+// DynamicFeeTx is an EIP-1559 (type 2) transaction without an access list.
+type DynamicFeeTx struct {
+	ChainID   *big.Int
+	Nonce     uint64
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+	Gas       uint64
+	To        *Address // nil for contract creation
+	Value     *big.Int
+	Data      []byte
+}
+
+func (tx *DynamicFeeTx) fields() [][]byte {
+	to := rlpBytes(nil)
+	if tx.To != nil {
+		to = rlpBytes(tx.To[:])
+	}
+	return [][]byte{
+		rlpUint(tx.ChainID),
+		rlpUint(new(big.Int).SetUint64(tx.Nonce)),
+		rlpUint(tx.GasTipCap),
+		rlpUint(tx.GasFeeCap),
+		rlpUint(new(big.Int).SetUint64(tx.Gas)),
+		to,
+		rlpUint(tx.Value),
+		rlpBytes(tx.Data),
+		rlpList(), // access list
+	}
+}
+
+// SigningHash is keccak256(0x02 || rlp([chainId, ..., accessList])).
+func (tx *DynamicFeeTx) SigningHash() []byte {
+	return Keccak256([]byte{0x02}, rlpList(tx.fields()...))
+}
+
+// SignTx returns the raw transaction for eth_sendRawTransaction and its hash.
+func (s *Signer) SignTx(tx *DynamicFeeTx) ([]byte, Hash, error) {
+	v, r, sig, err := s.sign(tx.SigningHash())
+	if err != nil {
+		return nil, Hash{}, fmt.Errorf("sign tx: %w", err)
+	}
+	fields := append(tx.fields(), rlpUint(new(big.Int).SetUint64(v)), rlpUint(r), rlpUint(sig))
+	raw := append([]byte{0x02}, rlpList(fields...)...)
+
+	var h Hash
+	copy(h[:], Keccak256(raw))
+	return raw, h, nil
+}
+
+// minimal RLP: byte strings and lists of already encoded items
+
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// integers are big-endian without leading zeros; nil and 0 encode as the empty string
+func rlpUint(n *big.Int) []byte {
+	if n == nil {
+		return rlpBytes(nil)
+	}
+	return rlpBytes(n.Bytes())
+}
+
+func rlpList(items ...[]byte) []byte {
+	var body []byte
+	for _, it := range items {
+		body = append(body, it...)
+	}
+	return append(rlpHeader(0xc0, len(body)), body...)
+}
+
+func rlpHeader(offset byte, n int) []byte {
+	if n < 56 {
+		return []byte{offset + byte(n)}
+	}
+	size := new(big.Int).SetUint64(uint64(n)).Bytes()
+	return append([]byte{offset + 55 + byte(len(size))}, size...)
+}
@@ -5,7 +5,10 @@ import (
 
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash"
 	"github.com/consensys/gnark/std/hash/mimc"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
 )
 
 const (
@@ -34,6 +37,19 @@ type Circuit struct {
 }
 
 func (c *Circuit) Define(api frontend.API) error {
+	return defineMultiSchnorr(api, c.Root, c.S[:], c.Message, c.SumValid, false)
+}
+
+// shared by Circuit, OptimizedCircuit and SizedCircuit; len(cands) must be a power of two.
+// optimized selects the fixed-base verification path (see optimized.go)
+func defineMultiSchnorr(
+	api frontend.API,
+	root frontend.Variable,
+	cands []Candidate,
+	message frontend.Variable,
+	sumValidPub frontend.Variable,
+	optimized bool,
+) error {
 	// Curve parameters (BabyJubJub over BN254 Fr)
 	E, err := twistededwards.NewEdCurve(api, tedwards.BN254)
 	if err != nil {
@@ -52,85 +68,94 @@ func (c *Circuit) Define(api frontend.API) error {
 
 	// Merkle membership of A under public Root
 	// leaf = H(Ax, Ay)
-	leaves := make([]frontend.Variable, MaxK)
-	for i := 0; i < MaxK; i++ {
-		wi := c.S[i]
-		h.Reset()
-		h.Write(wi.Ax, wi.Ay)
-		leaves[i] = h.Sum()
+	axs := make([]frontend.Variable, len(cands))
+	ays := make([]frontend.Variable, len(cands))
+	for i := range cands {
+		axs[i] = cands[i].Ax
+		ays[i] = cands[i].Ay
 	}
-
-	// tree bottom-up: Since, in most cases we provide 2/3 signatures,
-	// its is computationally cheaper to build the tree and then check membership,
-	// rather than verifying a Merkle proof for each signature
-	currentLevel := leaves
-	for d := 0; d < Depth; d++ {
-		next := make([]frontend.Variable, len(currentLevel)/2)
-		for k := 0; k < len(next); k++ {
-			h.Reset()
-			h.Write(currentLevel[2*k], currentLevel[2*k+1])
-			next[k] = h.Sum()
-		}
-		currentLevel = next
-	}
-	computedRoot := currentLevel[0]
-	api.AssertIsEqual(computedRoot, c.Root)
+	computedRoot := buildKeyRoot(api, &h, axs, ays)
+	api.AssertIsEqual(computedRoot, root)
 
 	var sumValid frontend.Variable = 0
 
 	// per-candidate checks
-	for i := 0; i < MaxK; i++ {
-		wi := c.S[i]
+	for i := range cands {
+		wi := cands[i]
 
 		A := twistededwards.Point{X: wi.Ax, Y: wi.Ay}
 		R := twistededwards.Point{X: wi.Sig.Rx, Y: wi.Sig.Ry}
 
-		// Safety: on-curve + subgroup (cofactor) checks
 		api.AssertIsBoolean(wi.IsIgnore)
 		active := api.Sub(1, wi.IsIgnore)
 
-		// gated on-curve checks (A and R): a*x^2 + y^2 = 1 + d*x^2*y^2
-		// A on-curve
-		x2 := api.Mul(A.X, A.X)
-		y2 := api.Mul(A.Y, A.Y)
-		ax2 := api.Mul(params.A, x2)
-		lhs := api.Add(ax2, y2)
-		x2y2 := api.Mul(x2, y2)
-		dx2y2 := api.Mul(params.D, x2y2)
-		rhs := api.Add(1, dx2y2)
-		api.AssertIsEqual(api.Mul(active, api.Sub(lhs, rhs)), 0)
-
-		// R on-curve
-		x2R := api.Mul(R.X, R.X)
-		y2R := api.Mul(R.Y, R.Y)
-		ax2R := api.Mul(params.A, x2R)
-		lhsR := api.Add(ax2R, y2R)
-		x2y2R := api.Mul(x2R, y2R)
-		dx2y2R := api.Mul(params.D, x2y2R)
-		rhsR := api.Add(1, dx2y2R)
-		api.AssertIsEqual(api.Mul(active, api.Sub(lhsR, rhsR)), 0)
-
-		// Schnorr challenge e = H(Rx, Ry, Ax, Ay, msg)
-		h.Reset()
-		h.Write(R.X, R.Y, A.X, A.Y, c.Message)
-		e := h.Sum() // lives in Fr on BN254
-
-		// check: [S]G == R + [e]A
-		sG := E.ScalarMul(G, wi.Sig.S)
-		eA := E.ScalarMul(A, e)
-		rhsP := E.Add(R, eA)
-		api.AssertIsEqual(api.Mul(active, api.Sub(sG.X, rhsP.X)), 0)
-		api.AssertIsEqual(api.Mul(active, api.Sub(sG.Y, rhsP.Y)), 0)
-		okX := api.IsZero(api.Sub(sG.X, rhsP.X))
-		okY := api.IsZero(api.Sub(sG.Y, rhsP.Y))
-
-		// valid = active ∧ okX ∧ okY  (AND via multiplication)
-		valid := api.Mul(active, okX)
-		valid = api.Mul(valid, okY)
-
+		var valid frontend.Variable
+		if optimized {
+			valid = verifySchnorrFixedBaseIf(api, E, params, &h, A, R, wi.Sig.S, message, active)
+		} else {
+			valid = verifySchnorrIf(api, E, params, &h, G, A, R, wi.Sig.S, message, active)
+		}
 		sumValid = api.Add(sumValid, valid)
 	}
 
-	api.AssertIsEqual(sumValid, c.SumValid)
+	api.AssertIsEqual(sumValid, sumValidPub)
 	return nil
 }
+
+// tree bottom-up: Since, in most cases we provide 2/3 signatures,
+// its is computationally cheaper to build the tree and then check membership,
+// rather than verifying a Merkle proof for each signature
+func buildKeyRoot(api frontend.API, h hash.FieldHasher, axs, ays []frontend.Variable) frontend.Variable {
+	leaves := make([]frontend.Variable, len(axs))
+	for i := range axs {
+		h.Reset()
+		h.Write(axs[i], ays[i])
+		leaves[i] = h.Sum()
+	}
+	return merkle.RootFromLeaves(api, h, leaves)
+}
+
+// gated on-curve check: a*x^2 + y^2 = 1 + d*x^2*y^2
+func assertOnCurveIf(api frontend.API, params *twistededwards.CurveParams, P twistededwards.Point, active frontend.Variable) {
+	x2 := api.Mul(P.X, P.X)
+	y2 := api.Mul(P.Y, P.Y)
+	ax2 := api.Mul(params.A, x2)
+	lhs := api.Add(ax2, y2)
+	x2y2 := api.Mul(x2, y2)
+	dx2y2 := api.Mul(params.D, x2y2)
+	rhs := api.Add(1, dx2y2)
+	api.AssertIsEqual(api.Mul(active, api.Sub(lhs, rhs)), 0)
+}
+
+// verifySchnorrIf enforces [S]G == R + [e]A when active and returns
+// valid = active ∧ okX ∧ okY
+func verifySchnorrIf(
+	api frontend.API,
+	E twistededwards.Curve,
+	params *twistededwards.CurveParams,
+	h hash.FieldHasher,
+	G, A, R twistededwards.Point,
+	S, msg, active frontend.Variable,
+) frontend.Variable {
+	// Safety: gated on-curve checks (A and R)
+	assertOnCurveIf(api, params, A, active)
+	assertOnCurveIf(api, params, R, active)
+
+	// Schnorr challenge e = H(Rx, Ry, Ax, Ay, msg)
+	h.Reset()
+	h.Write(R.X, R.Y, A.X, A.Y, msg)
+	e := h.Sum() // lives in Fr on BN254
+
+	// check: [S]G == R + [e]A
+	sG := E.ScalarMul(G, S)
+	eA := E.ScalarMul(A, e)
+	rhsP := E.Add(R, eA)
+	api.AssertIsEqual(api.Mul(active, api.Sub(sG.X, rhsP.X)), 0)
+	api.AssertIsEqual(api.Mul(active, api.Sub(sG.Y, rhsP.Y)), 0)
+	okX := api.IsZero(api.Sub(sG.X, rhsP.X))
+	okY := api.IsZero(api.Sub(sG.Y, rhsP.Y))
+
+	// valid = active ∧ okX ∧ okY  (AND via multiplication)
+	valid := api.Mul(active, okX)
+	return api.Mul(valid, okY)
+}
@@ -0,0 +1,90 @@
+package multischnorr
+
+import (
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
+)
+
+const NumCommittees = 2 // committee ids are in [0, NumCommittees)
+
+type CommitteeCandidate struct {
+	Ax, Ay    frontend.Variable // Public key A coordinates
+	Committee frontend.Variable // committee id, bound by the leaf
+	Sig       SchnorrSignature
+	IsIgnore  frontend.Variable // 1 if this candidate is to be ignored, 0 otherwise
+}
+
+// Same as Circuit, but leaf = H(Ax, Ay, Committee) and valid signatures are
+// counted per committee, so the verifier can require t_c signatures from committee c.
+type CommitteeCircuit struct {
+	Root     frontend.Variable                `gnark:",public"` // Merkle root of (key, committee) leaves
+	S        [MaxK]CommitteeCandidate         // K candidates
+	Message  frontend.Variable                `gnark:",public"`
+	SumValid [NumCommittees]frontend.Variable `gnark:",public"` // valid signatures per committee
+}
+
+func (c *CommitteeCircuit) Define(api frontend.API) error {
+	// Curve parameters (BabyJubJub over BN254 Fr)
+	E, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	params, err := twistededwards.GetCurveParams(tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	G := twistededwards.Point{X: params.Base[0], Y: params.Base[1]}
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	// leaf = H(Ax, Ay, Committee)
+	leaves := make([]frontend.Variable, MaxK)
+	for i := 0; i < MaxK; i++ {
+		h.Reset()
+		h.Write(c.S[i].Ax, c.S[i].Ay, c.S[i].Committee)
+		leaves[i] = h.Sum()
+	}
+	api.AssertIsEqual(merkle.RootFromLeaves(api, &h, leaves), c.Root)
+
+	var sumValid [NumCommittees]frontend.Variable
+	for k := 0; k < NumCommittees; k++ {
+		sumValid[k] = 0
+	}
+
+	for i := 0; i < MaxK; i++ {
+		wi := c.S[i]
+
+		A := twistededwards.Point{X: wi.Ax, Y: wi.Ay}
+		R := twistededwards.Point{X: wi.Sig.Rx, Y: wi.Sig.Ry}
+
+		api.AssertIsBoolean(wi.IsIgnore)
+		active := api.Sub(1, wi.IsIgnore)
+
+		// one-hot committee selector; exactly one id must match, so ids are in range
+		var isK [NumCommittees]frontend.Variable
+		var hits frontend.Variable = 0
+		for k := 0; k < NumCommittees; k++ {
+			isK[k] = api.IsZero(api.Sub(wi.Committee, k))
+			hits = api.Add(hits, isK[k])
+		}
+		api.AssertIsEqual(hits, 1)
+
+		valid := verifySchnorrIf(api, E, params, &h, G, A, R, wi.Sig.S, c.Message, active)
+		for k := 0; k < NumCommittees; k++ {
+			sumValid[k] = api.Add(sumValid[k], api.Mul(valid, isK[k]))
+		}
+	}
+
+	for k := 0; k < NumCommittees; k++ {
+		api.AssertIsEqual(sumValid[k], c.SumValid[k])
+	}
+	return nil
+}
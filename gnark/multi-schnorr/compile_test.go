@@ -31,3 +31,73 @@ func TestCompile(t *testing.T) {
 
 // run the command to generate the r1cs file
 // go test -v ./...
+
+func TestCompileMultiRoot(t *testing.T) {
+	var c MultiRootCircuit
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &c)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	t.Logf("Constraints: %d", cs.GetNbConstraints())
+	internal, secret, public := cs.GetNbVariables()
+	t.Logf("Variables  : total=%d (internal=%d, secret=%d, public=%d)",
+		internal+secret+public, internal, secret, public)
+}
+
+func TestCompileCommittee(t *testing.T) {
+	var c CommitteeCircuit
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &c)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	t.Logf("Constraints: %d", cs.GetNbConstraints())
+	internal, secret, public := cs.GetNbVariables()
+	t.Logf("Variables  : total=%d (internal=%d, secret=%d, public=%d)",
+		internal+secret+public, internal, secret, public)
+}
+
+func TestCompileOptimized(t *testing.T) {
+	var c OptimizedCircuit
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &c)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	t.Logf("Constraints: %d", cs.GetNbConstraints())
+	internal, secret, public := cs.GetNbVariables()
+	t.Logf("Variables  : total=%d (internal=%d, secret=%d, public=%d)",
+		internal+secret+public, internal, secret, public)
+}
+
+func TestCompileBatch(t *testing.T) {
+	var c BatchCircuit
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &c)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	t.Logf("Constraints: %d", cs.GetNbConstraints())
+	internal, secret, public := cs.GetNbVariables()
+	t.Logf("Variables  : total=%d (internal=%d, secret=%d, public=%d)",
+		internal+secret+public, internal, secret, public)
+}
+
+func TestCompileMuSig(t *testing.T) {
+	var c MuSigCircuit
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &c)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	t.Logf("Constraints: %d", cs.GetNbConstraints())
+	internal, secret, public := cs.GetNbVariables()
+	t.Logf("Variables  : total=%d (internal=%d, secret=%d, public=%d)",
+		internal+secret+public, internal, secret, public)
+}
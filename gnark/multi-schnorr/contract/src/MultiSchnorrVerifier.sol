@@ -66,25 +66,47 @@ contract MultiSchnorrVerifier is Ownable {
         return uint256(keccak256(m)) % R;
     }
 
-    /// @notice Verify proof binds {merkleRoot, hashToFr(message), sumValid}
-    /// and emits the original message.
-    function verify(
-        uint256[8] calldata proof, // if compressed: change to uint256[4]
+    function publicInputs(
         bytes calldata message,
         uint256 _merkleRoot,
         uint256 sumValid
-    ) external {
+    ) internal view returns (uint256[3] memory input) {
         if (sumValid < threshold) {
             revert InsufficientSignatures();
         }
         if (_merkleRoot != merkleRoot) {
             revert InvalidMerkleRoot();
         }
-        uint256 messageFr = keccakToFr(message);
-        uint256[3] memory input = [merkleRoot, messageFr, sumValid];
+        input = [merkleRoot, keccakToFr(message), sumValid];
+    }
+
+    /// @notice Verify proof binds {merkleRoot, hashToFr(message), sumValid}
+    /// and emits the original message.
+    function verify(
+        uint256[8] calldata proof,
+        bytes calldata message,
+        uint256 _merkleRoot,
+        uint256 sumValid
+    ) external {
+        uint256[3] memory input = publicInputs(message, _merkleRoot, sumValid);
 
         verifier.verifyProof(proof, input);
 
-        emit ProofVerified(message, merkleRoot, messageFr, sumValid);
+        emit ProofVerified(message, input[0], input[1], sumValid);
+    }
+
+    /// @notice Same as verify, with the proof points in compressed form
+    /// (compressedProof in proof.json, 128 bytes of calldata instead of 256).
+    function verifyCompressed(
+        uint256[4] calldata compressedProof,
+        bytes calldata message,
+        uint256 _merkleRoot,
+        uint256 sumValid
+    ) external {
+        uint256[3] memory input = publicInputs(message, _merkleRoot, sumValid);
+
+        verifier.verifyCompressedProof(compressedProof, input);
+
+        emit ProofVerified(message, input[0], input[1], sumValid);
     }
 }
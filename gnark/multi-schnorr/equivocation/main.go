@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// one signed message of the conflicting pair
+type SignedMessage struct {
+	MessageHex string `json:"messageHex"`
+	MessageFr  string `json:"messageFr"`
+	Rx         string `json:"rx"`
+	Ry         string `json:"ry"`
+	S          string `json:"s"`
+}
+
+// self-contained slashing evidence; all field elements are 0x-prefixed 32-byte hex
+type Evidence struct {
+	Epoch          uint64        `json:"epoch"`
+	Topic          string        `json:"topic"`
+	ValidatorIndex int           `json:"validatorIndex"`
+	Ax             string        `json:"ax"`
+	Ay             string        `json:"ay"`
+	Leaf           string        `json:"leaf"`
+	Path           []string      `json:"path"`
+	Root           string        `json:"root"`
+	First          SignedMessage `json:"first"`
+	Second         SignedMessage `json:"second"`
+}
+
+type slot struct {
+	epoch uint64
+	topic string
+	index int
+}
+
+type seen struct {
+	msgFr fr.Element
+	msg   SignedMessage
+}
+
+func main() {
+	keysPath := flag.String("keys", utils.DefaultKeyPath(), "validator registry committed under the current root")
+	archive := flag.String("archive", utils.RepoPath("../bundles"), "directory with signature bundle JSON files")
+	outDir := flag.String("out", utils.RepoPath("../evidence"), "directory to write evidence files to")
+	flag.Parse()
+
+	if err := run(*keysPath, *archive, *outDir); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(keysPath, archive, outDir string) error {
+	keys, err := utils.LoadKeysFrom(keysPath)
+	if err != nil {
+		return fmt.Errorf("load keys: %w", err)
+	}
+	root, leaves, err := utils.BuildRoot(keys)
+	if err != nil {
+		return fmt.Errorf("build merkle root: %w", err)
+	}
+
+	bundles, err := utils.LoadBundles(archive)
+	if err != nil {
+		return fmt.Errorf("load bundles: %w", err)
+	}
+	fmt.Printf("Loaded %d bundles from %s\n", len(bundles), archive)
+
+	first := map[slot]seen{}
+	reported := map[slot]bool{}
+	var found []Evidence
+
+	for _, b := range bundles {
+		msg, err := b.Message()
+		if err != nil {
+			return fmt.Errorf("epoch %d topic %q: %w", b.Epoch, b.Topic, err)
+		}
+		msgFr := utils.KeccakToFr(msg)
+
+		for _, bs := range b.Signatures {
+			pub, sig, err := bs.Decode()
+			if err != nil {
+				return fmt.Errorf("epoch %d topic %q: %w", b.Epoch, b.Topic, err)
+			}
+			if bs.Index < 0 || bs.Index >= len(keys) {
+				fmt.Printf("skip: validator index %d out of range (epoch %d, topic %q)\n", bs.Index, b.Epoch, b.Topic)
+				continue
+			}
+			// the key must be the one committed under the current root
+			if keys[bs.Index].Pub.Ax.Cmp(pub.Ax) != 0 || keys[bs.Index].Pub.Ay.Cmp(pub.Ay) != 0 {
+				fmt.Printf("skip: validator %d key does not match registry (epoch %d, topic %q)\n", bs.Index, b.Epoch, b.Topic)
+				continue
+			}
+			// unverifiable signatures are not attributable to the validator
+			if !utils.Verify(pub, msgFr, sig) {
+				fmt.Printf("skip: invalid signature from validator %d (epoch %d, topic %q)\n", bs.Index, b.Epoch, b.Topic)
+				continue
+			}
+
+			k := slot{epoch: b.Epoch, topic: b.Topic, index: bs.Index}
+			cur := seen{
+				msgFr: msgFr,
+				msg: SignedMessage{
+					MessageHex: "0x" + fmt.Sprintf("%x", msg),
+					MessageFr:  toHex32(msgFr.BigInt(new(big.Int))),
+					Rx:         toHex32(sig.Rx),
+					Ry:         toHex32(sig.Ry),
+					S:          toHex32(sig.S),
+				},
+			}
+
+			prev, ok := first[k]
+			if !ok {
+				first[k] = cur
+				continue
+			}
+			if prev.msgFr.Equal(&msgFr) || reported[k] {
+				continue
+			}
+
+			ev, err := buildEvidence(k, keys[bs.Index].Pub, root, leaves, prev.msg, cur.msg)
+			if err != nil {
+				return err
+			}
+			found = append(found, ev)
+			reported[k] = true
+		}
+	}
+
+	if len(found) == 0 {
+		fmt.Println("No equivocation found")
+		return nil
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	for _, ev := range found {
+		data, err := json.MarshalIndent(ev, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal evidence: %w", err)
+		}
+		name := fmt.Sprintf("evidence_%d_%s_%d.json", ev.Epoch, fileSafe(ev.Topic), ev.ValidatorIndex)
+		outPath := filepath.Join(outDir, name)
+		if err := os.WriteFile(outPath, data, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", outPath, err)
+		}
+		fmt.Printf("Validator %d equivocated at epoch %d topic %q -> %s\n", ev.ValidatorIndex, ev.Epoch, ev.Topic, outPath)
+	}
+	return nil
+}
+
+func buildEvidence(
+	k slot,
+	pub utils.PubKey,
+	root fr.Element,
+	leaves []fr.Element,
+	first, second SignedMessage,
+) (Evidence, error) {
+	path, err := utils.BuildPath(leaves, k.index)
+	if err != nil {
+		return Evidence{}, fmt.Errorf("merkle path for %d: %w", k.index, err)
+	}
+	leaf := leaves[k.index]
+	if !utils.VerifyPath(root, leaf, k.index, path) {
+		return Evidence{}, fmt.Errorf("merkle path for %d does not match root", k.index)
+	}
+
+	pathHex := make([]string, len(path))
+	for i := range path {
+		pathHex[i] = toHex32(path[i].BigInt(new(big.Int)))
+	}
+
+	return Evidence{
+		Epoch:          k.epoch,
+		Topic:          k.topic,
+		ValidatorIndex: k.index,
+		Ax:             toHex32(pub.Ax),
+		Ay:             toHex32(pub.Ay),
+		Leaf:           toHex32(leaf.BigInt(new(big.Int))),
+		Path:           pathHex,
+		Root:           toHex32(root.BigInt(new(big.Int))),
+		First:          first,
+		Second:         second,
+	}, nil
+}
+
+func toHex32(x *big.Int) string {
+	return "0x" + fmt.Sprintf("%064x", x)
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func fileSafe(s string) string {
+	return unsafeChars.ReplaceAllString(s, "_")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Validator 0 signs two messages for (1, "block/head"). Validator 1 signs the
+// same message twice there, other messages only in other slots, and a forged
+// signature over a third message in that slot: only validator 0 is reported.
+func TestEquivocation(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := utils.GeneratePaddedKeyPairs(2, multischnorr.Depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keysPath := filepath.Join(dir, "keys.json")
+	if err := utils.SaveKeysTo(keysPath, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(index int, msg []byte) utils.BundleSignature {
+		sig, err := utils.Sign(keys[index].Priv.Sk, keys[index].Pub, utils.KeccakToFr(msg), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return utils.NewBundleSignature(index, keys[index].Pub, sig)
+	}
+	forged := sign(1, []byte("block 3"))
+	forged.S = "1" + forged.S
+
+	const topic = "block/head"
+	archive := filepath.Join(dir, "bundles")
+	for i, b := range []struct {
+		epoch uint64
+		topic string
+		msg   string
+		sigs  []utils.BundleSignature
+	}{
+		{1, topic, "block 1", []utils.BundleSignature{sign(0, []byte("block 1")), sign(1, []byte("block 1"))}},
+		{1, topic, "block 2", []utils.BundleSignature{sign(0, []byte("block 2"))}},
+		{1, topic, "block 1", []utils.BundleSignature{sign(1, []byte("block 1"))}},
+		{1, topic, "block 3", []utils.BundleSignature{forged}},
+		{1, "block/finalized", "block 2", []utils.BundleSignature{sign(1, []byte("block 2"))}},
+		{2, topic, "block 2", []utils.BundleSignature{sign(1, []byte("block 2"))}},
+	} {
+		writeBundle(t, archive, fmt.Sprintf("%02d.json", i), utils.SignatureBundle{
+			Epoch:      b.epoch,
+			Topic:      b.topic,
+			MessageHex: "0x" + hex.EncodeToString([]byte(b.msg)),
+			Signatures: b.sigs,
+		})
+	}
+
+	out := filepath.Join(dir, "evidence")
+	if err := run(keysPath, archive, out); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(out, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "evidence_1_block_head_0.json" {
+		t.Fatalf("evidence files %v, want evidence_1_block_head_0.json", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev Evidence
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Epoch != 1 || ev.Topic != topic || ev.ValidatorIndex != 0 {
+		t.Fatalf("evidence for validator %d at (%d, %q)", ev.ValidatorIndex, ev.Epoch, ev.Topic)
+	}
+
+	// both signatures verify under the validator's key
+	pub := utils.PubKey{Ax: hexBig(t, ev.Ax), Ay: hexBig(t, ev.Ay)}
+	for _, m := range []SignedMessage{ev.First, ev.Second} {
+		msg, err := hex.DecodeString(strings.TrimPrefix(m.MessageHex, "0x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgFr := utils.KeccakToFr(msg)
+		if hexFr(t, m.MessageFr) != msgFr {
+			t.Fatalf("messageFr %s is not keccakToFr(%s)", m.MessageFr, m.MessageHex)
+		}
+		sig := utils.SchnorrSignature{Rx: hexBig(t, m.Rx), Ry: hexBig(t, m.Ry), S: hexBig(t, m.S)}
+		if !utils.Verify(pub, msgFr, sig) {
+			t.Fatalf("signature over %s does not verify", m.MessageHex)
+		}
+	}
+	if ev.First.MessageFr == ev.Second.MessageFr {
+		t.Fatal("evidence of a single message")
+	}
+
+	// the leaf and path verify under the registry's root
+	root, _, err := utils.BuildRoot(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hexFr(t, ev.Root) != root {
+		t.Fatalf("root %s, registry root %s", ev.Root, root.String())
+	}
+	leaf := hexFr(t, ev.Leaf)
+	if leaf != utils.LeafHash(pub) {
+		t.Fatal("leaf is not H(Ax, Ay)")
+	}
+	path := make([]fr.Element, len(ev.Path))
+	for i, p := range ev.Path {
+		path[i] = hexFr(t, p)
+	}
+	if !utils.VerifyPath(root, leaf, ev.ValidatorIndex, path) {
+		t.Fatal("path does not verify under the root")
+	}
+	if utils.VerifyPath(root, leaf, 1, path) {
+		t.Fatal("path verifies at another index")
+	}
+}
+
+func writeBundle(t *testing.T, dir, name string, b utils.SignatureBundle) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hexBig(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		t.Fatalf("bad hex %q", s)
+	}
+	return v
+}
+
+func hexFr(t *testing.T, s string) fr.Element {
+	t.Helper()
+	var e fr.Element
+	e.SetBigInt(hexBig(t, s))
+	return e
+}
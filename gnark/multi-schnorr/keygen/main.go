@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/big"
 	"os"
+	"strconv"
+	"strings"
 
 	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
 	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
@@ -14,6 +17,9 @@ func toHex32(x *big.Int) string {
 }
 
 func main() {
+	committees := flag.String("committees", "", "comma separated committee sizes assigned in key order, e.g. 40,24 (CommitteeCircuit)")
+	flag.Parse()
+
 	// read depth from circuit.go
 	depth := multischnorr.Depth
 
@@ -58,4 +64,45 @@ func main() {
 
 	fmt.Println("Merkle root:", rootHex)
 	fmt.Println("✅ merkle_root.txt written successfully")
+
+	if *committees == "" {
+		return
+	}
+
+	sizes, err := parseSizes(*committees)
+	if err != nil {
+		panic(fmt.Errorf("invalid --committees: %w", err))
+	}
+	if err := utils.AssignCommittees(keys, sizes); err != nil {
+		panic(fmt.Errorf("failed to assign committees: %w", err))
+	}
+	if err := utils.SaveKeysToFile(keys); err != nil {
+		panic(fmt.Errorf("failed to save keys: %w", err))
+	}
+
+	croot, _, err := utils.BuildCommitteeRoot(keys)
+	if err != nil {
+		panic(fmt.Errorf("failed to build committee merkle root: %w", err))
+	}
+	crootHex := toHex32(croot.BigInt(new(big.Int)))
+
+	if err := os.WriteFile("committee_root.txt", []byte(crootHex+"\n"), 0o644); err != nil {
+		panic(fmt.Errorf("failed to write committee_root.txt: %w", err))
+	}
+
+	fmt.Println("Committee Merkle root:", crootHex)
+	fmt.Println("✅ committee_root.txt written successfully")
+}
+
+func parseSizes(s string) ([]int, error) {
+	parts := strings.Split(s, ",")
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
 }
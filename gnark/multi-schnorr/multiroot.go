@@ -0,0 +1,90 @@
+package multischnorr
+
+import (
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// Used during a validator set rotation. Seat i holds leaf i of RootOld and leaf i
+// of RootNew, and carries one signature, by one of the two keys. A valid signature
+// counts toward every root whose leaf i is the signing key, so a validator kept at
+// the same slot (same key in both leaves) counts toward both roots. Soundness does
+// not depend on the two registries being slot-aligned, but a validator that moved
+// slots only counts toward the root of the seat it signs in.
+type MultiRootCandidate struct {
+	OldAx, OldAy frontend.Variable // key of this seat under RootOld
+	NewAx, NewAy frontend.Variable // key of this seat under RootNew
+	Sig          SchnorrSignature
+	UseNew       frontend.Variable // 1 if Sig is checked against the new key, 0 for the old key
+	IsIgnore     frontend.Variable // 1 if this candidate is to be ignored, 0 otherwise
+}
+
+type MultiRootCircuit struct {
+	RootOld     frontend.Variable        `gnark:",public"` // Merkle root of the outgoing validator set
+	RootNew     frontend.Variable        `gnark:",public"` // Merkle root of the incoming validator set
+	S           [MaxK]MultiRootCandidate // K candidates
+	Message     frontend.Variable        `gnark:",public"`
+	SumValidOld frontend.Variable        `gnark:",public"` // valid signatures by a RootOld key of their seat
+	SumValidNew frontend.Variable        `gnark:",public"` // valid signatures by a RootNew key of their seat
+}
+
+func (c *MultiRootCircuit) Define(api frontend.API) error {
+	// Curve parameters (BabyJubJub over BN254 Fr)
+	E, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	params, err := twistededwards.GetCurveParams(tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	G := twistededwards.Point{X: params.Base[0], Y: params.Base[1]}
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	// both trees rebuilt from the seats, as in Circuit
+	oldAx := make([]frontend.Variable, MaxK)
+	oldAy := make([]frontend.Variable, MaxK)
+	newAx := make([]frontend.Variable, MaxK)
+	newAy := make([]frontend.Variable, MaxK)
+	for i := 0; i < MaxK; i++ {
+		oldAx[i], oldAy[i] = c.S[i].OldAx, c.S[i].OldAy
+		newAx[i], newAy[i] = c.S[i].NewAx, c.S[i].NewAy
+	}
+	api.AssertIsEqual(buildKeyRoot(api, &h, oldAx, oldAy), c.RootOld)
+	api.AssertIsEqual(buildKeyRoot(api, &h, newAx, newAy), c.RootNew)
+
+	var sumOld, sumNew frontend.Variable = 0, 0
+
+	for i := 0; i < MaxK; i++ {
+		wi := c.S[i]
+
+		api.AssertIsBoolean(wi.IsIgnore)
+		api.AssertIsBoolean(wi.UseNew)
+		active := api.Sub(1, wi.IsIgnore)
+
+		// one signature per seat, against the key selected by UseNew
+		A := twistededwards.Point{
+			X: api.Select(wi.UseNew, wi.NewAx, wi.OldAx),
+			Y: api.Select(wi.UseNew, wi.NewAy, wi.OldAy),
+		}
+		R := twistededwards.Point{X: wi.Sig.Rx, Y: wi.Sig.Ry}
+
+		valid := verifySchnorrIf(api, E, params, &h, G, A, R, wi.Sig.S, c.Message, active)
+
+		// A is one of the two leaves; it is the other one too if the seat did not rotate
+		same := api.Mul(api.IsZero(api.Sub(wi.OldAx, wi.NewAx)), api.IsZero(api.Sub(wi.OldAy, wi.NewAy)))
+		sumOld = api.Add(sumOld, api.Mul(valid, api.Select(wi.UseNew, same, 1)))
+		sumNew = api.Add(sumNew, api.Mul(valid, api.Select(wi.UseNew, 1, same)))
+	}
+
+	api.AssertIsEqual(sumOld, c.SumValidOld)
+	api.AssertIsEqual(sumNew, c.SumValidNew)
+	return nil
+}
@@ -0,0 +1,83 @@
+package multischnorr_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/test"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Seat 0 rotates its key, seats 1 and 2 keep theirs. Seat 0 signs with its new
+// key, seat 1 with its old key and seat 2 with its new key. Seats 1 and 2 hold
+// the same key in both registries: they count under RootOld and RootNew, seat 0
+// only under RootNew.
+func TestMultiRootCounting(t *testing.T) {
+	oldKeys, err := utils.GeneratePaddedKeyPairs(3, multischnorr.Depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := utils.GenerateKeyPairs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKeys := append([]utils.KeyPair(nil), oldKeys...)
+	newKeys[0] = rotated[0]
+
+	var msg fr.Element
+	msg.SetUint64(7)
+	wd, err := utils.PrepareMultiRootWitnessData(oldKeys, newKeys, []int{1}, []int{0, 2}, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.SumValidOld != 2 || wd.SumValidNew != 3 {
+		t.Fatalf("sums %d/%d, want 2/3", wd.SumValidOld, wd.SumValidNew)
+	}
+
+	assignment := func() *multischnorr.MultiRootCircuit {
+		a := &multischnorr.MultiRootCircuit{
+			RootOld:     wd.RootOld.BigInt(new(big.Int)),
+			RootNew:     wd.RootNew.BigInt(new(big.Int)),
+			Message:     wd.Message.BigInt(new(big.Int)),
+			SumValidOld: wd.SumValidOld,
+			SumValidNew: wd.SumValidNew,
+		}
+		for i, c := range wd.Candidates {
+			a.S[i] = multischnorr.MultiRootCandidate{
+				OldAx: c.OldAx, OldAy: c.OldAy,
+				NewAx: c.NewAx, NewAy: c.NewAy,
+				Sig:      multischnorr.SchnorrSignature{Rx: c.Sig.Rx, Ry: c.Sig.Ry, S: c.Sig.S},
+				UseNew:   int(c.UseNew),
+				IsIgnore: int(c.IsIgnore),
+			}
+		}
+		return a
+	}
+
+	if err := test.IsSolved(&multischnorr.MultiRootCircuit{}, assignment(), ecc.BN254.ScalarField()); err != nil {
+		t.Fatalf("honest rotation rejected: %v", err)
+	}
+
+	for _, c := range []struct {
+		name string
+		edit func(a *multischnorr.MultiRootCircuit)
+	}{
+		// seats 1 and 2 counted only under the root UseNew selects
+		{"seat 2 only under RootNew", func(a *multischnorr.MultiRootCircuit) { a.SumValidOld = 1 }},
+		{"seat 1 only under RootOld", func(a *multischnorr.MultiRootCircuit) { a.SumValidNew = 2 }},
+		// seat 0's new key is not under RootOld
+		{"rotated seat under RootOld", func(a *multischnorr.MultiRootCircuit) { a.SumValidOld = 3 }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a := assignment()
+			c.edit(a)
+			if err := test.IsSolved(&multischnorr.MultiRootCircuit{}, a, ecc.BN254.ScalarField()); err == nil {
+				t.Fatal("wrong count accepted")
+			}
+		})
+	}
+}
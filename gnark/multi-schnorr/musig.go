@@ -0,0 +1,84 @@
+package multischnorr
+
+import (
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+type PublicKey struct {
+	Ax, Ay frontend.Variable
+}
+
+// One MuSig2 signature from the participants in Bitmap (see utils/musig2.go).
+// The aggregated key is recomputed in-circuit from the registry, so the cost is
+// one scalar multiplication per slot plus a single signature check, instead of
+// two scalar multiplications per slot in Circuit.
+type MuSigCircuit struct {
+	Root     frontend.Variable `gnark:",public"` // Merkle root of valid public keys
+	Keys     [MaxK]PublicKey   // registry, bound by Root
+	Sig      SchnorrSignature  // aggregated signature
+	Message  frontend.Variable `gnark:",public"`
+	SumValid frontend.Variable `gnark:",public"` // number of participants
+	Bitmap   frontend.Variable `gnark:",public"` // bit i set if slot i participates
+}
+
+func (c *MuSigCircuit) Define(api frontend.API) error {
+	// Curve parameters (BabyJubJub over BN254 Fr)
+	E, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	params, err := twistededwards.GetCurveParams(tedwards.BN254)
+	if err != nil {
+		return err
+	}
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	axs := make([]frontend.Variable, MaxK)
+	ays := make([]frontend.Variable, MaxK)
+	for i := 0; i < MaxK; i++ {
+		axs[i] = c.Keys[i].Ax
+		ays[i] = c.Keys[i].Ay
+	}
+	api.AssertIsEqual(buildKeyRoot(api, &h, axs, ays), c.Root)
+
+	// L = H(Root, Bitmap) binds the coefficients to the participant set
+	h.Reset()
+	h.Write(c.Root, c.Bitmap)
+	L := h.Sum()
+
+	bits := api.ToBinary(c.Bitmap, MaxK)
+
+	// Ã = sum over participants of [a_i]A_i, a_i = H(L, Ax_i, Ay_i)
+	agg := twistededwards.Point{X: 0, Y: 1}
+	var sumValid frontend.Variable = 0
+	for i := 0; i < MaxK; i++ {
+		A := twistededwards.Point{X: c.Keys[i].Ax, Y: c.Keys[i].Ay}
+		assertOnCurveIf(api, params, A, bits[i])
+
+		// padding slots (identity key) would add nothing to Ã but still be counted
+		api.AssertIsEqual(api.Mul(bits[i], api.IsZero(A.X)), 0)
+
+		h.Reset()
+		h.Write(L, A.X, A.Y)
+		aA := E.ScalarMul(A, h.Sum())
+		aA.X = api.Select(bits[i], aA.X, 0)
+		aA.Y = api.Select(bits[i], aA.Y, 1)
+		agg = E.Add(agg, aA)
+
+		sumValid = api.Add(sumValid, bits[i])
+	}
+	api.AssertIsEqual(sumValid, c.SumValid)
+
+	// single check [S]G == R + [e]Ã, e = H(Rx, Ry, Ãx, Ãy, msg), enforced with active = 1
+	R := twistededwards.Point{X: c.Sig.Rx, Y: c.Sig.Ry}
+	verifySchnorrFixedBaseIf(api, E, params, &h, agg, R, c.Sig.S, c.Message, 1)
+	return nil
+}
@@ -0,0 +1,86 @@
+package multischnorr_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/test"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+func muSigAssignment(t *testing.T, keys []utils.KeyPair, signers []int, msg fr.Element) *multischnorr.MuSigCircuit {
+	t.Helper()
+	wd, err := utils.PrepareMuSigWitnessData(keys, signers, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := new(multischnorr.MuSigCircuit)
+	a.Root = wd.Root.BigInt(new(big.Int))
+	a.Message = wd.Message.BigInt(new(big.Int))
+	a.SumValid = wd.SumValid
+	a.Bitmap = wd.Bitmap
+	a.Sig = multischnorr.SchnorrSignature{Rx: wd.Sig.Rx, Ry: wd.Sig.Ry, S: wd.Sig.S}
+	for i, k := range wd.Keys {
+		a.Keys[i] = multischnorr.PublicKey{Ax: k.Ax, Ay: k.Ay}
+	}
+	return a
+}
+
+func TestMuSig(t *testing.T) {
+	keys, err := utils.GeneratePaddedKeyPairs(40, multischnorr.Depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg fr.Element
+	msg.SetUint64(99)
+
+	full := make([]int, 40)
+	for i := range full {
+		full[i] = i
+	}
+	if !utils.IsFullSet(keys, full) || utils.IsFullSet(keys, full[1:]) {
+		t.Fatal("IsFullSet")
+	}
+
+	a := muSigAssignment(t, keys, full, msg)
+	if err := test.IsSolved(&multischnorr.MuSigCircuit{}, a, ecc.BN254.ScalarField()); err != nil {
+		t.Fatalf("honest MuSig2 signature rejected: %v", err)
+	}
+
+	// a signer missing from the session cannot be added to the bitmap
+	b := muSigAssignment(t, keys, full[1:], msg)
+	b.Bitmap = a.Bitmap
+	b.SumValid = 40
+	if test.IsSolved(&multischnorr.MuSigCircuit{}, b, ecc.BN254.ScalarField()) == nil {
+		t.Fatal("bitmap larger than the signing set accepted")
+	}
+
+	// padding slots cannot be counted
+	c := muSigAssignment(t, keys, full, msg)
+	c.Bitmap = new(big.Int).SetBit(a.Bitmap.(*big.Int), 63, 1)
+	c.SumValid = 41
+	if test.IsSolved(&multischnorr.MuSigCircuit{}, c, ecc.BN254.ScalarField()) == nil {
+		t.Fatal("padding slot counted as a participant")
+	}
+
+	// secret nonces are single use
+	ctx, err := utils.AggregateKeys(keys, []int{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn, pn, err := utils.NonceGen(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := utils.NewSigningSession(ctx, utils.NonceAgg([]utils.PubNonce{pn}), msg)
+	if _, err := session.PartialSign(0, keys[0].Priv.Sk, sn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.PartialSign(0, keys[0].Priv.Sk, sn); err != utils.ErrNonceReused {
+		t.Fatalf("nonce reuse: got %v", err)
+	}
+}
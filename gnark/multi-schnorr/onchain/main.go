@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/chain"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+const usage = `Usage:
+  go run ./onchain deploy --rpc-url <URL> --private-key <0xPK> --threshold <uint> [--merkle-root <uint|0xhex>] [--proof-format uncompressed|compressed]
+  go run ./onchain submit --rpc-url <URL> --private-key <0xPK> [--multiSchnorrVerifier <0xAddress>] [--proof proof.json] [--vk <path>] [--compressed]
+  go run ./onchain check [--proof proof.json] [--vk <path>] [--threshold <uint> --merkle-root <uint|0xhex>] [--rpc-url <URL>] [--compressed]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch os.Args[1] {
+	case "deploy":
+		runDeploy(ctx, os.Args[2:])
+	case "submit":
+		runSubmit(ctx, os.Args[2:])
+	case "check":
+		runCheck(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+}
+
+func transactor(ctx context.Context, rpcURL, pk string) *chain.Transactor {
+	if rpcURL == "" || pk == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	signer, err := chain.NewSigner(pk)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tr, err := chain.NewTransactor(ctx, chain.NewClient(rpcURL), signer)
+	if err != nil {
+		log.Fatalf("connect %s: %v", rpcURL, err)
+	}
+	return tr
+}
+
+// go run ./onchain deploy: Verifier, then MultiSchnorrVerifier(verifier, threshold, root, deployer)
+// from the Foundry artifacts in contract/out (run forge build first)
+func runDeploy(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+	rpcURL := fs.String("rpc-url", "", "JSON-RPC endpoint")
+	pk := fs.String("private-key", "", "deployer private key (0x hex)")
+	thresholdStr := fs.String("threshold", "", "minimum SumValid accepted by verify")
+	rootStr := fs.String("merkle-root", "", "registry root (default: merkle_root.txt)")
+	network := fs.String("network", "", "network name written to deployment.json (default: from chain id)")
+	outDir := fs.String("out", utils.RepoPath("../contract/out"), "Foundry out directory")
+	deployPath := fs.String("deployment", utils.RepoPath("../deployment.json"), "where to write the deployment record")
+	formatStr := fs.String("proof-format", string(chain.ProofUncompressed), "entry point submit uses by default: uncompressed (verify) or compressed (verifyCompressed)")
+	_ = fs.Parse(args)
+
+	format, err := chain.ParseProofFormat(*formatStr)
+	if err != nil {
+		log.Fatalf("--proof-format: %v", err)
+	}
+	threshold, ok := parseUint(*thresholdStr)
+	if !ok {
+		log.Fatalf("--threshold: %q is not a uint256", *thresholdStr)
+	}
+	if *rootStr == "" {
+		data, err := os.ReadFile(utils.RepoPath("../merkle_root.txt"))
+		if err != nil {
+			log.Fatalf("--merkle-root not provided and merkle_root.txt unreadable (run keygen): %v", err)
+		}
+		*rootStr = strings.TrimSpace(string(data))
+		fmt.Println(">> Using Merkle root from merkle_root.txt:", *rootStr)
+	}
+	root, ok := parseUint(*rootStr)
+	if !ok {
+		log.Fatalf("--merkle-root: %q is not a uint256", *rootStr)
+	}
+
+	tr := transactor(ctx, *rpcURL, *pk)
+	fmt.Printf(">> Deploying from %s on chain %s\n", tr.From(), tr.ChainID)
+	d, err := chain.Deploy(ctx, tr, chain.DeployConfig{
+		OutDir:      *outDir,
+		Threshold:   threshold,
+		MerkleRoot:  root,
+		Network:     *network,
+		ProofFormat: format,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Verifier deployed at:", d.Verifier)
+	fmt.Println("MultischnorrVerifier deployed at:", d.MultiSchnorrVerifier)
+	fmt.Println("owner:", d.Owner)
+	fmt.Println("proof format:", d.ProofFormat)
+
+	if err := chain.WriteDeployment(*deployPath, d); err != nil {
+		log.Fatalf("write %s: %v", *deployPath, err)
+	}
+	fmt.Println(">> Deployment info saved to", *deployPath)
+}
+
+// go run ./onchain submit: sends proof.json to MultiSchnorrVerifier.verify, or to
+// verifyCompressed with --compressed (default: the format recorded in deployment.json)
+func runSubmit(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	rpcURL := fs.String("rpc-url", "", "JSON-RPC endpoint")
+	pk := fs.String("private-key", "", "sender private key (0x hex)")
+	addrStr := fs.String("multiSchnorrVerifier", "", "contract address (default: deployment.json)")
+	proofPath := fs.String("proof", utils.RepoPath("../proof.json"), "proof written by the prover")
+	deployPath := fs.String("deployment", utils.RepoPath("../deployment.json"), "deployment record")
+	vkPath := fs.String("vk", utils.RepoPath("../setup/multischnorr.g16.vk"), "verifying key for the offline check (empty: skip)")
+	compressed := fs.Bool("compressed", false, "call verifyCompressed with the 4-word proof (default: deployment.json proofFormat)")
+	_ = fs.Parse(args)
+
+	var addr chain.Address
+	format := chain.ProofUncompressed
+	if *addrStr != "" {
+		a, err := chain.ParseAddress(*addrStr)
+		if err != nil {
+			log.Fatalf("--multiSchnorrVerifier: %v", err)
+		}
+		addr = a
+	} else {
+		d, err := chain.ReadDeployment(*deployPath)
+		if err != nil {
+			log.Fatalf("no --multiSchnorrVerifier and no deployment record: %v", err)
+		}
+		addr = d.MultiSchnorrVerifier
+		format = deploymentFormat(d)
+	}
+	format = formatFlag(fs, *compressed, format)
+
+	p, err := chain.ReadProofFile(*proofPath)
+	if err != nil {
+		log.Fatalf("read proof: %v", err)
+	}
+
+	tr := transactor(ctx, *rpcURL, *pk)
+	if *vkPath != "" {
+		st, err := chain.ReadState(ctx, tr.Client, addr)
+		if err != nil {
+			log.Fatalf("read contract state: %v", err)
+		}
+		if err := chain.CheckVerify(loadVK(*vkPath), st, p, format); err != nil {
+			log.Fatalf("verify would revert, not sending: %v", err)
+		}
+		fmt.Println("✓ Offline check passed")
+	}
+	fmt.Printf(">> Sending verify tx (%s proof) to %s\n", format, addr)
+	rcpt, err := chain.SubmitProof(ctx, tr, addr, p, format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("✓ Proof verified on-chain: tx %s, block %d, gas %d\n", rcpt.TxHash, rcpt.BlockNumber, rcpt.GasUsed)
+}
+
+// go run ./onchain check: runs MultiSchnorrVerifier.verify offline. Contract state comes
+// from --threshold/--merkle-root, else from the contract (--rpc-url), else from deployment.json
+func runCheck(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	proofPath := fs.String("proof", utils.RepoPath("../proof.json"), "proof written by the prover")
+	vkPath := fs.String("vk", utils.RepoPath("../setup/multischnorr.g16.vk"), "verifying key matching the deployed Verifier")
+	thresholdStr := fs.String("threshold", "", "contract threshold")
+	rootStr := fs.String("merkle-root", "", "contract merkleRoot")
+	rpcURL := fs.String("rpc-url", "", "read threshold and merkleRoot from the deployed contract")
+	addrStr := fs.String("multiSchnorrVerifier", "", "contract address (default: deployment.json)")
+	deployPath := fs.String("deployment", utils.RepoPath("../deployment.json"), "deployment record")
+	compressed := fs.Bool("compressed", false, "check verifyCompressed instead of verify (default: deployment.json proofFormat)")
+	_ = fs.Parse(args)
+
+	p, err := chain.ReadProofFile(*proofPath)
+	if err != nil {
+		log.Fatalf("read proof: %v", err)
+	}
+
+	var st chain.ContractState
+	format := chain.ProofUncompressed
+	if d, err := chain.ReadDeployment(*deployPath); err == nil {
+		format = deploymentFormat(d)
+	}
+	format = formatFlag(fs, *compressed, format)
+	switch {
+	case *thresholdStr != "" || *rootStr != "":
+		var ok bool
+		if st.Threshold, ok = parseUint(*thresholdStr); !ok {
+			log.Fatalf("--threshold: %q is not a uint256", *thresholdStr)
+		}
+		if st.MerkleRoot, ok = parseUint(*rootStr); !ok {
+			log.Fatalf("--merkle-root: %q is not a uint256", *rootStr)
+		}
+	case *rpcURL != "":
+		addr, err := chain.ParseAddress(*addrStr)
+		if *addrStr == "" {
+			var d *chain.Deployment
+			if d, err = chain.ReadDeployment(*deployPath); err == nil {
+				addr = d.MultiSchnorrVerifier
+			}
+		}
+		if err != nil {
+			log.Fatalf("contract address: %v", err)
+		}
+		if st, err = chain.ReadState(ctx, chain.NewClient(*rpcURL), addr); err != nil {
+			log.Fatalf("read contract state: %v", err)
+		}
+	default:
+		d, err := chain.ReadDeployment(*deployPath)
+		if err != nil {
+			log.Fatalf("no --threshold/--merkle-root and no deployment record: %v", err)
+		}
+		if st, err = chain.StateFromDeployment(d); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := chain.CheckVerify(loadVK(*vkPath), st, p, format); err != nil {
+		fmt.Printf("✗ verify (%s proof) would revert: %v\n", format, err)
+		os.Exit(2)
+	}
+	fmt.Printf("✓ verify (%s proof) would succeed\n", format)
+}
+
+func deploymentFormat(d *chain.Deployment) chain.ProofFormat {
+	f, err := chain.ParseProofFormat(string(d.ProofFormat))
+	if err != nil {
+		log.Fatalf("deployment record: %v", err)
+	}
+	return f
+}
+
+// an explicit --compressed / --compressed=false overrides the recorded format
+func formatFlag(fs *flag.FlagSet, compressed bool, def chain.ProofFormat) chain.ProofFormat {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == "compressed" })
+	if !set {
+		return def
+	}
+	if compressed {
+		return chain.ProofCompressed
+	}
+	return chain.ProofUncompressed
+}
+
+func loadVK(path string) groth16.VerifyingKey {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open VK: %v", err)
+	}
+	defer f.Close()
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if _, err := vk.ReadFrom(f); err != nil {
+		log.Fatalf("read VK %s: %v", path, err)
+	}
+	return vk
+}
+
+func parseUint(s string) (*big.Int, bool) {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return nil, false
+	}
+	return n, true
+}
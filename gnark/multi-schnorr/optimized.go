@@ -0,0 +1,202 @@
+package multischnorr
+
+import (
+	"math/big"
+
+	tebn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash"
+)
+
+// Same inputs and semantics as Circuit (drop-in for the same contract wrapper),
+// but each signature is checked with a fixed-base [S]G over precomputed multiples
+// of G and a single GLV [e]A, instead of two variable-base multiplications.
+//
+// gnark's DoubleBaseScalarMul(G, -A, S, e) was measured too but is more expensive
+// than two fake-GLV ScalarMul calls, since it runs over the full 254-bit scalars.
+type OptimizedCircuit struct {
+	Root     frontend.Variable `gnark:",public"` // Merkle root of valid public keys
+	S        [MaxK]Candidate   // K candidates
+	Message  frontend.Variable `gnark:",public"`
+	SumValid frontend.Variable `gnark:",public"` // number of valid signatures found
+}
+
+func (c *OptimizedCircuit) Define(api frontend.API) error {
+	return defineMultiSchnorr(api, c.Root, c.S[:], c.Message, c.SumValid, true)
+}
+
+// Circuit with the number of candidates fixed at compile time by len(S)
+// (a power of two, i.e. 2^depth), used to benchmark sizes other than MaxK.
+type SizedCircuit struct {
+	Root      frontend.Variable `gnark:",public"`
+	S         []Candidate
+	Message   frontend.Variable `gnark:",public"`
+	SumValid  frontend.Variable `gnark:",public"`
+	Optimized bool              `gnark:"-"`
+}
+
+func NewSizedCircuit(depth int, optimized bool) *SizedCircuit {
+	return &SizedCircuit{S: make([]Candidate, 1<<depth), Optimized: optimized}
+}
+
+func (c *SizedCircuit) Define(api frontend.API) error {
+	return defineMultiSchnorr(api, c.Root, c.S, c.Message, c.SumValid, c.Optimized)
+}
+
+const fixedBaseWindow = 3 // bits of S per precomputed table; 3 measured cheapest (2 and 4 cost more)
+
+// baseTables[w][j] = [j * 2^(fixedBaseWindow*w)]G for j in [0, 2^fixedBaseWindow)
+var baseTables = precomputeGTables()
+
+func precomputeGTables() [][][2]*big.Int {
+	params := tebn254.GetEdwardsCurve()
+	return precomputeBaseTables(params.Base, params.Order.BitLen())
+}
+
+// window tables of base for scalars of nbBits bits
+func precomputeBaseTables(base tebn254.PointAffine, nbBits int) [][][2]*big.Int {
+	nbWindows := (nbBits + fixedBaseWindow - 1) / fixedBaseWindow
+
+	var cur tebn254.PointAffine
+	cur.Set(&base)
+
+	tables := make([][][2]*big.Int, nbWindows)
+	for w := 0; w < nbWindows; w++ {
+		bits := fixedBaseWindow
+		if rem := nbBits - w*fixedBaseWindow; rem < bits {
+			bits = rem
+		}
+		tables[w] = make([][2]*big.Int, 1<<bits)
+
+		var acc tebn254.PointAffine
+		acc.X.SetZero()
+		acc.Y.SetOne()
+		for j := range tables[w] {
+			tables[w][j] = [2]*big.Int{acc.X.BigInt(new(big.Int)), acc.Y.BigInt(new(big.Int))}
+			acc.Add(&acc, &cur)
+		}
+		for b := 0; b < bits; b++ {
+			cur.Double(&cur)
+		}
+	}
+	return tables
+}
+
+// [s]G for s < 2^Order.BitLen()
+func fixedBaseMulG(api frontend.API, E twistededwards.Curve, s frontend.Variable) twistededwards.Point {
+	return fixedBaseMul(api, E, baseTables, s)
+}
+
+// [s]B with tables = precomputeBaseTables(B, nbBits) and s < 2^nbBits: one addition
+// per window, the window point is a multilinear polynomial in its bits with constant
+// coefficients, so only the bit products cost constraints (shared by X and Y)
+func fixedBaseMul(api frontend.API, E twistededwards.Curve, tables [][][2]*big.Int, s frontend.Variable) twistededwards.Point {
+	nbBits := 0
+	for _, table := range tables {
+		nbBits += log2(len(table))
+	}
+	b := api.ToBinary(s, nbBits)
+
+	var acc twistededwards.Point
+	for w, table := range tables {
+		bits := b[w*fixedBaseWindow : w*fixedBaseWindow+log2(len(table))]
+		P := lookupConstPoint(api, table, bits)
+		if w == 0 {
+			acc = P
+		} else {
+			acc = E.Add(acc, P)
+		}
+	}
+	return acc
+}
+
+// table[idx] with idx given by little-endian bits, len(table) == 2^len(bits)
+func lookupConstPoint(api frontend.API, table [][2]*big.Int, bits []frontend.Variable) twistededwards.Point {
+	n := len(table)
+
+	// monomials m[mask] = prod_{k in mask} bits[k]
+	m := make([]frontend.Variable, n)
+	m[0] = 1
+	for mask := 1; mask < n; mask++ {
+		k := 0
+		for mask&(1<<k) == 0 {
+			k++
+		}
+		rest := mask &^ (1 << k)
+		if rest == 0 {
+			m[mask] = bits[k]
+		} else {
+			m[mask] = api.Mul(m[rest], bits[k])
+		}
+	}
+
+	// coefficients by Möbius inversion: c[mask] = sum_{sub ⊆ mask} (-1)^{|mask\sub|} table[sub]
+	var x, y frontend.Variable = 0, 0
+	for mask := 0; mask < n; mask++ {
+		cx, cy := new(big.Int), new(big.Int)
+		for sub := mask; ; sub = (sub - 1) & mask {
+			if popcount(mask^sub)%2 == 0 {
+				cx.Add(cx, table[sub][0])
+				cy.Add(cy, table[sub][1])
+			} else {
+				cx.Sub(cx, table[sub][0])
+				cy.Sub(cy, table[sub][1])
+			}
+			if sub == 0 {
+				break
+			}
+		}
+		x = api.Add(x, api.Mul(m[mask], cx))
+		y = api.Add(y, api.Mul(m[mask], cy))
+	}
+	return twistededwards.Point{X: x, Y: y}
+}
+
+// same checks and result as verifySchnorrIf, with [S]G computed by fixedBaseMulG.
+// S is zeroed for ignored candidates so the bit decomposition holds for any filler.
+func verifySchnorrFixedBaseIf(
+	api frontend.API,
+	E twistededwards.Curve,
+	params *twistededwards.CurveParams,
+	h hash.FieldHasher,
+	A, R twistededwards.Point,
+	S, msg, active frontend.Variable,
+) frontend.Variable {
+	assertOnCurveIf(api, params, A, active)
+	assertOnCurveIf(api, params, R, active)
+
+	// Schnorr challenge e = H(Rx, Ry, Ax, Ay, msg)
+	h.Reset()
+	h.Write(R.X, R.Y, A.X, A.Y, msg)
+	e := h.Sum()
+
+	// check: [S]G == R + [e]A
+	sG := fixedBaseMulG(api, E, api.Select(active, S, 0))
+	eA := E.ScalarMul(A, e)
+	rhsP := E.Add(R, eA)
+	api.AssertIsEqual(api.Mul(active, api.Sub(sG.X, rhsP.X)), 0)
+	api.AssertIsEqual(api.Mul(active, api.Sub(sG.Y, rhsP.Y)), 0)
+	okX := api.IsZero(api.Sub(sG.X, rhsP.X))
+	okY := api.IsZero(api.Sub(sG.Y, rhsP.Y))
+
+	valid := api.Mul(active, okX)
+	return api.Mul(valid, okY)
+}
+
+func log2(n int) int {
+	k := 0
+	for 1<<k < n {
+		k++
+	}
+	return k
+}
+
+func popcount(x int) int {
+	c := 0
+	for ; x > 0; x >>= 1 {
+		c += x & 1
+	}
+	return c
+}
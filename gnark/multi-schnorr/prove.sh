@@ -48,26 +48,18 @@ read -r -a SIGNERS_ARR <<< "$SIGNERS_STR"
   go run . "$MSG" "${SIGNERS_ARR[@]}"
 popd >/dev/null
 
-if [[ ! -f proof.json ]]; then
+if [[ ! -f "$ROOT/proof.json" ]]; then
   echo "proof.json not found in repo root." >&2
   exit 1
 fi
 
 echo "✓ Proof generated successfully"
-echo ">> Reading proof data…"
-
-PROOF=$(jq -c '.proof' proof.json)
-INPUT=$(jq -c '.input' proof.json)
-MESSAGE_HEX=$(jq -r '.messageHex' proof.json)
-
-MERKLE_ROOT=$(jq -r '.input[0]' proof.json)
-SUM_VALID=$(jq -r '.input[2]' proof.json)
-
-echo ">> Sending verifyProof tx…"
-cast send \
-  --rpc-url "$RPC_URL" \
-  --private-key "$PK" \
-  "$MULTISCHNORRVERIFIER" \
-  "verify(uint256[8],bytes,uint256,uint256)" \
-  "$PROOF" "$MESSAGE_HEX" "$MERKLE_ROOT" "$SUM_VALID"
-echo ">> Done."
\ No newline at end of file
+echo ">> Sending verify tx…"
+pushd "$ROOT" >/dev/null
+  go run ./onchain submit \
+    --rpc-url "$RPC_URL" \
+    --private-key "$PK" \
+    --multiSchnorrVerifier "$MULTISCHNORRVERIFIER" \
+    --proof "$ROOT/proof.json"
+popd >/dev/null
+echo ">> Done."
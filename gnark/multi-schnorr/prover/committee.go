@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+const (
+	csCommitteePath = "../circuit_committee.r1cs"
+	pkCommitteePath = "../setup/multischnorr_committee.g16.pk"
+)
+
+type CommitteePublicInputs struct {
+	Root     *big.Int
+	Message  *big.Int
+	SumValid []*big.Int // per committee
+}
+
+// go run . committee --msg <message> --signers "0 1 2"
+func runCommittee(args []string) {
+	fs := flag.NewFlagSet("committee", flag.ExitOnError)
+	keysPath := fs.String("keys", utils.RepoPath("../keys.json"), "registry with committee assignments (keygen --committees)")
+	msgToHash := fs.String("msg", "", "message string or 0x hex")
+	signersStr := fs.String("signers", "", "space separated signer indices")
+	out := fs.String("out", defaultProofPath, "where to write proof.json")
+	_ = fs.Parse(args)
+
+	if *msgToHash == "" {
+		fmt.Fprintf(os.Stderr, "Usage: go run . committee [--keys <path>] [--out proof.json] --msg <message> --signers \"0 1 2\"\n")
+		os.Exit(1)
+	}
+
+	signers, err := parseIndices(*signersStr)
+	if err != nil {
+		log.Fatalf("signers: %v", err)
+	}
+	keys, err := utils.LoadKeysFrom(*keysPath)
+	if err != nil {
+		log.Fatalf("load registry: %v", err)
+	}
+
+	fmt.Printf("Generating committee proof with msg=%q, signers=%v\n", *msgToHash, signers)
+
+	proof, _, pubs, err := GenerateCommitteeProof(csCommitteePath, pkCommitteePath, keys, signers, *msgToHash)
+	if err != nil {
+		log.Fatalf("GenerateCommitteeProof failed: %v", err)
+	}
+
+	inputs := []namedInput{{"Root", pubs.Root}, {"Message", pubs.Message}}
+	for k, n := range pubs.SumValid {
+		inputs = append(inputs, namedInput{fmt.Sprintf("SumValid[%d]", k), n})
+	}
+	solOut, err := solidityOutput(proof, inputs, *msgToHash)
+	if err != nil {
+		log.Fatalf("solidityOutput failed: %v", err)
+	}
+	solOut.CommitteeCounts = pubs.SumValid
+	writeProofJSON(solOut, *out)
+}
+
+// msgToHash is hashed to Fr with Keccak.
+func GenerateCommitteeProof(
+	csPath string,
+	pkPath string,
+	keys []utils.KeyPair,
+	signerIndices []int,
+	msgToHash string,
+) (groth16.Proof, witness.Witness, CommitteePublicInputs, error) {
+
+	wd, err := utils.PrepareCommitteeWitnessData(
+		keys,
+		signerIndices,
+		frFromKeccak(msgToHash),
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, nil, CommitteePublicInputs{}, fmt.Errorf("prepare witness data: %w", err)
+	}
+
+	publics := CommitteePublicInputs{
+		Root:     wd.Root.BigInt(new(big.Int)),
+		Message:  wd.Message.BigInt(new(big.Int)),
+		SumValid: make([]*big.Int, multischnorr.NumCommittees),
+	}
+
+	assignment := new(multischnorr.CommitteeCircuit)
+	assignment.Root = publics.Root
+	assignment.Message = publics.Message
+	for k := 0; k < multischnorr.NumCommittees; k++ {
+		publics.SumValid[k] = big.NewInt(int64(wd.SumValid[k]))
+		assignment.SumValid[k] = publics.SumValid[k]
+	}
+
+	for i := 0; i < multischnorr.MaxK; i++ {
+		c := wd.Candidates[i]
+		assignment.S[i].Ax = c.Ax
+		assignment.S[i].Ay = c.Ay
+		assignment.S[i].Committee = big.NewInt(int64(wd.Committees[i]))
+		assignment.S[i].Sig.Rx = c.Sig.Rx
+		assignment.S[i].Sig.Ry = c.Sig.Ry
+		assignment.S[i].Sig.S = c.Sig.S
+		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+	}
+
+	fullW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, nil, CommitteePublicInputs{}, fmt.Errorf("NewWitness: %w", err)
+	}
+
+	cs := groth16.NewCS(ecc.BN254)
+	if err := readFromFile(csPath, cs); err != nil {
+		return nil, nil, CommitteePublicInputs{}, fmt.Errorf("read CS: %w", err)
+	}
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if err := readFromFile(pkPath, pk); err != nil {
+		return nil, nil, CommitteePublicInputs{}, fmt.Errorf("read PK: %w", err)
+	}
+
+	proof, err := groth16.Prove(cs, pk, fullW)
+	if err != nil {
+		return nil, nil, CommitteePublicInputs{}, fmt.Errorf("Prove: %w", err)
+	}
+
+	return proof, fullW, publics, nil
+}
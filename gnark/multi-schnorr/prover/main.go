@@ -1,8 +1,9 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -11,14 +12,10 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/consensys/gnark-crypto/ecc"
 	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/backend/groth16"
-	"github.com/consensys/gnark/backend/witness"
-	"github.com/consensys/gnark/frontend"
-	"golang.org/x/crypto/sha3"
 
-	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
 	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
 )
 
@@ -26,128 +23,46 @@ type SolidityOutput struct {
 	A          [2]*big.Int
 	B          [2][2]*big.Int
 	C          [2]*big.Int
-	Inputs     [3]*big.Int // [Root, Message, SumValid]
+	Compressed [4]*big.Int // for verifyCompressed
+	Inputs     []*big.Int  // [Root, Message, SumValid] for Circuit
 	MessageHex string
+
+	CommitteeCounts []*big.Int // valid signatures per committee, CommitteeCircuit only
 }
 
-type PublicInputs struct {
-	Root     *big.Int
-	Message  *big.Int
-	SumValid *big.Int
+// public input with the label it is printed under
+type namedInput struct {
+	Name  string
+	Value *big.Int
 }
 
 const (
-	csPath     = "../circuit.r1cs"
-	pkPath     = "../setup/multischnorr.g16.pk"
-	outputPath = "output.json"
-	vkPath     = "../setup/multischnorr.g16.vk"
+	csPath = "../circuit.r1cs"
+	pkPath = "../setup/multischnorr.g16.pk"
+	vkPath = "../setup/multischnorr.g16.vk"
+
+	// OptimizedCircuit has the same witness layout as Circuit
+	csOptimizedPath = "../circuit_optimized.r1cs"
+	pkOptimizedPath = "../setup/multischnorr_optimized.g16.pk"
+	vkOptimizedPath = "../setup/multischnorr_optimized.g16.vk"
 )
 
-func frFromKeccak(input string) fr.Element {
-	var bytes []byte
-	if strings.HasPrefix(input, "0x") {
-		hexStr := strings.TrimPrefix(input, "0x")
-		decoded, err := hex.DecodeString(hexStr)
-		if err != nil {
-			bytes = []byte(input)
-		} else {
-			bytes = decoded
-		}
-	} else {
-		bytes = []byte(input)
-	}
+// proof.json at the repo root, read by onchain submit
+var defaultProofPath = utils.RepoPath("../proof.json")
 
-	h := sha3.NewLegacyKeccak256()
-	h.Write(bytes)
-	digest := h.Sum(nil)
-	r := fr.Modulus()
-	bi := new(big.Int).SetBytes(digest)
-	bi.Mod(bi, r)
-	var out fr.Element
-	out.SetBigInt(bi)
-	return out
+func frFromKeccak(input string) fr.Element {
+	return utils.KeccakToFr(proving.ParseMessage(input))
 }
 
-// msgToHash is hashed to Fr with Keccak.
-func GenerateProof(
-	csPath string,
-	pkPath string,
-	signerIndices []int,
-	msgToHash string,
-) (groth16.Proof, witness.Witness, PublicInputs, error) {
-
-	wd, err := utils.PrepareWitnessData(
-		signerIndices,
-		frFromKeccak(msgToHash),
-		nil,
-		nil,
-	)
+// proves Circuit (or OptimizedCircuit) through the proving package,
+// the proof is checked against the VK before it is returned
+func GenerateProof(art proving.Artifacts, signerIndices []int, msgToHash string) (*proving.Result, error) {
+	ctx := context.Background()
+	p, err := proving.New(ctx, art)
 	if err != nil {
-		return nil, nil, PublicInputs{}, fmt.Errorf("prepare witness data: %w", err)
-	}
-
-	assignment := new(multischnorr.Circuit)
-	assignment.Root = wd.Root.BigInt(new(big.Int))
-	assignment.Message = wd.Message.BigInt(new(big.Int))
-	assignment.SumValid = big.NewInt(int64(wd.SumValid))
-
-	for i := 0; i < multischnorr.MaxK; i++ {
-		c := wd.Candidates[i]
-		assignment.S[i].Ax = c.Ax
-		assignment.S[i].Ay = c.Ay
-		assignment.S[i].Sig.Rx = c.Sig.Rx
-		assignment.S[i].Sig.Ry = c.Sig.Ry
-		assignment.S[i].Sig.S = c.Sig.S
-		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+		return nil, err
 	}
-
-	fullW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
-	if err != nil {
-		return nil, nil, PublicInputs{}, fmt.Errorf("NewWitness: %w", err)
-	}
-
-	cs := groth16.NewCS(ecc.BN254)
-	if err := readFromFile(csPath, cs); err != nil {
-		return nil, nil, PublicInputs{}, fmt.Errorf("read CS: %w", err)
-	}
-	pk := groth16.NewProvingKey(ecc.BN254)
-	if err := readFromFile(pkPath, pk); err != nil {
-		return nil, nil, PublicInputs{}, fmt.Errorf("read PK: %w", err)
-	}
-
-	proof, err := groth16.Prove(cs, pk, fullW)
-	if err != nil {
-		return nil, nil, PublicInputs{}, fmt.Errorf("Prove: %w", err)
-	}
-
-	publics := PublicInputs{
-		Root:     wd.Root.BigInt(new(big.Int)),
-		Message:  wd.Message.BigInt(new(big.Int)),
-		SumValid: big.NewInt(int64(wd.SumValid)),
-	}
-
-	println("proof", proof)
-	return proof, fullW, publics, nil
-}
-
-func verifyProofLocally(
-	proof *groth16.Proof,
-	fullW witness.Witness,
-) error {
-	vk := groth16.NewVerifyingKey(ecc.BN254)
-	if err := readFromFile(vkPath, vk); err != nil {
-		log.Fatalf("read VK: %v", err)
-	}
-	pubW, err := fullW.Public()
-	if err != nil {
-		return fmt.Errorf("Public(): %w", err)
-	}
-	err = groth16.Verify(*proof, vk, pubW)
-	if err != nil {
-		log.Fatalf("Local verification failed: %v", err)
-	}
-	fmt.Println("✓ Local verification passed!")
-	return nil
+	return p.Prove(ctx, proving.ParseMessage(msgToHash), signerIndices)
 }
 
 func convertProofToSolidityOutput(
@@ -155,29 +70,31 @@ func convertProofToSolidityOutput(
 	rootBI, msgBI, sumValidBI *big.Int,
 	msgToHash string,
 ) (SolidityOutput, error) {
-	var buf bytes.Buffer
-	if _, err := proof.WriteRawTo(&buf); err != nil {
-		return SolidityOutput{}, fmt.Errorf("WriteRawTo: %w", err)
-	}
-	proofBytes := buf.Bytes()
+	return solidityOutput(proof, []namedInput{
+		{"Root", rootBI},
+		{"Message", msgBI},
+		{"SumValid", sumValidBI},
+	}, msgToHash)
+}
 
-	const fpSize = 32
-	if len(proofBytes) < 8*fpSize {
-		return SolidityOutput{}, fmt.Errorf("raw proof too small: %d bytes, expected at least %d", len(proofBytes), 8*fpSize)
+// inputs are in public witness order
+func solidityOutput(
+	proof groth16.Proof,
+	inputs []namedInput,
+	msgToHash string,
+) (SolidityOutput, error) {
+	raw, err := proving.SolidityProof(proof)
+	if err != nil {
+		return SolidityOutput{}, err
+	}
+	a := [2]*big.Int{raw[0], raw[1]}
+	b := [2][2]*big.Int{{raw[2], raw[3]}, {raw[4], raw[5]}}
+	c := [2]*big.Int{raw[6], raw[7]}
+	compressed, err := proving.CompressSolidityProof(raw)
+	if err != nil {
+		return SolidityOutput{}, err
 	}
 
-	var a [2]*big.Int
-	var b [2][2]*big.Int
-	var c [2]*big.Int
-
-	a[0] = new(big.Int).SetBytes(proofBytes[fpSize*0 : fpSize*1])
-	a[1] = new(big.Int).SetBytes(proofBytes[fpSize*1 : fpSize*2])
-	b[0][0] = new(big.Int).SetBytes(proofBytes[fpSize*2 : fpSize*3])
-	b[0][1] = new(big.Int).SetBytes(proofBytes[fpSize*3 : fpSize*4])
-	b[1][0] = new(big.Int).SetBytes(proofBytes[fpSize*4 : fpSize*5])
-	b[1][1] = new(big.Int).SetBytes(proofBytes[fpSize*5 : fpSize*6])
-	c[0] = new(big.Int).SetBytes(proofBytes[fpSize*6 : fpSize*7])
-	c[1] = new(big.Int).SetBytes(proofBytes[fpSize*7 : fpSize*8])
 	var messageHex string
 	if strings.HasPrefix(msgToHash, "0x") {
 		messageHex = msgToHash
@@ -185,11 +102,17 @@ func convertProofToSolidityOutput(
 		messageHex = "0x" + hex.EncodeToString([]byte(msgToHash))
 	}
 
+	inputVals := make([]*big.Int, len(inputs))
+	for i, in := range inputs {
+		inputVals[i] = in.Value
+	}
+
 	output := SolidityOutput{
 		A:          a,
 		B:          b,
 		C:          c,
-		Inputs:     [3]*big.Int{rootBI, msgBI, sumValidBI},
+		Compressed: compressed,
+		Inputs:     inputVals,
 		MessageHex: messageHex,
 	}
 
@@ -208,15 +131,21 @@ func convertProofToSolidityOutput(
 	fmt.Printf("  C[0]: %s\n", c[0].String())
 	fmt.Printf("  C[1]: %s\n", c[1].String())
 
+	fmt.Printf("\nCompressed proof:\n")
+	for i, w := range compressed {
+		fmt.Printf("  [%d]: %s\n", i, w.String())
+	}
+
 	fmt.Printf("\nPublic Inputs:\n")
-	fmt.Printf("  Root:     %s\n", rootBI.String())
-	fmt.Printf("  Message:  %s\n", msgBI.String())
-	fmt.Printf("  SumValid: %s\n", sumValidBI.String())
+	for _, in := range inputs {
+		fmt.Printf("  %-12s %s\n", in.Name+":", in.Value.String())
+	}
 
 	fmt.Printf("\nMessage:\n")
 	fmt.Printf("  Original: %s\n", msgToHash)
 	fmt.Printf("  Message in Bytes:      %s\n", messageHex)
-	fmt.Println("======================\n")
+	fmt.Println("======================")
+	fmt.Println()
 
 	return output, nil
 }
@@ -235,58 +164,93 @@ func readFromFile(path string, r interface {
 
 func main() {
 
-	if len(os.Args) < 3 {
-		fmt.Fprintf(os.Stderr, "Usage: go run . <message> <signer_indices...>\n")
+	if len(os.Args) > 1 && os.Args[1] == "multiroot" {
+		runMultiRoot(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "committee" {
+		runCommittee(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "musig" {
+		runMuSig(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "request" {
+		runRequests(os.Args[2:])
+		return
+	}
+
+	args := os.Args[1:]
+	art := proving.Artifacts{CS: csPath, PK: pkPath, VK: vkPath}
+	if len(args) > 0 && args[0] == "optimized" {
+		art = proving.Artifacts{CS: csOptimizedPath, PK: pkOptimizedPath, VK: vkOptimizedPath}
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("prover", flag.ExitOnError)
+	out := fs.String("out", defaultProofPath, "where to write proof.json")
+	_ = fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: go run . [optimized] [--out proof.json] <message> <signer_indices...>\n")
+		fmt.Fprintf(os.Stderr, "       go run . request [--optimized] <request.json|yaml>...\n")
 		fmt.Fprintf(os.Stderr, "Example: go run . 'Hello world' 0 1 2 3 4 5 6 7 8 9\n")
 		os.Exit(1)
 	}
 
-	msgToHash := os.Args[1]
+	msgToHash := args[0]
 
-	signerIndices := make([]int, 0, len(os.Args)-2)
-	for _, arg := range os.Args[2:] {
-		signerIndices = append(signerIndices, atoiOrExit(arg, "signer index"))
+	signerIndices, err := parseIndices(strings.Join(args[1:], " "))
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	fmt.Printf("Generating proof with msg=%q, signers=%v\n",
 		msgToHash, signerIndices)
 
-	proof, _, pubs, err := GenerateProof(csPath, pkPath, signerIndices, msgToHash)
+	res, err := GenerateProof(art, signerIndices, msgToHash)
 	if err != nil {
 		log.Fatalf("GenerateProof failed: %v", err)
 	}
+	fmt.Println("✓ Local verification passed!")
 
-	solOut, err := convertProofToSolidityOutput(proof, pubs.Root, pubs.Message, pubs.SumValid, msgToHash)
+	solOut, err := convertProofToSolidityOutput(res.Proof, res.Public.Root, res.Public.Message, res.Public.SumValid, msgToHash)
 	if err != nil {
 		log.Fatalf("convertProofToSolidityOutput failed: %v", err)
 	}
-	writeProofJSON(solOut)
+	writeProofJSON(solOut, *out)
 }
 
-func atoiOrExit(s string, name string) int {
-	val, ok := new(big.Int).SetString(s, 10)
-	if !ok {
-		fmt.Fprintf(os.Stderr, "Invalid %s: %q\n", name, s)
-		os.Exit(1)
+func writeProofJSON(out SolidityOutput, outPath string) {
+	inputs := make([]string, len(out.Inputs))
+	for i, in := range out.Inputs {
+		inputs[i] = in.String()
+	}
+	committeeCounts := ""
+	if out.CommitteeCounts != nil {
+		counts := make([]string, len(out.CommitteeCounts))
+		for i, n := range out.CommitteeCounts {
+			counts[i] = n.String()
+		}
+		committeeCounts = fmt.Sprintf(",\n\t\"committeeCounts\": [%s]", strings.Join(counts, ","))
 	}
-	return int(val.Int64())
-}
-
-func writeProofJSON(out SolidityOutput) {
 	data := fmt.Sprintf(`{
   	"proof": [%s,%s,%s,%s,%s,%s,%s,%s],
-  	"input": [%s,%s,%s],
-	"messageHex":"%s"
+  	"compressedProof": [%s,%s,%s,%s],
+  	"input": [%s],
+	"messageHex":"%s"%s
 	}`,
 		out.A[0], out.A[1],
 		out.B[0][0], out.B[0][1],
 		out.B[1][0], out.B[1][1],
 		out.C[0], out.C[1],
-		out.Inputs[0], out.Inputs[1], out.Inputs[2],
+		out.Compressed[0], out.Compressed[1], out.Compressed[2], out.Compressed[3],
+		strings.Join(inputs, ","),
 		out.MessageHex,
+		committeeCounts,
 	)
 
-	outPath := utils.RepoPath("../proof.json")
 	if err := os.WriteFile(outPath, []byte(data), 0644); err != nil {
 		log.Fatalf("failed to write %s: %v", outPath, err)
 	}
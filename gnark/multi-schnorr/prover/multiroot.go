@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+const (
+	csMultiRootPath = "../circuit_multiroot.r1cs"
+	pkMultiRootPath = "../setup/multischnorr_multiroot.g16.pk"
+)
+
+type MultiRootPublicInputs struct {
+	RootOld     *big.Int
+	RootNew     *big.Int
+	Message     *big.Int
+	SumValidOld *big.Int
+	SumValidNew *big.Int
+}
+
+// go run . multiroot --new-keys <path> --msg <message> --old-signers "0 1" --new-signers "2 3"
+func runMultiRoot(args []string) {
+	fs := flag.NewFlagSet("multiroot", flag.ExitOnError)
+	oldKeysPath := fs.String("old-keys", utils.RepoPath("../keys.json"), "registry committed under RootOld")
+	newKeysPath := fs.String("new-keys", "", "registry committed under RootNew")
+	msgToHash := fs.String("msg", "", "message string or 0x hex")
+	oldSignersStr := fs.String("old-signers", "", "space separated seats signing with their old key")
+	newSignersStr := fs.String("new-signers", "", "space separated seats signing with their new key")
+	out := fs.String("out", defaultProofPath, "where to write proof.json")
+	_ = fs.Parse(args)
+
+	if *newKeysPath == "" || *msgToHash == "" {
+		fmt.Fprintf(os.Stderr, "Usage: go run . multiroot --new-keys <path> [--old-keys <path>] [--out proof.json] --msg <message> --old-signers \"0 1\" --new-signers \"2 3\"\n")
+		os.Exit(1)
+	}
+
+	oldSigners, err := parseIndices(*oldSignersStr)
+	if err != nil {
+		log.Fatalf("old-signers: %v", err)
+	}
+	newSigners, err := parseIndices(*newSignersStr)
+	if err != nil {
+		log.Fatalf("new-signers: %v", err)
+	}
+
+	oldKeys, err := utils.LoadKeysFrom(*oldKeysPath)
+	if err != nil {
+		log.Fatalf("load old registry: %v", err)
+	}
+	newKeys, err := utils.LoadKeysFrom(*newKeysPath)
+	if err != nil {
+		log.Fatalf("load new registry: %v", err)
+	}
+
+	fmt.Printf("Generating multi-root proof with msg=%q, old signers=%v, new signers=%v\n",
+		*msgToHash, oldSigners, newSigners)
+
+	proof, _, pubs, err := GenerateMultiRootProof(csMultiRootPath, pkMultiRootPath, oldKeys, newKeys, oldSigners, newSigners, *msgToHash)
+	if err != nil {
+		log.Fatalf("GenerateMultiRootProof failed: %v", err)
+	}
+
+	solOut, err := solidityOutput(proof, []namedInput{
+		{"RootOld", pubs.RootOld},
+		{"RootNew", pubs.RootNew},
+		{"Message", pubs.Message},
+		{"SumValidOld", pubs.SumValidOld},
+		{"SumValidNew", pubs.SumValidNew},
+	}, *msgToHash)
+	if err != nil {
+		log.Fatalf("solidityOutput failed: %v", err)
+	}
+	writeProofJSON(solOut, *out)
+}
+
+// msgToHash is hashed to Fr with Keccak.
+func GenerateMultiRootProof(
+	csPath string,
+	pkPath string,
+	oldKeys, newKeys []utils.KeyPair,
+	oldSigners, newSigners []int,
+	msgToHash string,
+) (groth16.Proof, witness.Witness, MultiRootPublicInputs, error) {
+
+	wd, err := utils.PrepareMultiRootWitnessData(
+		oldKeys, newKeys,
+		oldSigners, newSigners,
+		frFromKeccak(msgToHash),
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, nil, MultiRootPublicInputs{}, fmt.Errorf("prepare witness data: %w", err)
+	}
+
+	publics := MultiRootPublicInputs{
+		RootOld:     wd.RootOld.BigInt(new(big.Int)),
+		RootNew:     wd.RootNew.BigInt(new(big.Int)),
+		Message:     wd.Message.BigInt(new(big.Int)),
+		SumValidOld: big.NewInt(int64(wd.SumValidOld)),
+		SumValidNew: big.NewInt(int64(wd.SumValidNew)),
+	}
+
+	assignment := new(multischnorr.MultiRootCircuit)
+	assignment.RootOld = publics.RootOld
+	assignment.RootNew = publics.RootNew
+	assignment.Message = publics.Message
+	assignment.SumValidOld = publics.SumValidOld
+	assignment.SumValidNew = publics.SumValidNew
+
+	for i := 0; i < multischnorr.MaxK; i++ {
+		c := wd.Candidates[i]
+		assignment.S[i].OldAx = c.OldAx
+		assignment.S[i].OldAy = c.OldAy
+		assignment.S[i].NewAx = c.NewAx
+		assignment.S[i].NewAy = c.NewAy
+		assignment.S[i].Sig.Rx = c.Sig.Rx
+		assignment.S[i].Sig.Ry = c.Sig.Ry
+		assignment.S[i].Sig.S = c.Sig.S
+		assignment.S[i].UseNew = big.NewInt(int64(c.UseNew))
+		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+	}
+
+	fullW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, nil, MultiRootPublicInputs{}, fmt.Errorf("NewWitness: %w", err)
+	}
+
+	cs := groth16.NewCS(ecc.BN254)
+	if err := readFromFile(csPath, cs); err != nil {
+		return nil, nil, MultiRootPublicInputs{}, fmt.Errorf("read CS: %w", err)
+	}
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if err := readFromFile(pkPath, pk); err != nil {
+		return nil, nil, MultiRootPublicInputs{}, fmt.Errorf("read PK: %w", err)
+	}
+
+	proof, err := groth16.Prove(cs, pk, fullW)
+	if err != nil {
+		return nil, nil, MultiRootPublicInputs{}, fmt.Errorf("Prove: %w", err)
+	}
+
+	return proof, fullW, publics, nil
+}
+
+func parseIndices(s string) ([]int, error) {
+	fields := strings.Fields(s)
+	out := make([]int, 0, len(fields))
+	for _, f := range fields {
+		i, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signer index %q", f)
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
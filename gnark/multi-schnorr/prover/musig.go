@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+const (
+	csMuSigPath = "../circuit_musig.r1cs"
+	pkMuSigPath = "../setup/multischnorr_musig.g16.pk"
+)
+
+type MuSigPublicInputs struct {
+	Root     *big.Int
+	Message  *big.Int
+	SumValid *big.Int
+	Bitmap   *big.Int
+}
+
+// go run . musig --msg <message> [--signers "0 1 2"]
+// the whole validator set (default) signs once with MuSig2; a partial set falls back
+// to the per-signer circuit, since the interactive session needs every participant online
+func runMuSig(args []string) {
+	fs := flag.NewFlagSet("musig", flag.ExitOnError)
+	msgToHash := fs.String("msg", "", "message string or 0x hex")
+	signersStr := fs.String("signers", "", "space separated signer indices (default: every registered validator)")
+	out := fs.String("out", defaultProofPath, "where to write proof.json")
+	_ = fs.Parse(args)
+
+	if *msgToHash == "" {
+		fmt.Fprintf(os.Stderr, "Usage: go run . musig --msg <message> [--signers \"0 1 2\"] [--out proof.json]\n")
+		os.Exit(1)
+	}
+
+	keys, err := utils.LoadKeysFromFile()
+	if err != nil {
+		log.Fatalf("load registry: %v", err)
+	}
+	signers, err := parseIndices(*signersStr)
+	if err != nil {
+		log.Fatalf("signers: %v", err)
+	}
+	if len(signers) == 0 {
+		for i := range keys {
+			if keys[i].Pub.Ax.Sign() != 0 {
+				signers = append(signers, i)
+			}
+		}
+	}
+
+	if !utils.IsFullSet(keys, signers) {
+		fmt.Printf("Partial signer set %v: falling back to per-signer verification\n", signers)
+		res, err := GenerateProof(proving.Artifacts{CS: csPath, PK: pkPath, VK: vkPath}, signers, *msgToHash)
+		if err != nil {
+			log.Fatalf("GenerateProof failed: %v", err)
+		}
+		solOut, err := convertProofToSolidityOutput(res.Proof, res.Public.Root, res.Public.Message, res.Public.SumValid, *msgToHash)
+		if err != nil {
+			log.Fatalf("convertProofToSolidityOutput failed: %v", err)
+		}
+		writeProofJSON(solOut, *out)
+		return
+	}
+
+	fmt.Printf("Generating MuSig2 proof with msg=%q, signers=%v\n", *msgToHash, signers)
+
+	proof, _, pubs, err := GenerateMuSigProof(csMuSigPath, pkMuSigPath, keys, signers, *msgToHash)
+	if err != nil {
+		log.Fatalf("GenerateMuSigProof failed: %v", err)
+	}
+
+	solOut, err := solidityOutput(proof, []namedInput{
+		{"Root", pubs.Root},
+		{"Message", pubs.Message},
+		{"SumValid", pubs.SumValid},
+		{"Bitmap", pubs.Bitmap},
+	}, *msgToHash)
+	if err != nil {
+		log.Fatalf("solidityOutput failed: %v", err)
+	}
+	writeProofJSON(solOut, *out)
+}
+
+// msgToHash is hashed to Fr with Keccak.
+func GenerateMuSigProof(
+	csPath string,
+	pkPath string,
+	keys []utils.KeyPair,
+	signerIndices []int,
+	msgToHash string,
+) (groth16.Proof, witness.Witness, MuSigPublicInputs, error) {
+
+	wd, err := utils.PrepareMuSigWitnessData(keys, signerIndices, frFromKeccak(msgToHash), nil)
+	if err != nil {
+		return nil, nil, MuSigPublicInputs{}, fmt.Errorf("prepare witness data: %w", err)
+	}
+
+	publics := MuSigPublicInputs{
+		Root:     wd.Root.BigInt(new(big.Int)),
+		Message:  wd.Message.BigInt(new(big.Int)),
+		SumValid: big.NewInt(int64(wd.SumValid)),
+		Bitmap:   wd.Bitmap,
+	}
+
+	assignment := new(multischnorr.MuSigCircuit)
+	assignment.Root = publics.Root
+	assignment.Message = publics.Message
+	assignment.SumValid = publics.SumValid
+	assignment.Bitmap = publics.Bitmap
+	assignment.Sig.Rx = wd.Sig.Rx
+	assignment.Sig.Ry = wd.Sig.Ry
+	assignment.Sig.S = wd.Sig.S
+	for i := 0; i < multischnorr.MaxK; i++ {
+		assignment.Keys[i].Ax = wd.Keys[i].Ax
+		assignment.Keys[i].Ay = wd.Keys[i].Ay
+	}
+
+	fullW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, nil, MuSigPublicInputs{}, fmt.Errorf("NewWitness: %w", err)
+	}
+
+	cs := groth16.NewCS(ecc.BN254)
+	if err := readFromFile(csPath, cs); err != nil {
+		return nil, nil, MuSigPublicInputs{}, fmt.Errorf("read CS: %w", err)
+	}
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if err := readFromFile(pkPath, pk); err != nil {
+		return nil, nil, MuSigPublicInputs{}, fmt.Errorf("read PK: %w", err)
+	}
+
+	proof, err := groth16.Prove(cs, pk, fullW)
+	if err != nil {
+		return nil, nil, MuSigPublicInputs{}, fmt.Errorf("Prove: %w", err)
+	}
+
+	return proof, fullW, publics, nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/chain"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/proving"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Request is one proof to generate, read from a JSON or YAML request file:
+//
+//	message: "hello"                  # or 0x hex; or messageFields
+//	messageFields:                    # abi.encodePacked of typed values
+//	  - {type: uint64, value: 7}
+//	  - {type: string, value: price}
+//	signers: [0, 1, 2]                # registry indices signed with keys.json; or bundle
+//	bundle: bundles/epoch7.json       # signatures already collected, no secret keys needed
+//	out: proofs/hello.json            # proof.json for onchain submit
+//	rawOut: proofs/hello.proof        # optional, gnark's raw proof encoding
+//	verify: true                      # check against the VK before writing (default)
+//
+// A file holds one request, or many under "requests" (batch mode).
+// Relative paths are resolved against the directory of the request file.
+type Request struct {
+	Message       *string        `json:"message,omitempty" yaml:"message,omitempty"`
+	MessageFields []MessageField `json:"messageFields,omitempty" yaml:"messageFields,omitempty"`
+	Signers       []int          `json:"signers,omitempty" yaml:"signers,omitempty"`
+	Bundle        string         `json:"bundle,omitempty" yaml:"bundle,omitempty"`
+	Out           string         `json:"out,omitempty" yaml:"out,omitempty"`
+	RawOut        string         `json:"rawOut,omitempty" yaml:"rawOut,omitempty"`
+	Verify        *bool          `json:"verify,omitempty" yaml:"verify,omitempty"`
+}
+
+// MessageField is a value packed like Solidity's abi.encodePacked.
+// Types: uint8..uint256, int8..int256, address, bool, bytes1..bytes32, bytes (hex), string.
+type MessageField struct {
+	Type  string     `json:"type" yaml:"type"`
+	Value FieldValue `json:"value" yaml:"value"`
+}
+
+// FieldValue keeps the literal text of a string or number, so that uint256
+// values survive JSON decoding without going through float64.
+type FieldValue string
+
+func (v *FieldValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = FieldValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("field value must be a string or a number, got %s", data)
+	}
+	*v = FieldValue(n)
+	return nil
+}
+
+func (v *FieldValue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: field value must be a scalar", node.Line)
+	}
+	*v = FieldValue(node.Value)
+	return nil
+}
+
+type requestFile struct {
+	Request  `yaml:",inline"`
+	Requests []Request `json:"requests,omitempty" yaml:"requests,omitempty"`
+}
+
+// LoadRequests reads a request file (.yaml/.yml, JSON otherwise) and resolves
+// its relative paths. Unknown keys are rejected to catch typos.
+func LoadRequests(path string) ([]Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f requestFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	default:
+		// requestFile embeds Request, which encoding/json flattens as well
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	reqs := f.Requests
+	single := f.Request
+	if single.Message != nil || single.MessageFields != nil || single.Signers != nil || single.Bundle != "" {
+		if len(reqs) > 0 {
+			return nil, fmt.Errorf("%s: either one request or a requests list, not both", path)
+		}
+		reqs = []Request{single}
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%s: no request", path)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range reqs {
+		r := &reqs[i]
+		if len(reqs) > 1 && r.Out == "" {
+			return nil, fmt.Errorf("%s: request %d: out is required in batch mode", path, i)
+		}
+		r.Bundle = resolve(dir, r.Bundle)
+		r.Out = resolve(dir, r.Out)
+		r.RawOut = resolve(dir, r.RawOut)
+	}
+	return reqs, nil
+}
+
+func resolve(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+func (r *Request) verify() bool { return r.Verify == nil || *r.Verify }
+
+// MessageBytes is the message as passed to MultiSchnorrVerifier.verify.
+func (r *Request) MessageBytes() ([]byte, error) {
+	switch {
+	case r.Message != nil && r.MessageFields != nil:
+		return nil, errors.New("message and messageFields are exclusive")
+	case r.Message != nil:
+		return proving.ParseMessage(*r.Message), nil
+	case r.MessageFields != nil:
+		return EncodePacked(r.MessageFields)
+	}
+	return nil, errors.New("no message or messageFields")
+}
+
+// EncodePacked concatenates the fields like abi.encodePacked.
+func EncodePacked(fields []MessageField) ([]byte, error) {
+	var out []byte
+	for i, f := range fields {
+		b, err := packField(f.Type, string(f.Value))
+		if err != nil {
+			return nil, fmt.Errorf("messageFields[%d] (%s): %w", i, f.Type, err)
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+func packField(typ, value string) ([]byte, error) {
+	switch {
+	case typ == "string":
+		return []byte(value), nil
+	case typ == "bytes":
+		return decodeHexValue(value)
+	case typ == "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case typ == "address":
+		a, err := chain.ParseAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		return a[:], nil
+	case strings.HasPrefix(typ, "bytes"):
+		n, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || n < 1 || n > 32 {
+			return nil, fmt.Errorf("unknown type")
+		}
+		b, err := decodeHexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != n {
+			return nil, fmt.Errorf("%d bytes, want %d", len(b), n)
+		}
+		return b, nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"))
+		if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("unknown type")
+		}
+		n, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		lo, hi := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if signed {
+			hi.Rsh(hi, 1)
+			lo.Neg(hi)
+		}
+		if n.Cmp(lo) < 0 || n.Cmp(hi) >= 0 {
+			return nil, fmt.Errorf("%v out of range", n)
+		}
+		if n.Sign() < 0 { // two's complement
+			n.Add(n, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+		}
+		return n.FillBytes(make([]byte, bits/8)), nil
+	}
+	return nil, fmt.Errorf("unknown type")
+}
+
+func decodeHexValue(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("%q is not 0x hex", s)
+	}
+	return hex.DecodeString(s[2:])
+}
+
+// go run . request [--optimized] [--keys keys.json] <request file>...
+// every request of every file is proved with the same loaded circuit and proving key
+func runRequests(args []string) {
+	fs := flag.NewFlagSet("request", flag.ExitOnError)
+	optimized := fs.Bool("optimized", false, "prove OptimizedCircuit")
+	keysPath := fs.String("keys", utils.DefaultKeyPath(), "validator registry")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: go run . request [--optimized] [--keys <path>] <request.json|yaml>...\n")
+		os.Exit(1)
+	}
+
+	var reqs []Request
+	for _, path := range fs.Args() {
+		rs, err := LoadRequests(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reqs = append(reqs, rs...)
+	}
+	if len(reqs) > 1 {
+		seen := make(map[string]bool, len(reqs))
+		for _, r := range reqs {
+			if seen[r.Out] {
+				log.Fatalf("two requests write to %s", r.Out)
+			}
+			seen[r.Out] = true
+		}
+	}
+
+	art := proving.Artifacts{CS: utils.RepoPath(csPath), PK: utils.RepoPath(pkPath), VK: utils.RepoPath(vkPath)}
+	if *optimized {
+		art = proving.Artifacts{CS: utils.RepoPath(csOptimizedPath), PK: utils.RepoPath(pkOptimizedPath), VK: utils.RepoPath(vkOptimizedPath)}
+	}
+	needVK := false
+	for i := range reqs {
+		needVK = needVK || reqs[i].verify()
+	}
+	if !needVK {
+		art.VK = ""
+	}
+
+	// Prove does not verify; requests with verify: true call Verify with the loaded VK
+	ctx := context.Background()
+	p, err := proving.New(ctx, art, proving.WithKeyFile(*keysPath), proving.WithLocalVerify(false))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := 0
+	for i := range reqs {
+		fmt.Printf(">> request %d/%d\n", i+1, len(reqs))
+		if err := proveRequest(ctx, p, &reqs[i]); err != nil {
+			log.Printf("request %d: %v", i+1, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d requests failed", failed, len(reqs))
+	}
+}
+
+func proveRequest(ctx context.Context, p *proving.Prover, r *Request) error {
+	var res *proving.Result
+	switch {
+	case r.Bundle != "" && r.Signers != nil:
+		return errors.New("signers and bundle are exclusive")
+	case r.Bundle != "":
+		if r.Message != nil || r.MessageFields != nil {
+			return errors.New("the message of a bundle is its messageHex")
+		}
+		data, err := os.ReadFile(r.Bundle)
+		if err != nil {
+			return err
+		}
+		var b utils.SignatureBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			return fmt.Errorf("%s: %w", r.Bundle, err)
+		}
+		fmt.Printf("Proving %d signatures of bundle %s\n", len(b.Signatures), r.Bundle)
+		if res, err = p.ProveBundle(ctx, b); err != nil {
+			return err
+		}
+	default:
+		msg, err := r.MessageBytes()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Proving msg=0x%x, signers=%v\n", msg, r.Signers)
+		if res, err = p.Prove(ctx, msg, r.Signers); err != nil {
+			return err
+		}
+	}
+
+	if r.verify() {
+		if err := p.Verify(res); err != nil {
+			return err
+		}
+		fmt.Println("✓ Local verification passed!")
+	}
+
+	out := r.Out
+	if out == "" {
+		out = defaultProofPath
+	}
+	if err := writeResult(out, res); err != nil {
+		return err
+	}
+	fmt.Println("Proof exported to", out)
+	if r.RawOut != "" {
+		f, err := os.Create(r.RawOut)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := res.Proof.WriteRawTo(f); err != nil {
+			return fmt.Errorf("%s: %w", r.RawOut, err)
+		}
+		fmt.Println("Raw proof exported to", r.RawOut)
+	}
+	return nil
+}
+
+func writeResult(path string, res *proving.Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := res.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
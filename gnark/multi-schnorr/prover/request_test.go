@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRequestsJSONAndYAML(t *testing.T) {
+	dir := t.TempDir()
+	fromJSON, err := LoadRequests(writeFile(t, dir, "r.json", `{
+		"requests": [
+			{"message": "hello", "signers": [0, 1, 2], "out": "a.json", "verify": false},
+			{"messageFields": [{"type": "uint256", "value": 115792089237316195423570985008687907853269984665640564039457584007913129639935}],
+			 "bundle": "b/epoch7.json", "out": "/abs/b.json", "rawOut": "b.proof"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := LoadRequests(writeFile(t, dir, "r.yaml", `
+requests:
+  - message: hello
+    signers: [0, 1, 2]
+    out: a.json
+    verify: false
+  - messageFields:
+      - {type: uint256, value: 115792089237316195423570985008687907853269984665640564039457584007913129639935}
+    bundle: b/epoch7.json
+    out: /abs/b.json
+    rawOut: b.proof
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Fatalf("JSON and YAML differ:\n%+v\n%+v", fromJSON, fromYAML)
+	}
+
+	r := fromJSON
+	if r[0].Out != filepath.Join(dir, "a.json") || r[1].Out != "/abs/b.json" ||
+		r[1].Bundle != filepath.Join(dir, "b/epoch7.json") || r[1].RawOut != filepath.Join(dir, "b.proof") {
+		t.Fatalf("paths not resolved against the request file: %+v", r)
+	}
+	if r[0].verify() || !r[1].verify() {
+		t.Fatal("verify defaults to true, false is kept")
+	}
+	// uint256 max survives JSON number decoding
+	msg, err := r[1].MessageBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(msg) != strings.Repeat("ff", 32) {
+		t.Fatalf("message %x", msg)
+	}
+}
+
+func TestLoadRequestsSingle(t *testing.T) {
+	dir := t.TempDir()
+	r, err := LoadRequests(writeFile(t, dir, "one.yml", "message: '0x6869'\nsigners: [3]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := r[0].MessageBytes()
+	if len(r) != 1 || err != nil || string(msg) != "hi" || r[0].Out != "" {
+		t.Fatalf("%+v %q %v", r, msg, err)
+	}
+}
+
+func TestLoadRequestsErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"typo.json":    `{"mesage": "hi", "signers": [0]}`,
+		"typo.yaml":    "message: hi\nsigner: [0]\n",
+		"empty.json":   `{}`,
+		"no-out.yaml":  "requests:\n  - {message: a, signers: [0]}\n  - {message: b, signers: [1]}\n",
+		"both.json":    `{"message": "a", "signers": [0], "requests": [{"message": "b", "signers": [1], "out": "x"}]}`,
+		"bigidx.json":  `{"message": "a", "signers": [18446744073709551617]}`,
+		"fields.yaml":  "messageFields:\n  - {type: uint8, value: [1]}\n",
+		"notfile.json": `[`,
+	} {
+		if _, err := LoadRequests(writeFile(t, dir, name, data)); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
+
+func TestEncodePacked(t *testing.T) {
+	// abi.encodePacked(uint8(1), int16(-2), true, bytes2(0xabcd), "hi", address(...), hex"00ff", uint64(7))
+	got, err := EncodePacked([]MessageField{
+		{"uint8", "1"},
+		{"int16", "-2"},
+		{"bool", "true"},
+		{"bytes2", "0xabcd"},
+		{"string", "hi"},
+		{"address", "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+		{"bytes", "0x00ff"},
+		{"uint64", "0x7"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "01" + "fffe" + "01" + "abcd" + "6869" + "2c7536e3605d9c16a7a3d7b1898e529396a65c23" + "00ff" + "0000000000000007"
+	if hex.EncodeToString(got) != want {
+		t.Fatalf("got  %x\nwant %s", got, want)
+	}
+
+	for _, f := range []MessageField{
+		{"uint8", "256"},
+		{"uint8", "-1"},
+		{"int8", "128"},
+		{"int8", "-129"},
+		{"uint7", "1"},
+		{"bytes33", "0x00"},
+		{"bytes2", "0xab"},
+		{"bytes", "ab"},
+		{"bool", "maybe"},
+		{"address", "0x2C7536E3605D9C16a7a3D7b1898e529396a65c23"}, // bad checksum
+		{"tuple", "1"},
+	} {
+		if _, err := EncodePacked([]MessageField{f}); err == nil {
+			t.Errorf("%s %q accepted", f.Type, f.Value)
+		}
+	}
+}
+
+// signer indices used to go through big.Int.Int64 and wrap around
+func TestParseIndicesStrict(t *testing.T) {
+	if got, err := parseIndices("0 1 63"); err != nil || !reflect.DeepEqual(got, []int{0, 1, 63}) {
+		t.Fatalf("%v %v", got, err)
+	}
+	for _, s := range []string{"18446744073709551617", "1.5", "0x01", "one"} {
+		if _, err := parseIndices(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
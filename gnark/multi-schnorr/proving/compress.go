@@ -0,0 +1,219 @@
+package proving
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+)
+
+// Point compression of gnark's Solidity verifier (compressProof / verifyCompressedProof).
+// A compressed proof is 4 words instead of 8:
+//
+//	[0] A: x << 1 | sign(y)
+//	[1] B: x.A1
+//	[2] B: x.A0 << 2 | hint << 1 | sign(y)
+//	[3] C: x << 1 | sign(y)
+//
+// where sign is 1 if y is the negation of the root returned by the verifier's
+// square root, and hint selects the Fp2 square root branch. The point at
+// infinity is compressed to 0. The functions below follow the contract step by
+// step so that Go and Solidity agree on every bit.
+
+var errNotOnCurve = errors.New("point not on curve")
+
+var (
+	pMod = fp.Modulus()
+
+	fraction1_2   = fpFraction(1, 2)
+	fraction27_82 = fpFraction(27, 82)
+	fraction3_82  = fpFraction(3, 82)
+
+	expSqrt = new(big.Int).Rsh(new(big.Int).Add(pMod, big.NewInt(1)), 2) // (p+1)/4
+)
+
+func fpFraction(n, d int64) *big.Int {
+	inv := new(big.Int).ModInverse(big.NewInt(d), pMod)
+	return inv.Mul(inv, big.NewInt(n)).Mod(inv, pMod)
+}
+
+func fpMul(a, b *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Mul(a, b), pMod) }
+func fpAdd(a, b *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Add(a, b), pMod) }
+func fpNeg(a *big.Int) *big.Int    { return new(big.Int).Mod(new(big.Int).Sub(pMod, a), pMod) }
+
+// sqrt_Fp: ok is false if a is not a square
+func fpSqrt(a *big.Int) (*big.Int, bool) {
+	x := new(big.Int).Exp(a, expSqrt, pMod)
+	return x, fpMul(x, x).Cmp(a) == 0
+}
+
+// sqrt_Fp2 of a0 + a1*i, taking the branch selected by hint
+func fp2Sqrt(a0, a1 *big.Int, hint bool) (*big.Int, *big.Int, bool) {
+	d, ok := fpSqrt(fpAdd(fpMul(a0, a0), fpMul(a1, a1)))
+	if !ok {
+		return nil, nil, false
+	}
+	if hint {
+		d = fpNeg(d)
+	}
+	x0, ok := fpSqrt(fpMul(fpAdd(a0, d), fraction1_2))
+	if !ok {
+		return nil, nil, false
+	}
+	inv := new(big.Int).ModInverse(fpMul(x0, big.NewInt(2)), pMod)
+	if inv == nil {
+		return nil, nil, false
+	}
+	x1 := fpMul(a1, inv)
+	if a0.Cmp(fpAdd(fpMul(x0, x0), fpNeg(fpMul(x1, x1)))) != 0 || a1.Cmp(fpMul(big.NewInt(2), fpMul(x0, x1))) != 0 {
+		return nil, nil, false
+	}
+	return x0, x1, true
+}
+
+// y^2 = x^3 + 3/(9+i) on the twist
+func g2RHS(x0, x1 *big.Int) (*big.Int, *big.Int) {
+	n3ab := fpMul(fpMul(x0, x1), new(big.Int).Sub(pMod, big.NewInt(3)))
+	a3 := fpMul(fpMul(x0, x0), x0)
+	b3 := fpMul(fpMul(x1, x1), x1)
+	y0 := fpAdd(fraction27_82, fpAdd(a3, fpMul(n3ab, x1)))
+	y1 := fpNeg(fpAdd(fraction3_82, fpAdd(b3, fpMul(n3ab, x0))))
+	return y0, y1
+}
+
+func inField(xs ...*big.Int) bool {
+	for _, x := range xs {
+		if x.Sign() < 0 || x.Cmp(pMod) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func compressG1(x, y *big.Int) (*big.Int, error) {
+	if !inField(x, y) {
+		return nil, errors.New("G1 coordinate not in field")
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return new(big.Int), nil
+	}
+	yPos, ok := fpSqrt(fpAdd(fpMul(fpMul(x, x), x), big.NewInt(3)))
+	if !ok {
+		return nil, errNotOnCurve
+	}
+	c := new(big.Int).Lsh(x, 1)
+	switch {
+	case y.Cmp(yPos) == 0:
+	case y.Cmp(fpNeg(yPos)) == 0:
+		c.SetBit(c, 0, 1)
+	default:
+		return nil, errNotOnCurve
+	}
+	return c, nil
+}
+
+func decompressG1(c *big.Int) (*big.Int, *big.Int, error) {
+	if c.Sign() == 0 {
+		return new(big.Int), new(big.Int), nil
+	}
+	x := new(big.Int).Rsh(c, 1)
+	if !inField(x) {
+		return nil, nil, errors.New("G1 x not in field")
+	}
+	y, ok := fpSqrt(fpAdd(fpMul(fpMul(x, x), x), big.NewInt(3)))
+	if !ok {
+		return nil, nil, errNotOnCurve
+	}
+	if c.Bit(0) == 1 {
+		y = fpNeg(y)
+	}
+	return x, y, nil
+}
+
+func compressG2(x0, x1, y0, y1 *big.Int) (*big.Int, *big.Int, error) {
+	if !inField(x0, x1, y0, y1) {
+		return nil, nil, errors.New("G2 coefficient not in field")
+	}
+	if x0.Sign() == 0 && x1.Sign() == 0 && y0.Sign() == 0 && y1.Sign() == 0 {
+		return new(big.Int), new(big.Int), nil
+	}
+	y0Pos, y1Pos := g2RHS(x0, x1)
+	d, ok := fpSqrt(fpAdd(fpMul(y0Pos, y0Pos), fpMul(y1Pos, y1Pos)))
+	if !ok {
+		return nil, nil, errNotOnCurve
+	}
+	_, isSquare := fpSqrt(fpMul(fpAdd(y0Pos, d), fraction1_2))
+	hint := !isSquare
+
+	y0Pos, y1Pos, ok = fp2Sqrt(y0Pos, y1Pos, hint)
+	if !ok {
+		return nil, nil, errNotOnCurve
+	}
+	c0 := new(big.Int).Lsh(x0, 2)
+	if hint {
+		c0.SetBit(c0, 1, 1)
+	}
+	switch {
+	case y0.Cmp(y0Pos) == 0 && y1.Cmp(y1Pos) == 0:
+	case y0.Cmp(fpNeg(y0Pos)) == 0 && y1.Cmp(fpNeg(y1Pos)) == 0:
+		c0.SetBit(c0, 0, 1)
+	default:
+		return nil, nil, errNotOnCurve
+	}
+	return c0, new(big.Int).Set(x1), nil
+}
+
+func decompressG2(c0, c1 *big.Int) (x0, x1, y0, y1 *big.Int, err error) {
+	if c0.Sign() == 0 && c1.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int), new(big.Int), nil
+	}
+	x0 = new(big.Int).Rsh(c0, 2)
+	x1 = new(big.Int).Set(c1)
+	if !inField(x0, x1) {
+		return nil, nil, nil, nil, errors.New("G2 x not in field")
+	}
+	y0, y1 = g2RHS(x0, x1)
+	y0, y1, ok := fp2Sqrt(y0, y1, c0.Bit(1) == 1)
+	if !ok {
+		return nil, nil, nil, nil, errNotOnCurve
+	}
+	if c0.Bit(0) == 1 {
+		y0, y1 = fpNeg(y0), fpNeg(y1)
+	}
+	return x0, x1, y0, y1, nil
+}
+
+// CompressSolidityProof is compressProof of the Solidity verifier: the 8-word
+// (A, B, C) proof of SolidityProof to the 4 words taken by verifyCompressedProof.
+func CompressSolidityProof(proof [8]*big.Int) ([4]*big.Int, error) {
+	var out [4]*big.Int
+	var err error
+	if out[0], err = compressG1(proof[0], proof[1]); err != nil {
+		return out, fmt.Errorf("A: %w", err)
+	}
+	// B is (x.A1, x.A0, y.A1, y.A0) in EIP-197 order
+	if out[2], out[1], err = compressG2(proof[3], proof[2], proof[5], proof[4]); err != nil {
+		return out, fmt.Errorf("B: %w", err)
+	}
+	if out[3], err = compressG1(proof[6], proof[7]); err != nil {
+		return out, fmt.Errorf("C: %w", err)
+	}
+	return out, nil
+}
+
+// DecompressSolidityProof is the inverse of CompressSolidityProof, as done by verifyCompressedProof.
+func DecompressSolidityProof(c [4]*big.Int) ([8]*big.Int, error) {
+	var out [8]*big.Int
+	var err error
+	if out[0], out[1], err = decompressG1(c[0]); err != nil {
+		return out, fmt.Errorf("A: %w", err)
+	}
+	if out[3], out[2], out[5], out[4], err = decompressG2(c[2], c[1]); err != nil {
+		return out, fmt.Errorf("B: %w", err)
+	}
+	if out[6], out[7], err = decompressG1(c[3]); err != nil {
+		return out, fmt.Errorf("C: %w", err)
+	}
+	return out, nil
+}
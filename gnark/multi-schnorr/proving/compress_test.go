@@ -0,0 +1,175 @@
+package proving
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// constants of gnark's Solidity verifier template
+func TestCompressConstants(t *testing.T) {
+	for name, c := range map[string]struct {
+		got  *big.Int
+		want string
+	}{
+		"FRACTION_1_2_FP":   {fraction1_2, "183227397098d014dc2822db40c0ac2ecbc0b548b438e5469e10460b6c3e7ea4"},
+		"FRACTION_27_82_FP": {fraction27_82, "2b149d40ceb8aaae81be18991be06ac3b5b4c5e559dbefa33267e6dc24a138e5"},
+		"FRACTION_3_82_FP":  {fraction3_82, "2fcd3ac2a640a154eb23960892a85a68f031ca0c8344b23a577dcf1052b9e775"},
+		"EXP_SQRT_FP":       {expSqrt, "c19139cb84c680a6e14116da060561765e05aa45a1c72a34f082305b61f3f52"},
+	} {
+		if c.got.Text(16) != c.want {
+			t.Errorf("%s = %x, want %s", name, c.got, c.want)
+		}
+	}
+}
+
+func solidityWords(a bn254.G1Affine, b bn254.G2Affine, c bn254.G1Affine) [8]*big.Int {
+	var out [8]*big.Int
+	for i, e := range []interface{ BigInt(*big.Int) *big.Int }{
+		&a.X, &a.Y, &b.X.A1, &b.X.A0, &b.Y.A1, &b.Y.A0, &c.X, &c.Y,
+	} {
+		out[i] = e.BigInt(new(big.Int))
+	}
+	return out
+}
+
+func roundTrip(t *testing.T, proof [8]*big.Int) {
+	t.Helper()
+	c, err := CompressSolidityProof(proof)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	back, err := DecompressSolidityProof(c)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	for i := range proof {
+		if back[i].Cmp(proof[i]) != 0 {
+			t.Fatalf("word %d: %v after round trip, want %v", i, back[i], proof[i])
+		}
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+	for i := 0; i < 64; i++ {
+		var s1, s2, s3 fr.Element
+		s1.SetRandom()
+		s2.SetRandom()
+		s3.SetRandom()
+		var a, c bn254.G1Affine
+		var b bn254.G2Affine
+		a.ScalarMultiplication(&g1, s1.BigInt(new(big.Int)))
+		b.ScalarMultiplication(&g2, s2.BigInt(new(big.Int)))
+		c.ScalarMultiplication(&g1, s3.BigInt(new(big.Int)))
+		roundTrip(t, solidityWords(a, b, c))
+
+		// both y signs of every point
+		a.Neg(&a)
+		b.Neg(&b)
+		c.Neg(&c)
+		roundTrip(t, solidityWords(a, b, c))
+	}
+
+	var inf1 bn254.G1Affine
+	var inf2 bn254.G2Affine
+	roundTrip(t, solidityWords(inf1, inf2, g1))
+}
+
+func TestCompressRejectsOffCurve(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+	proof := solidityWords(g1, g2, g1)
+	proof[1] = new(big.Int).Add(proof[1], big.NewInt(1))
+	if _, err := CompressSolidityProof(proof); err == nil {
+		t.Fatal("A off curve accepted")
+	}
+	proof = solidityWords(g1, g2, g1)
+	proof[4] = new(big.Int).Add(proof[4], big.NewInt(1))
+	if _, err := CompressSolidityProof(proof); err == nil {
+		t.Fatal("B off curve accepted")
+	}
+	// x = 0 is not on either curve
+	if _, err := DecompressSolidityProof([4]*big.Int{big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(0)}); err == nil {
+		t.Fatal("compressed A with x = 0 accepted")
+	}
+}
+
+type cubeCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubeCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X, c.X), c.Y)
+	return nil
+}
+
+// a real proof survives compression and still verifies after decompression
+func TestCompressGroth16Proof(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, new(cubeCircuit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := rand.Int(rand.Reader, big.NewInt(1<<40))
+	y := new(big.Int).Exp(x, big.NewInt(3), nil)
+	w, err := frontend.NewWitness(&cubeCircuit{X: x, Y: y}, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &Result{Proof: proof}
+	sol, err := res.Solidity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := res.SolidityCompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := DecompressSolidityProof(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sol {
+		if back[i].Cmp(sol[i]) != 0 {
+			t.Fatalf("word %d differs after round trip", i)
+		}
+	}
+
+	// the 8 words are the head of gnark's raw proof encoding
+	var raw bytes.Buffer
+	if _, err := proof.WriteRawTo(&raw); err != nil {
+		t.Fatal(err)
+	}
+	enc := raw.Bytes()
+	for i, word := range back {
+		word.FillBytes(enc[32*i : 32*(i+1)])
+	}
+	decoded := groth16.NewProof(ecc.BN254)
+	if _, err := decoded.ReadFrom(bytes.NewReader(enc)); err != nil {
+		t.Fatal(err)
+	}
+	pubW, err := w.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(decoded, vk, pubW); err != nil {
+		t.Fatalf("decompressed proof does not verify: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package proving
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSigners is wrapped by Prove when a signer index is out of range,
+// repeated, or points at a padding slot without a secret key.
+var ErrInvalidSigners = errors.New("invalid signer set")
+
+// ErrInvalidSignature is wrapped by ProveBundle when a collected signature does not verify.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Op is the step of loading or proving that failed.
+type Op string
+
+const (
+	OpLoadCS    Op = "load constraint system"
+	OpLoadPK    Op = "load proving key"
+	OpLoadVK    Op = "load verifying key"
+	OpLoadKeys  Op = "load keys"
+	OpWitness   Op = "build witness"
+	OpProve     Op = "prove"
+	OpVerify    Op = "verify"
+	OpEncode    Op = "encode output"
+	OpCancelled Op = "cancelled"
+)
+
+// Error is returned by every exported function of this package.
+// Use errors.As to read Op, and errors.Is on the wrapped error
+// (ErrInvalidSigners, ErrInvalidSignature, context.Canceled, os.ErrNotExist, ...).
+type Error struct {
+	Op   Op
+	Path string // artifact or key file, if any
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
@@ -0,0 +1,94 @@
+package proving
+
+import (
+	"context"
+	"io"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// KeySource provides the validator registry (MaxK keys, padded).
+type KeySource interface {
+	LoadKeys(ctx context.Context) ([]utils.KeyPair, error)
+}
+
+// KeyFile is a registry in the keys.json format.
+type KeyFile string
+
+func (f KeyFile) LoadKeys(ctx context.Context) ([]utils.KeyPair, error) {
+	return utils.LoadKeysFrom(string(f))
+}
+
+// StaticKeys is a registry already held in memory.
+type StaticKeys []utils.KeyPair
+
+func (k StaticKeys) LoadKeys(ctx context.Context) ([]utils.KeyPair, error) {
+	return k, nil
+}
+
+// HashToField maps the signed message to the Message public input.
+// It must match the verifying contract (keccak256 mod r for MultiSchnorrVerifier).
+type HashToField func(msg []byte) fr.Element
+
+// OutputFormat selects what Result.WriteTo writes.
+type OutputFormat int
+
+const (
+	// proof.json: {"proof": [8 uint256], "compressedProof": [4 uint256], "input": [...], "messageHex": "0x..."}
+	FormatSolidityJSON OutputFormat = iota
+	// gnark's raw (uncompressed) proof encoding
+	FormatRaw
+)
+
+type config struct {
+	keys        KeySource
+	hashToField HashToField
+	format      OutputFormat
+	verify      bool
+	rng         io.Reader
+}
+
+func defaultConfig() config {
+	return config{
+		keys:        KeyFile(utils.DefaultKeyPath()),
+		hashToField: utils.KeccakToFr,
+		format:      FormatSolidityJSON,
+		verify:      true,
+	}
+}
+
+type Option func(*config)
+
+// WithKeySource sets where the registry is read from (default: keys.json at the repo root).
+func WithKeySource(src KeySource) Option {
+	return func(c *config) { c.keys = src }
+}
+
+func WithKeyFile(path string) Option {
+	return WithKeySource(KeyFile(path))
+}
+
+func WithKeys(keys []utils.KeyPair) Option {
+	return WithKeySource(StaticKeys(keys))
+}
+
+// WithHashToField replaces the default keccak256 mod r message reduction.
+func WithHashToField(h HashToField) Option {
+	return func(c *config) { c.hashToField = h }
+}
+
+func WithOutputFormat(f OutputFormat) Option {
+	return func(c *config) { c.format = f }
+}
+
+// WithLocalVerify turns the check of every proof against the VK on or off (default on).
+func WithLocalVerify(verify bool) Option {
+	return func(c *config) { c.verify = verify }
+}
+
+// WithRand sets the randomness used for signing nonces (default crypto/rand).
+func WithRand(rng io.Reader) Option {
+	return func(c *config) { c.rng = rng }
+}
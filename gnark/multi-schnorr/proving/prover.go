@@ -0,0 +1,388 @@
+// Package proving generates multi-schnorr Groth16 proofs from Go code.
+//
+// A Prover loads the constraint system, keys and validator registry once,
+// then proves any number of (message, signers) pairs:
+//
+//	p, err := proving.New(ctx, proving.DefaultArtifacts(), proving.WithKeyFile("keys.json"))
+//	res, err := p.Prove(ctx, []byte("hello"), []int{0, 1, 2})
+//	_, err = res.WriteTo(f) // proof.json
+package proving
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Artifacts are the files written by setup for Circuit (or OptimizedCircuit,
+// which has the same witness layout). VK may be empty with WithLocalVerify(false);
+// if set, it is loaded anyway so that Verify can be called on demand.
+type Artifacts struct {
+	CS string
+	PK string
+	VK string
+}
+
+// files written by `go run .` in setup/
+func DefaultArtifacts() Artifacts {
+	return Artifacts{
+		CS: utils.RepoPath("../circuit.r1cs"),
+		PK: utils.RepoPath("../setup/multischnorr.g16.pk"),
+		VK: utils.RepoPath("../setup/multischnorr.g16.vk"),
+	}
+}
+
+type Prover struct {
+	cfg  config
+	cs   constraint.ConstraintSystem
+	pk   groth16.ProvingKey
+	vk   groth16.VerifyingKey
+	keys []utils.KeyPair
+	root fr.Element
+}
+
+type PublicInputs struct {
+	Root     *big.Int
+	Message  *big.Int
+	SumValid *big.Int
+}
+
+type Result struct {
+	Proof   groth16.Proof
+	Public  PublicInputs
+	Message []byte // signed message, before hash-to-field
+	format  OutputFormat
+}
+
+func New(ctx context.Context, art Artifacts, opts ...Option) (*Prover, error) {
+	p := &Prover{cfg: defaultConfig()}
+	for _, o := range opts {
+		o(&p.cfg)
+	}
+
+	p.cs = groth16.NewCS(ecc.BN254)
+	if err := load(ctx, OpLoadCS, art.CS, p.cs); err != nil {
+		return nil, err
+	}
+	p.pk = groth16.NewProvingKey(ecc.BN254)
+	if err := load(ctx, OpLoadPK, art.PK, p.pk); err != nil {
+		return nil, err
+	}
+	if p.cfg.verify || art.VK != "" {
+		p.vk = groth16.NewVerifyingKey(ecc.BN254)
+		if err := load(ctx, OpLoadVK, art.VK, p.vk); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, &Error{Op: OpCancelled, Err: err}
+	}
+	keys, err := p.cfg.keys.LoadKeys(ctx)
+	if err != nil {
+		return nil, &Error{Op: OpLoadKeys, Path: keySourcePath(p.cfg.keys), Err: err}
+	}
+	if len(keys) != multischnorr.MaxK {
+		return nil, &Error{Op: OpLoadKeys, Path: keySourcePath(p.cfg.keys),
+			Err: fmt.Errorf("registry has %d keys, expected maxK=%d", len(keys), multischnorr.MaxK)}
+	}
+	p.keys = keys
+	if p.root, _, err = utils.BuildRoot(keys); err != nil {
+		return nil, &Error{Op: OpLoadKeys, Err: err}
+	}
+	return p, nil
+}
+
+// Merkle root of the loaded registry
+func (p *Prover) Root() fr.Element { return p.root }
+
+// Prove signs msg with the registry keys of signers and proves the signatures.
+// Cancelling ctx returns early; a running groth16.Prove finishes in the background.
+func (p *Prover) Prove(ctx context.Context, msg []byte, signers []int) (*Result, error) {
+	if err := p.checkSigners(signers); err != nil {
+		return nil, &Error{Op: OpWitness, Err: err}
+	}
+
+	message := p.cfg.hashToField(msg)
+	wd, err := utils.PrepareWitnessData(p.keys, signers, message, p.cfg.rng, nil)
+	if err != nil {
+		return nil, &Error{Op: OpWitness, Err: err}
+	}
+	return p.prove(ctx, msg, wd.Message, wd.Candidates, wd.SumValid)
+}
+
+// ProveBundle proves the signatures collected in b, so no secret key is needed.
+// Every signature must carry the registry key at its index (ErrInvalidSigners)
+// and verify against the bundle message (ErrInvalidSignature).
+func (p *Prover) ProveBundle(ctx context.Context, b utils.SignatureBundle) (*Result, error) {
+	msg, err := b.Message()
+	if err != nil {
+		return nil, &Error{Op: OpWitness, Err: err}
+	}
+	message := p.cfg.hashToField(msg)
+	cands, sumValid, err := p.bundleCandidates(b, message)
+	if err != nil {
+		return nil, &Error{Op: OpWitness, Err: err}
+	}
+	return p.prove(ctx, msg, message, cands, sumValid)
+}
+
+// every registry slot ignored, then the bundle signatures filled in
+func (p *Prover) bundleCandidates(b utils.SignatureBundle, message fr.Element) ([]utils.Candidate, int, error) {
+	cands, _, err := utils.BuildCandidates(p.keys, nil, message, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	seen := make(map[int]bool, len(b.Signatures))
+	for _, bs := range b.Signatures {
+		i := bs.Index
+		if i < 0 || i >= len(p.keys) {
+			return nil, 0, fmt.Errorf("%w: index %d out of range [0,%d)", ErrInvalidSigners, i, len(p.keys))
+		}
+		if seen[i] {
+			return nil, 0, fmt.Errorf("%w: index %d listed twice", ErrInvalidSigners, i)
+		}
+		seen[i] = true
+		pub, sig, err := bs.Decode()
+		if err != nil {
+			return nil, 0, err
+		}
+		if pub.Ax.Cmp(p.keys[i].Pub.Ax) != 0 || pub.Ay.Cmp(p.keys[i].Pub.Ay) != 0 {
+			return nil, 0, fmt.Errorf("%w: index %d signed with a key that is not the registry key", ErrInvalidSigners, i)
+		}
+		if !utils.Verify(pub, message, sig) {
+			return nil, 0, fmt.Errorf("%w: index %d", ErrInvalidSignature, i)
+		}
+		cands[i].Sig = sig
+		cands[i].IsIgnore = 0
+	}
+	return cands, len(seen), nil
+}
+
+func (p *Prover) prove(ctx context.Context, msg []byte, message fr.Element, cands []utils.Candidate, sumValid int) (*Result, error) {
+	assignment := new(multischnorr.Circuit)
+	assignment.Root = p.root.BigInt(new(big.Int))
+	assignment.Message = message.BigInt(new(big.Int))
+	assignment.SumValid = big.NewInt(int64(sumValid))
+	for i := 0; i < multischnorr.MaxK; i++ {
+		c := cands[i]
+		assignment.S[i].Ax = c.Ax
+		assignment.S[i].Ay = c.Ay
+		assignment.S[i].Sig.Rx = c.Sig.Rx
+		assignment.S[i].Sig.Ry = c.Sig.Ry
+		assignment.S[i].Sig.S = c.Sig.S
+		assignment.S[i].IsIgnore = big.NewInt(int64(c.IsIgnore))
+	}
+
+	fullW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, &Error{Op: OpWitness, Err: err}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &Error{Op: OpCancelled, Err: err}
+	}
+
+	type proved struct {
+		proof groth16.Proof
+		err   error
+	}
+	done := make(chan proved, 1)
+	go func() {
+		proof, err := groth16.Prove(p.cs, p.pk, fullW)
+		done <- proved{proof, err}
+	}()
+
+	var proof groth16.Proof
+	select {
+	case <-ctx.Done():
+		return nil, &Error{Op: OpCancelled, Err: ctx.Err()}
+	case r := <-done:
+		if r.err != nil {
+			return nil, &Error{Op: OpProve, Err: r.err}
+		}
+		proof = r.proof
+	}
+
+	res := &Result{
+		Proof: proof,
+		Public: PublicInputs{
+			Root:     assignment.Root.(*big.Int),
+			Message:  assignment.Message.(*big.Int),
+			SumValid: assignment.SumValid.(*big.Int),
+		},
+		Message: msg,
+		format:  p.cfg.format,
+	}
+
+	if p.cfg.verify {
+		if err := p.Verify(res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Verify checks res against the loaded VK.
+func (p *Prover) Verify(res *Result) error {
+	if p.vk == nil {
+		return &Error{Op: OpVerify, Err: fmt.Errorf("no verifying key loaded")}
+	}
+	assignment := &multischnorr.Circuit{
+		Root:     res.Public.Root,
+		Message:  res.Public.Message,
+		SumValid: res.Public.SumValid,
+	}
+	pubW, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return &Error{Op: OpVerify, Err: err}
+	}
+	if err := groth16.Verify(res.Proof, p.vk, pubW); err != nil {
+		return &Error{Op: OpVerify, Err: err}
+	}
+	return nil
+}
+
+func (p *Prover) checkSigners(signers []int) error {
+	seen := make(map[int]bool, len(signers))
+	for _, i := range signers {
+		if i < 0 || i >= len(p.keys) {
+			return fmt.Errorf("%w: index %d out of range [0,%d)", ErrInvalidSigners, i, len(p.keys))
+		}
+		if seen[i] {
+			return fmt.Errorf("%w: index %d listed twice", ErrInvalidSigners, i)
+		}
+		if p.keys[i].Priv.Sk == nil || p.keys[i].Priv.Sk.Sign() == 0 {
+			return fmt.Errorf("%w: index %d has no secret key", ErrInvalidSigners, i)
+		}
+		seen[i] = true
+	}
+	return nil
+}
+
+// Solidity returns the proof as the uint256[8] (A, B, C) taken by the verifier contract.
+func (r *Result) Solidity() ([8]*big.Int, error) {
+	return SolidityProof(r.Proof)
+}
+
+// SolidityCompressed returns the proof as the uint256[4] taken by verifyCompressedProof.
+func (r *Result) SolidityCompressed() ([4]*big.Int, error) {
+	sol, err := r.Solidity()
+	if err != nil {
+		return [4]*big.Int{}, err
+	}
+	return CompressSolidityProof(sol)
+}
+
+// 0x-prefixed hex of the signed message, as passed to MultiSchnorrVerifier.verify
+func (r *Result) MessageHex() string {
+	return "0x" + hex.EncodeToString(r.Message)
+}
+
+// Inputs in public witness order: Root, Message, SumValid
+func (r *Result) Inputs() []*big.Int {
+	return []*big.Int{r.Public.Root, r.Public.Message, r.Public.SumValid}
+}
+
+// WriteTo writes the proof in the format selected with WithOutputFormat.
+func (r *Result) WriteTo(w io.Writer) (int64, error) {
+	switch r.format {
+	case FormatRaw:
+		n, err := r.Proof.WriteRawTo(w)
+		if err != nil {
+			return n, &Error{Op: OpEncode, Err: err}
+		}
+		return n, nil
+	case FormatSolidityJSON:
+		sol, err := r.Solidity()
+		if err != nil {
+			return 0, &Error{Op: OpEncode, Err: err}
+		}
+		compressed, err := CompressSolidityProof(sol)
+		if err != nil {
+			return 0, &Error{Op: OpEncode, Err: err}
+		}
+		data, err := json.MarshalIndent(struct {
+			Proof           [8]*big.Int `json:"proof"`
+			CompressedProof [4]*big.Int `json:"compressedProof"`
+			Input           []*big.Int  `json:"input"`
+			MessageHex      string      `json:"messageHex"`
+		}{sol, compressed, r.Inputs(), r.MessageHex()}, "", "  ")
+		if err != nil {
+			return 0, &Error{Op: OpEncode, Err: err}
+		}
+		n, err := w.Write(append(data, '\n'))
+		if err != nil {
+			return int64(n), &Error{Op: OpEncode, Err: err}
+		}
+		return int64(n), nil
+	default:
+		return 0, &Error{Op: OpEncode, Err: fmt.Errorf("unknown output format %d", r.format)}
+	}
+}
+
+// SolidityProof splits gnark's raw BN254 proof encoding into (A, B, C) coordinates.
+func SolidityProof(proof groth16.Proof) ([8]*big.Int, error) {
+	var out [8]*big.Int
+	var buf bytes.Buffer
+	if _, err := proof.WriteRawTo(&buf); err != nil {
+		return out, fmt.Errorf("WriteRawTo: %w", err)
+	}
+	proofBytes := buf.Bytes()
+
+	const fpSize = 32
+	if len(proofBytes) < 8*fpSize {
+		return out, fmt.Errorf("raw proof too small: %d bytes, expected at least %d", len(proofBytes), 8*fpSize)
+	}
+	for i := range out {
+		out[i] = new(big.Int).SetBytes(proofBytes[fpSize*i : fpSize*(i+1)])
+	}
+	return out, nil
+}
+
+// ParseMessage reads a CLI message: 0x-prefixed hex if it decodes, the raw string otherwise.
+func ParseMessage(s string) []byte {
+	if strings.HasPrefix(s, "0x") {
+		if decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil {
+			return decoded
+		}
+	}
+	return []byte(s)
+}
+
+func load(ctx context.Context, op Op, path string, r io.ReaderFrom) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{Op: OpCancelled, Err: err}
+	}
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return &Error{Op: op, Path: path, Err: err}
+	}
+	defer f.Close()
+	if _, err := r.ReadFrom(f); err != nil {
+		return &Error{Op: op, Path: path, Err: err}
+	}
+	return nil
+}
+
+func keySourcePath(src KeySource) string {
+	if f, ok := src.(KeyFile); ok {
+		return string(f)
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package proving
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+func TestNewErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "circuit.r1cs")
+
+	_, err := New(context.Background(), Artifacts{CS: missing})
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Op != OpLoadCS || perr.Path != missing {
+		t.Fatalf("expected %s error for %s, got %v", OpLoadCS, missing, err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist to be wrapped, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = New(ctx, Artifacts{CS: missing})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	for in, want := range map[string]string{
+		"hello":  "hello",
+		"0x6869": "hi",
+		"0xzz":   "0xzz", // not hex, kept as is
+	} {
+		if got := string(ParseMessage(in)); got != want {
+			t.Errorf("ParseMessage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBundleCandidates(t *testing.T) {
+	keys, err := utils.GeneratePaddedKeyPairs(3, multischnorr.Depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Prover{cfg: defaultConfig(), keys: keys}
+
+	msg := []byte("epoch 9")
+	message := p.cfg.hashToField(msg)
+	bundle := func(signers ...int) utils.SignatureBundle {
+		b := utils.SignatureBundle{MessageHex: "0x" + hex.EncodeToString(msg)}
+		for _, i := range signers {
+			sig, err := utils.Sign(keys[i].Priv.Sk, keys[i].Pub, message, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Signatures = append(b.Signatures, utils.NewBundleSignature(i, keys[i].Pub, sig))
+		}
+		return b
+	}
+
+	cands, sum, err := p.bundleCandidates(bundle(0, 2), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 2 || cands[0].IsIgnore != 0 || cands[1].IsIgnore != 1 || cands[2].IsIgnore != 0 {
+		t.Fatalf("sum %d, IsIgnore %d %d %d", sum, cands[0].IsIgnore, cands[1].IsIgnore, cands[2].IsIgnore)
+	}
+
+	for name, tc := range map[string]struct {
+		edit func(*utils.SignatureBundle)
+		want error
+	}{
+		"signed another message": {func(b *utils.SignatureBundle) { b.MessageHex = "0x00" }, ErrInvalidSignature},
+		"S changed": {func(b *utils.SignatureBundle) {
+			b.Signatures[0].S = new(big.Int).Add(big.NewInt(1), mustHex(t, b.Signatures[0].S)).Text(16)
+		}, ErrInvalidSignature},
+		"key of another slot": {func(b *utils.SignatureBundle) { b.Signatures[0].Index = 1 }, ErrInvalidSigners},
+		"listed twice":        {func(b *utils.SignatureBundle) { b.Signatures[1] = b.Signatures[0] }, ErrInvalidSigners},
+		"out of range":        {func(b *utils.SignatureBundle) { b.Signatures[0].Index = len(keys) }, ErrInvalidSigners},
+	} {
+		b := bundle(0, 2)
+		tc.edit(&b)
+		m, err := b.Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := p.bundleCandidates(b, p.cfg.hashToField(m)); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %q", s)
+	}
+	return n
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -16,15 +17,68 @@ import (
 	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
 )
 
+// output file names per circuit variant
+type target struct {
+	circuit frontend.Circuit
+	r1cs    string
+	pk      string
+	vk      string
+	solDir  string
+}
+
+var targets = map[string]target{
+	"single": {
+		circuit: &multischnorr.Circuit{},
+		r1cs:    "../circuit.r1cs",
+		pk:      "multischnorr.g16.pk",
+		vk:      "multischnorr.g16.vk",
+		solDir:  "../contract/src/",
+	},
+	"committee": {
+		circuit: &multischnorr.CommitteeCircuit{},
+		r1cs:    "../circuit_committee.r1cs",
+		pk:      "multischnorr_committee.g16.pk",
+		vk:      "multischnorr_committee.g16.vk",
+		solDir:  "../contract/src/committee/",
+	},
+	"optimized": {
+		circuit: &multischnorr.OptimizedCircuit{},
+		r1cs:    "../circuit_optimized.r1cs",
+		pk:      "multischnorr_optimized.g16.pk",
+		vk:      "multischnorr_optimized.g16.vk",
+		solDir:  "../contract/src/optimized/",
+	},
+	"musig": {
+		circuit: &multischnorr.MuSigCircuit{},
+		r1cs:    "../circuit_musig.r1cs",
+		pk:      "multischnorr_musig.g16.pk",
+		vk:      "multischnorr_musig.g16.vk",
+		solDir:  "../contract/src/musig/",
+	},
+	"multiroot": {
+		circuit: &multischnorr.MultiRootCircuit{},
+		r1cs:    "../circuit_multiroot.r1cs",
+		pk:      "multischnorr_multiroot.g16.pk",
+		vk:      "multischnorr_multiroot.g16.vk",
+		solDir:  "../contract/src/multiroot/",
+	},
+}
+
 func main() {
-	if err := run(); err != nil {
+	name := flag.String("circuit", "single", "circuit variant: single | optimized | musig | multiroot | committee")
+	flag.Parse()
+
+	t, ok := targets[*name]
+	if !ok {
+		log.Fatalf("unknown circuit %q", *name)
+	}
+	if err := run(t); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run() error {
-	var circuit multischnorr.Circuit
-	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit)
+func run(t target) error {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, t.circuit)
 	if err != nil {
 		return fmt.Errorf("compile: %w", err)
 	}
@@ -35,10 +89,10 @@ func run() error {
 		internal+secret+public, internal, secret, public)
 	fmt.Println("Writing circuit.r1cs...")
 
-	r1cspath := repoPath("../circuit.r1cs")
-	vkPath := "multischnorr.g16.vk"
-	pkPath := "multischnorr.g16.pk"
-	outDir := repoPath("../contract/src/")
+	r1cspath := repoPath(t.r1cs)
+	vkPath := t.vk
+	pkPath := t.pk
+	outDir := repoPath(t.solDir)
 	outPath := filepath.Join(outDir, "Verifier.sol")
 
 	if err := writetoPath(r1cspath, func(f *os.File) error {
@@ -84,8 +138,8 @@ func run() error {
 	}
 
 	fmt.Println("Wrote:", outPath)
-	fmt.Println("Wrote: multischnorr.g16.vk")
-	fmt.Println("Wrote: multischnorr.g16.pk")
+	fmt.Println("Wrote:", vkPath)
+	fmt.Println("Wrote:", pkPath)
 	return nil
 }
 
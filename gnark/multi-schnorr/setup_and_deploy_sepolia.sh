@@ -10,11 +10,12 @@ Usage:
     --rpc-url https://sepolia.rpc.url \
     --threshold <uint256> \
     [--merkle-root <uint256-or-0xhex>] \
+    [--compressed] \
     --etherscan-api-key ETHERSCAN_API_KEY
 EOF
 }
 
-PK="" RPC_URL="" THRESHOLD="" MERKLE_ROOT="" ETHERSCAN_API_KEY=""
+PK="" RPC_URL="" THRESHOLD="" MERKLE_ROOT="" ETHERSCAN_API_KEY="" PROOF_FORMAT="uncompressed"
 
 while [[ $# -gt 0 ]]; do
   case "$1" in
@@ -23,12 +24,13 @@ while [[ $# -gt 0 ]]; do
     --threshold)     THRESHOLD="$2"; shift 2 ;;
     --merkle-root)   MERKLE_ROOT="$2"; shift 2 ;;
     --etherscan-api-key) ETHERSCAN_API_KEY="$2"; shift 2 ;;
+    --compressed)    PROOF_FORMAT="compressed"; shift ;;
     *) echo "Unknown arg: $1"; exit 1 ;;
   esac
 done
 
 [[ -n "$PK" && -n "$RPC_URL" && -n "$THRESHOLD" && -n "$ETHERSCAN_API_KEY" ]] || {
-  echo "Usage: $0 --private-key <0xPK> --rpc-url <URL> --threshold <uint> [--merkle-root <uint|0xhex>] --etherscan-api-key <KEY>"
+  echo "Usage: $0 --private-key <0xPK> --rpc-url <URL> --threshold <uint> [--merkle-root <uint|0xhex>] [--compressed] --etherscan-api-key <KEY>"
   exit 1
 }
 
@@ -61,49 +63,32 @@ pushd "$CONTRACT_DIR" >/dev/null
   echo ">> forge build"
   forge build
 
-  echo ">> Deploying to Sepolia..."
-  DEPLOY_OUTPUT=$(forge script script/DeployMultiSchnorrVerifier.s.sol:DeployMultischnorr \
-      --rpc-url "$RPC_URL" \
-      --private-key "$PK" \
-      --broadcast \
-      --sig "run(uint256,uint256)" "$THRESHOLD" "$MERKLE_ROOT" \
-      --etherscan-api-key "$ETHERSCAN_API_KEY" \
-      --verify \
-      -vvvv 2>&1)
-
-echo "$DEPLOY_OUTPUT"   
-
-VERIFIER_ADDR=$(echo "$DEPLOY_OUTPUT" | grep "Verifier deployed at:" | grep -oE "0x[a-fA-F0-9]{40}" | head -1)
-MULTISCHNORR_ADDR=$(echo "$DEPLOY_OUTPUT" | grep "MultischnorrVerifier deployed at:" | grep -oE "0x[a-fA-F0-9]{40}" | head -1)
-OWNER_ADDR=$(echo "$DEPLOY_OUTPUT" | grep "owner:" | grep -oE "0x[a-fA-F0-9]{40}" | head -1)
+popd >/dev/null
 
-  if [[ -z "$VERIFIER_ADDR" || -z "$MULTISCHNORR_ADDR" ]]; then
-    echo "Warning: Could not extract contract addresses from deployment output."
-    echo "Please manually create $DEPLOYMENT_FILE with the following structure:"
-    cat <<EOF
-{
-  "network": "sepolia",
-  "verifier": "0x...",
-  "multiSchnorrVerifier": "0x...",
-  "owner": "0x...",
-  "deployedAt": "$(date -u +%Y-%m-%dT%H:%M:%SZ)"
-}
-EOF
-  else
-    # Save deployment info to JSON file
-    cat > "$DEPLOYMENT_FILE" <<EOF
-{
-  "network": "sepolia",
-  "verifier": "$VERIFIER_ADDR",
-  "multiSchnorrVerifier": "$MULTISCHNORR_ADDR",
-  "owner": "$OWNER_ADDR",
-  "deployedAt": "$(date -u +%Y-%m-%dT%H:%M:%SZ)"
-}
-EOF
+echo ">> Deploying to Sepolia..."
+pushd "$ROOT" >/dev/null
+  go run ./onchain deploy \
+    --rpc-url "$RPC_URL" \
+    --private-key "$PK" \
+    --threshold "$THRESHOLD" \
+    --merkle-root "$MERKLE_ROOT" \
+    --network sepolia \
+    --proof-format "$PROOF_FORMAT" \
+    --deployment "$DEPLOYMENT_FILE"
+popd >/dev/null
 
-echo ">> Deployment info saved to $DEPLOYMENT_FILE"
-  fi
+VERIFIER_ADDR="$(jq -r '.verifier' "$DEPLOYMENT_FILE")"
+MULTISCHNORR_ADDR="$(jq -r '.multiSchnorrVerifier' "$DEPLOYMENT_FILE")"
+OWNER_ADDR="$(jq -r '.owner' "$DEPLOYMENT_FILE")"
 
+echo ">> Verifying sources on Etherscan..."
+pushd "$CONTRACT_DIR" >/dev/null
+  forge verify-contract --chain sepolia --etherscan-api-key "$ETHERSCAN_API_KEY" \
+    "$VERIFIER_ADDR" src/Verifier.sol:Verifier
+  forge verify-contract --chain sepolia --etherscan-api-key "$ETHERSCAN_API_KEY" \
+    --constructor-args "$(cast abi-encode "constructor(address,uint256,uint256,address)" \
+      "$VERIFIER_ADDR" "$THRESHOLD" "$MERKLE_ROOT" "$OWNER_ADDR")" \
+    "$MULTISCHNORR_ADDR" src/MultiSchnorrVerifier.sol:MultiSchnorrVerifier
 popd >/dev/null
 
 echo ">> Done."
@@ -0,0 +1,208 @@
+package multischnorr_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+// Witnesses a prover could try to get past Circuit (and OptimizedCircuit), on the
+// 4-slot registry of batch_test.go: signers 0..2 and a padding slot 3.
+//
+// By default gnark's assert runs the test engine and the R1CS solver; with
+// -tags prover_checks it also runs the Groth16 setup, prover and verifier per case.
+// TestSoundnessGroth16 proves every case once with a single setup.
+
+type soundnessCase struct {
+	name  string
+	valid bool
+	edit  func(f *batchFixture, a *multischnorr.SizedCircuit)
+}
+
+func garbageSig() multischnorr.SchnorrSignature {
+	return multischnorr.SchnorrSignature{
+		Rx: big.NewInt(12345),
+		Ry: big.NewInt(678),
+		S:  new(big.Int).Lsh(big.NewInt(1), 253),
+	}
+}
+
+var soundnessCases = []soundnessCase{
+	{"honest", true, nil},
+	{"forged S", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[1].Sig.S = new(big.Int).Add(f.cands[1].Sig.S, big.NewInt(1))
+	}},
+	{"wrong message", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.Message = new(big.Int).Add(f.msg.BigInt(new(big.Int)), big.NewInt(1))
+	}},
+	{"signatures over another message", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		other := f.msg
+		other.SetUint64(8)
+		cands, _, err := utils.BuildCandidates(f.keys, []int{0, 1, 2}, other, nil, nil)
+		if err != nil {
+			panic(err)
+		}
+		a.S[0].Sig = multischnorr.SchnorrSignature{Rx: cands[0].Sig.Rx, Ry: cands[0].Sig.Ry, S: cands[0].Sig.S}
+	}},
+	{"key not under root", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		// a correct signature, by a key the registry does not hold
+		kp, err := utils.GenerateKeyPairs(1)
+		if err != nil {
+			panic(err)
+		}
+		sig, err := utils.Sign(kp[0].Priv.Sk, kp[0].Pub, f.msg, nil, nil)
+		if err != nil {
+			panic(err)
+		}
+		a.S[0] = multischnorr.Candidate{
+			Ax: kp[0].Pub.Ax, Ay: kp[0].Pub.Ay,
+			Sig:      multischnorr.SchnorrSignature{Rx: sig.Rx, Ry: sig.Ry, S: sig.S},
+			IsIgnore: 0,
+		}
+	}},
+	{"IsIgnore = 2", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[0].IsIgnore = 2
+	}},
+	{"IsIgnore = -1 on padding", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		// active = 2 would count the padding slot twice if IsIgnore were not boolean
+		a.S[3].IsIgnore = -1
+	}},
+	{"SumValid + 1", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.SumValid = f.sum + 1
+	}},
+	{"SumValid - 1", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.SumValid = f.sum - 1
+	}},
+	{"R off curve", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[2].Sig.Ry = new(big.Int).Add(f.cands[2].Sig.Ry, big.NewInt(1))
+	}},
+	{"padding slot claimed", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[3].IsIgnore = 0
+		a.SumValid = f.sum + 1
+	}},
+	{"garbage in padding slot", true, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[3].Sig = garbageSig()
+	}},
+	{"garbage in ignored signer slot", true, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[2].Sig = garbageSig()
+		a.S[2].IsIgnore = 1
+		a.SumValid = f.sum - 1
+	}},
+	{"R at identity in ignored slot", true, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		a.S[1].Sig = multischnorr.SchnorrSignature{Rx: 0, Ry: 1, S: 0}
+		a.S[1].IsIgnore = 1
+		a.SumValid = f.sum - 1
+	}},
+	{"garbage key in ignored slot", false, func(f *batchFixture, a *multischnorr.SizedCircuit) {
+		// ignored slots still hash their key into the root
+		a.S[3].Ax, a.S[3].Ay = big.NewInt(1), big.NewInt(2)
+	}},
+}
+
+func soundnessAssignment(f *batchFixture, c soundnessCase, optimized bool) *multischnorr.SizedCircuit {
+	b := f.assignment()
+	a := multischnorr.NewSizedCircuit(batchDepth, optimized)
+	a.Root, a.Message, a.SumValid = b.Root, b.Message, b.SumValid
+	copy(a.S, b.S)
+	if c.edit != nil {
+		c.edit(f, a)
+	}
+	return a
+}
+
+func TestSoundness(t *testing.T) {
+	f := newBatchFixture(t)
+	for _, optimized := range []bool{false, true} {
+		name := "current"
+		if optimized {
+			name = "optimized"
+		}
+		for _, c := range soundnessCases {
+			t.Run(name+"/"+c.name, func(t *testing.T) {
+				assert := test.NewAssert(t)
+				circuit := multischnorr.NewSizedCircuit(batchDepth, optimized)
+				a := soundnessAssignment(f, c, optimized)
+				opts := []test.TestingOption{
+					test.WithCurves(ecc.BN254),
+					test.WithBackends(backend.GROTH16),
+					test.NoFuzzing(),
+					test.NoSerializationChecks(),
+				}
+				if c.valid {
+					assert.ProverSucceeded(circuit, a, opts...)
+				} else {
+					assert.ProverFailed(circuit, a, opts...)
+				}
+			})
+		}
+	}
+}
+
+// every case through groth16.Prove, sharing one setup; valid proofs must verify
+func TestSoundnessGroth16(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Groth16 setup")
+	}
+	f := newBatchFixture(t)
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, multischnorr.NewSizedCircuit(batchDepth, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range soundnessCases {
+		t.Run(c.name, func(t *testing.T) {
+			w, err := frontend.NewWitness(soundnessAssignment(f, c, false), ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := groth16.Prove(ccs, pk, w)
+			if !c.valid {
+				if err == nil {
+					t.Fatal("proof generated for an invalid witness")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("prove: %v", err)
+			}
+			pubW, err := w.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := groth16.Verify(proof, vk, pubW); err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+		})
+	}
+
+	// a valid proof does not verify against another SumValid
+	w, err := frontend.NewWitness(soundnessAssignment(f, soundnessCases[0], false), ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := soundnessAssignment(f, soundnessCases[0], false)
+	other.SumValid = f.sum + 1
+	pubW, err := frontend.NewWitness(other, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groth16.Verify(proof, vk, pubW) == nil {
+		t.Fatal("proof verified with SumValid + 1")
+	}
+}
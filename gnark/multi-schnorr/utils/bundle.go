@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+// signatures collected independently from validators for one (epoch, topic) slot
+// the archive is a directory holding one bundle per JSON file
+type SignatureBundle struct {
+	Epoch      uint64            `json:"epoch"`
+	Topic      string            `json:"topic"`
+	MessageHex string            `json:"messageHex"` // raw message bytes, as passed to the contract
+	Signatures []BundleSignature `json:"signatures"`
+}
+
+// hex encoded like keys.json (with or without 0x prefix)
+type BundleSignature struct {
+	Index int    `json:"index"` // validator position in keys.json / Merkle tree
+	Ax    string `json:"ax"`
+	Ay    string `json:"ay"`
+	Rx    string `json:"rx"`
+	Ry    string `json:"ry"`
+	S     string `json:"s"`
+}
+
+func (b SignatureBundle) Message() ([]byte, error) {
+	msg, err := hex.DecodeString(strings.TrimPrefix(b.MessageHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("bad messageHex: %w", err)
+	}
+	return msg, nil
+}
+
+func (s BundleSignature) Decode() (PubKey, SchnorrSignature, error) {
+	var vals [5]*big.Int
+	for i, v := range []string{s.Ax, s.Ay, s.Rx, s.Ry, s.S} {
+		bi, ok := new(big.Int).SetString(strings.TrimPrefix(v, "0x"), 16)
+		if !ok {
+			return PubKey{}, SchnorrSignature{}, fmt.Errorf("bad hex value %q for validator %d", v, s.Index)
+		}
+		vals[i] = bi
+	}
+	return PubKey{Ax: vals[0], Ay: vals[1]}, SchnorrSignature{Rx: vals[2], Ry: vals[3], S: vals[4]}, nil
+}
+
+func NewBundleSignature(index int, pub PubKey, sig SchnorrSignature) BundleSignature {
+	return BundleSignature{
+		Index: index,
+		Ax:    pub.Ax.Text(16),
+		Ay:    pub.Ay.Text(16),
+		Rx:    sig.Rx.Text(16),
+		Ry:    sig.Ry.Text(16),
+		S:     sig.S.Text(16),
+	}
+}
+
+// reads every *.json bundle in dir, in file name order
+func LoadBundles(dir string) ([]SignatureBundle, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	out := make([]SignatureBundle, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f, err)
+		}
+		var b SignatureBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", f, err)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// keccak256(m) mod r, same reduction as MultiSchnorrVerifier.keccakToFr
+func KeccakToFr(m []byte) fr.Element {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(m)
+	bi := new(big.Int).SetBytes(h.Sum(nil))
+	bi.Mod(bi, fr.Modulus())
+	var out fr.Element
+	out.SetBigInt(bi)
+	return out
+}
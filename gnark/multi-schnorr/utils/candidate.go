@@ -79,6 +79,44 @@ func Sign(sk *big.Int, pub PubKey, msg fr.Element, rng io.Reader, nonce *big.Int
 	}, nil
 }
 
+// off-chain counterpart of the circuit check: [S]G == R + [e]A
+// with e = MiMC(Rx, Ry, Ax, Ay, msg); A and R must be on the curve
+func Verify(pub PubKey, msg fr.Element, sig SchnorrSignature) bool {
+	if pub.Ax == nil || pub.Ay == nil || sig.Rx == nil || sig.Ry == nil || sig.S == nil {
+		return false
+	}
+	params := tebn254.GetEdwardsCurve()
+	G := params.Base
+
+	var A, R tebn254.PointAffine
+	A.X.SetBigInt(pub.Ax)
+	A.Y.SetBigInt(pub.Ay)
+	R.X.SetBigInt(sig.Rx)
+	R.Y.SetBigInt(sig.Ry)
+	if !A.IsOnCurve() || !R.IsOnCurve() {
+		return false
+	}
+
+	e := hash5(R.X, R.Y, A.X, A.Y, msg)
+
+	var sG, eA tebn254.PointAffine
+	sG.ScalarMultiplication(&G, sig.S)
+	eA.ScalarMultiplication(&A, e.BigInt(new(big.Int)))
+	rhs := addAffine(R, eA)
+
+	return sG.Equal(&rhs)
+}
+
+// e = MiMC(Rx, Ry, Ax, Ay, msg), the Schnorr challenge used by Sign and the circuits
+func Challenge(rx, ry *big.Int, pub PubKey, msg fr.Element) fr.Element {
+	var x, y, ax, ay fr.Element
+	x.SetBigInt(rx)
+	y.SetBigInt(ry)
+	ax.SetBigInt(pub.Ax)
+	ay.SetBigInt(pub.Ay)
+	return hash5(x, y, ax, ay, msg)
+}
+
 func hash5(x1, x2, x3, x4, x5 fr.Element) fr.Element {
 	h := mimc.NewMiMC()
 	h.Write(x1.Marshal())
@@ -0,0 +1,57 @@
+package utils_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr/utils"
+)
+
+func TestVerify(t *testing.T) {
+	keys, err := utils.GenerateKeyPairs(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := keys[0].Pub
+	msg := utils.KeccakToFr([]byte("block 1"))
+	sig, err := utils.Sign(keys[0].Priv.Sk, pub, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.Verify(pub, msg, sig) {
+		t.Fatal("honest signature rejected")
+	}
+
+	// a signature survives its bundle encoding
+	decPub, decSig, err := utils.NewBundleSignature(0, pub, sig).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.Verify(decPub, msg, decSig) {
+		t.Fatal("decoded bundle signature rejected")
+	}
+
+	one := big.NewInt(1)
+	for _, c := range []struct {
+		name string
+		pub  utils.PubKey
+		msg  []byte
+		sig  utils.SchnorrSignature
+	}{
+		{"other message", pub, []byte("block 2"), sig},
+		{"other key", keys[1].Pub, nil, sig},
+		{"S+1", pub, nil, utils.SchnorrSignature{Rx: sig.Rx, Ry: sig.Ry, S: new(big.Int).Add(sig.S, one)}},
+		{"R off the curve", pub, nil, utils.SchnorrSignature{Rx: sig.Rx, Ry: new(big.Int).Add(sig.Ry, one), S: sig.S}},
+		{"key off the curve", utils.PubKey{Ax: pub.Ax, Ay: new(big.Int).Add(pub.Ay, one)}, nil, sig},
+		{"missing S", pub, nil, utils.SchnorrSignature{Rx: sig.Rx, Ry: sig.Ry}},
+		{"missing key", utils.PubKey{}, nil, sig},
+	} {
+		m := msg
+		if c.msg != nil {
+			m = utils.KeccakToFr(c.msg)
+		}
+		if utils.Verify(c.pub, m, c.sig) {
+			t.Errorf("%s: accepted", c.name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	mimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+)
+
+type CommitteeWitnessData struct {
+	Root       fr.Element
+	Candidates []Candidate
+	Committees []int // committee id per candidate
+	Message    fr.Element
+	SumValid   []int // valid signatures per committee
+}
+
+// assigns committees in key order: the first sizes[0] keys to committee 0, the next sizes[1] to committee 1, ...
+// remaining (padding) keys go to committee 0
+func AssignCommittees(keys []KeyPair, sizes []int) error {
+	if len(sizes) > multischnorr.NumCommittees {
+		return fmt.Errorf("%d committees > NumCommittees (%d)", len(sizes), multischnorr.NumCommittees)
+	}
+	total := 0
+	for _, n := range sizes {
+		if n < 0 {
+			return fmt.Errorf("committee size must be >= 0, got %d", n)
+		}
+		total += n
+	}
+	if total > len(keys) {
+		return fmt.Errorf("committee sizes add up to %d > %d keys", total, len(keys))
+	}
+
+	i := 0
+	for c, n := range sizes {
+		for j := 0; j < n; j++ {
+			keys[i].Committee = c
+			i++
+		}
+	}
+	for ; i < len(keys); i++ {
+		keys[i].Committee = 0
+	}
+	return nil
+}
+
+// leaf = H(Ax, Ay, Committee), same as in CommitteeCircuit
+func CommitteeLeafHash(k KeyPair) fr.Element {
+	var ax, ay, cid fr.Element
+	ax.SetBigInt(k.Pub.Ax)
+	ay.SetBigInt(k.Pub.Ay)
+	cid.SetUint64(uint64(k.Committee))
+
+	h := mimc.NewMiMC()
+	h.Write(ax.Marshal())
+	h.Write(ay.Marshal())
+	h.Write(cid.Marshal())
+	var out fr.Element
+	_ = out.SetBytes(h.Sum(nil))
+	return out
+}
+
+func BuildCommitteeRoot(keys []KeyPair) (root fr.Element, leaves []fr.Element, err error) {
+	if len(keys) == 0 {
+		return fr.Element{}, nil, errors.New("no keys provided")
+	}
+
+	leaves = make([]fr.Element, 0, len(keys))
+	for _, k := range keys {
+		if k.Committee < 0 || k.Committee >= multischnorr.NumCommittees {
+			return fr.Element{}, nil, fmt.Errorf("committee id %d out of range [0,%d)", k.Committee, multischnorr.NumCommittees)
+		}
+		leaves = append(leaves, CommitteeLeafHash(k))
+	}
+
+	root, err = merkle.Root(leaves)
+	if err != nil {
+		return fr.Element{}, nil, err
+	}
+	return root, leaves, nil
+}
+
+func PrepareCommitteeWitnessData(
+	keys []KeyPair,
+	signerIndices []int,
+	message fr.Element,
+	rng io.Reader,
+	nonce *big.Int,
+) (*CommitteeWitnessData, error) {
+	maxK := multischnorr.MaxK
+	if len(keys) != maxK {
+		return nil, fmt.Errorf("registry has %d keys, expected maxK=%d", len(keys), maxK)
+	}
+
+	fmt.Println("Building committee Merkle root...")
+	root, _, err := BuildCommitteeRoot(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build merkle root: %w", err)
+	}
+
+	fmt.Printf("Generating signatures for %d signers...\n", len(signerIndices))
+	candidates, _, err := BuildCandidates(keys, signerIndices, message, rng, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build candidates: %w", err)
+	}
+
+	committees := make([]int, len(keys))
+	sumValid := make([]int, multischnorr.NumCommittees)
+	for i := range keys {
+		committees[i] = keys[i].Committee
+		if candidates[i].IsIgnore == 0 {
+			sumValid[keys[i].Committee]++
+		}
+	}
+
+	fmt.Printf("Witness data prepared: root=%s, sumValid=%v\n", root.String(), sumValid)
+	return &CommitteeWitnessData{
+		Root:       root,
+		Candidates: candidates,
+		Committees: committees,
+		Message:    message,
+		SumValid:   sumValid,
+	}, nil
+}
@@ -22,8 +22,9 @@ type PubKey struct {
 }
 
 type KeyPair struct {
-	Priv PrivKey
-	Pub  PubKey
+	Priv      PrivKey
+	Pub       PubKey
+	Committee int // committee id, only used by CommitteeCircuit (0 by default)
 }
 
 // samples Sk uniformly in [1, order-1]
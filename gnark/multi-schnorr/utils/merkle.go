@@ -2,47 +2,54 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 
 	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
-	mimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/cowprotocol/Zk-benchmark/gnark/merkle"
 )
 
+// BuildRoot returns the registry root and its leaves; use BuildTree for paths and updates
 func BuildRoot(keys []KeyPair) (root fr.Element, leaves []fr.Element, err error) {
-	if len(keys) == 0 {
-		return fr.Element{}, nil, errors.New("no keys provided")
+	t, leaves, err := BuildTree(keys)
+	if err != nil {
+		return fr.Element{}, nil, err
 	}
+	return t.Root(), leaves, nil
+}
 
-	leaves = make([]fr.Element, 0, len(keys))
+// BuildTree returns the registry tree over LeafHash of each key
+func BuildTree(keys []KeyPair) (*merkle.Tree, []fr.Element, error) {
+	if len(keys) == 0 {
+		return nil, nil, errors.New("no keys provided")
+	}
+	leaves := make([]fr.Element, 0, len(keys))
 	for _, k := range keys {
-		var ax, ay fr.Element
-		ax.SetBigInt(k.Pub.Ax)
-		ay.SetBigInt(k.Pub.Ay)
-
-		leaves = append(leaves, hash2(ax, ay))
+		leaves = append(leaves, LeafHash(k.Pub))
 	}
+	t, err := merkle.New(leaves)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, leaves, nil
+}
 
-	cur := make([]fr.Element, len(leaves))
-	copy(cur, leaves)
+// leaf = H(Ax, Ay), same as in the circuit
+func LeafHash(pub PubKey) fr.Element {
+	var ax, ay fr.Element
+	ax.SetBigInt(pub.Ax)
+	ay.SetBigInt(pub.Ay)
+	return merkle.HashElems(ax, ay)
+}
 
-	for w := len(cur); w > 1; w >>= 1 {
-		next := make([]fr.Element, w/2)
-		for i := 0; i < w/2; i++ {
-			next[i] = hash2(cur[2*i], cur[2*i+1])
-		}
-		cur = next
+// sibling hashes from the leaf at index up to (excluding) the root
+func BuildPath(leaves []fr.Element, index int) ([]fr.Element, error) {
+	t, err := merkle.New(leaves)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Printf("merkle root: %v\n", cur[0])
-	return cur[0], leaves, nil
+	return t.Proof(index)
 }
 
-func hash2(x, y fr.Element) fr.Element {
-	h := mimc.NewMiMC()
-	h.Write(x.Marshal())
-	h.Write(y.Marshal())
-	sum := h.Sum(nil)
-	var out fr.Element
-	_ = out.SetBytes(sum)
-	return out
+// recomputes the root from leaf and path; bit d of index selects the side at level d
+func VerifyPath(root, leaf fr.Element, index int, path []fr.Element) bool {
+	return index >= 0 && merkle.Verify(root, leaf, uint64(index), path)
 }
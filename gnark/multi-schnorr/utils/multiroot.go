@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+)
+
+// seat i of both registries, see multischnorr.MultiRootCandidate
+type MultiRootCandidate struct {
+	OldAx, OldAy *big.Int
+	NewAx, NewAy *big.Int
+	Sig          SchnorrSignature
+	UseNew       uint8 // 1 if signed with the new key, 0 with the old key
+	IsIgnore     uint8 // 1 if this candidate is to be ignored, 0 otherwise
+}
+
+type MultiRootWitnessData struct {
+	RootOld     fr.Element
+	RootNew     fr.Element
+	Candidates  []MultiRootCandidate
+	Message     fr.Element
+	SumValidOld int
+	SumValidNew int
+}
+
+// oldSigners sign with their key from oldKeys, newSigners with their key from newKeys;
+// a seat can sign only once. A signature counts toward both sums when the seat
+// holds the same key in both registries.
+func BuildMultiRootCandidates(
+	oldKeys, newKeys []KeyPair,
+	oldSigners, newSigners []int,
+	msg fr.Element,
+	rng io.Reader,
+	nonce *big.Int,
+) ([]MultiRootCandidate, int, int, error) {
+	if len(oldKeys) == 0 || len(oldKeys) != len(newKeys) {
+		return nil, 0, 0, fmt.Errorf("registries must be non-empty and of the same size, got %d old and %d new keys", len(oldKeys), len(newKeys))
+	}
+
+	// seat -> signs with new key
+	useNew := make(map[int]bool, len(oldSigners)+len(newSigners))
+	for _, set := range []struct {
+		idx   []int
+		isNew bool
+	}{{oldSigners, false}, {newSigners, true}} {
+		for _, i := range set.idx {
+			if i < 0 || i >= len(oldKeys) {
+				return nil, 0, 0, fmt.Errorf("signer index %d out of range [0,%d)", i, len(oldKeys))
+			}
+			if _, dup := useNew[i]; dup {
+				return nil, 0, 0, fmt.Errorf("seat %d listed more than once", i)
+			}
+			useNew[i] = set.isNew
+		}
+	}
+
+	out := make([]MultiRootCandidate, len(oldKeys))
+	sumOld, sumNew := 0, 0
+
+	for i := range oldKeys {
+		c := MultiRootCandidate{
+			OldAx: oldKeys[i].Pub.Ax, OldAy: oldKeys[i].Pub.Ay,
+			NewAx: newKeys[i].Pub.Ax, NewAy: newKeys[i].Pub.Ay,
+			Sig:      zeroSig(),
+			IsIgnore: 1,
+		}
+
+		if isNew, want := useNew[i]; want {
+			kp := oldKeys[i]
+			if isNew {
+				kp = newKeys[i]
+			}
+			if kp.Priv.Sk.Cmp(big.NewInt(0)) == 0 {
+				return nil, 0, 0, fmt.Errorf("index %d marked as signer but has nil/zero SK", i)
+			}
+			sig, err := Sign(kp.Priv.Sk, kp.Pub, msg, rng, nonce)
+			if err != nil {
+				return nil, 0, 0, fmt.Errorf("sign(%d): %w", i, err)
+			}
+			c.Sig = sig
+			c.IsIgnore = 0
+			if isNew {
+				c.UseNew = 1
+			}
+			if samePub(kp.Pub, oldKeys[i].Pub) {
+				sumOld++
+			}
+			if samePub(kp.Pub, newKeys[i].Pub) {
+				sumNew++
+			}
+		}
+
+		out[i] = c
+	}
+
+	return out, sumOld, sumNew, nil
+}
+
+func PrepareMultiRootWitnessData(
+	oldKeys, newKeys []KeyPair,
+	oldSigners, newSigners []int,
+	message fr.Element,
+	rng io.Reader,
+	nonce *big.Int,
+) (*MultiRootWitnessData, error) {
+	maxK := multischnorr.MaxK
+	if len(oldKeys) != maxK || len(newKeys) != maxK {
+		return nil, fmt.Errorf("registries have %d/%d keys, expected maxK=%d each", len(oldKeys), len(newKeys), maxK)
+	}
+
+	fmt.Println("Building old and new Merkle roots...")
+	rootOld, _, err := BuildRoot(oldKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build old merkle root: %w", err)
+	}
+	rootNew, _, err := BuildRoot(newKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build new merkle root: %w", err)
+	}
+
+	fmt.Printf("Generating signatures for %d old and %d new signers...\n", len(oldSigners), len(newSigners))
+	candidates, sumOld, sumNew, err := BuildMultiRootCandidates(oldKeys, newKeys, oldSigners, newSigners, message, rng, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build candidates: %w", err)
+	}
+
+	fmt.Printf("Witness data prepared: sumValidOld=%d, sumValidNew=%d\n", sumOld, sumNew)
+	return &MultiRootWitnessData{
+		RootOld:     rootOld,
+		RootNew:     rootNew,
+		Candidates:  candidates,
+		Message:     message,
+		SumValidOld: sumOld,
+		SumValidNew: sumNew,
+	}, nil
+}
+
+func samePub(a, b PubKey) bool {
+	return a.Ax.Cmp(b.Ax) == 0 && a.Ay.Cmp(b.Ay) == 0
+}
@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	mimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	tebn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	multischnorr "github.com/cowprotocol/Zk-benchmark/gnark/multi-schnorr"
+)
+
+// MuSig2 (two nonces per signer) on BabyJubJub, with MiMC for every hash so that
+// key aggregation can be redone in MuSigCircuit:
+//
+//	L   = H(Root, Bitmap)                 participants, bound by the registry root
+//	a_i = H(L, Ax_i, Ay_i)                key aggregation coefficient
+//	Ã   = sum a_i*A_i                     aggregated key
+//	b   = H(Ãx, Ãy, R1x, R1y, R2x, R2y, msg)
+//	R   = R1 + b*R2                       R1, R2 are the sums of the signers' nonces
+//	e   = H(Rx, Ry, Ãx, Ãy, msg)          same challenge as Sign
+//	s_i = k_i1 + b*k_i2 + e*a_i*sk_i
+//
+// The final signature (R, sum s_i) verifies with Verify(Ã, msg, .).
+
+var ErrNonceReused = errors.New("secret nonce already used")
+
+type KeyAggContext struct {
+	Root   fr.Element
+	Bitmap *big.Int   // bit i set if registry slot i participates
+	L      fr.Element // H(Root, Bitmap)
+	Coeffs []*big.Int // a_i per registry slot, nil for non-participants
+	AggKey PubKey
+}
+
+type SecNonce struct {
+	K1, K2 *big.Int
+}
+
+type PubNonce struct {
+	R1, R2 tebn254.PointAffine
+}
+
+// AggregateKeys computes Ã for the given registry slots
+func AggregateKeys(keys []KeyPair, participants []int) (*KeyAggContext, error) {
+	if len(participants) == 0 {
+		return nil, errors.New("no participants")
+	}
+	root, _, err := BuildRoot(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := new(big.Int)
+	for _, i := range participants {
+		if i < 0 || i >= len(keys) {
+			return nil, fmt.Errorf("participant index %d out of range [0,%d)", i, len(keys))
+		}
+		if bitmap.Bit(i) == 1 {
+			return nil, fmt.Errorf("participant %d listed twice", i)
+		}
+		if keys[i].Pub.Ax.Sign() == 0 {
+			return nil, fmt.Errorf("participant %d has no key (padding slot)", i)
+		}
+		bitmap.SetBit(bitmap, i, 1)
+	}
+
+	var bm fr.Element
+	bm.SetBigInt(bitmap)
+	ctx := &KeyAggContext{
+		Root:   root,
+		Bitmap: bitmap,
+		L:      hashFr(root, bm),
+		Coeffs: make([]*big.Int, len(keys)),
+	}
+
+	var agg tebn254.PointAffine
+	agg.X.SetZero()
+	agg.Y.SetOne()
+	for i := range keys {
+		if bitmap.Bit(i) == 0 {
+			continue
+		}
+		var ax, ay fr.Element
+		ax.SetBigInt(keys[i].Pub.Ax)
+		ay.SetBigInt(keys[i].Pub.Ay)
+		a := hashFr(ctx.L, ax, ay)
+		ctx.Coeffs[i] = a.BigInt(new(big.Int))
+
+		var A, aA tebn254.PointAffine
+		A.X, A.Y = ax, ay
+		aA.ScalarMultiplication(&A, ctx.Coeffs[i])
+		agg.Add(&agg, &aA)
+	}
+	ctx.AggKey = PubKey{Ax: agg.X.BigInt(new(big.Int)), Ay: agg.Y.BigInt(new(big.Int))}
+	return ctx, nil
+}
+
+// round 1: fresh nonces, the public part is sent to the aggregator
+func NonceGen(rng io.Reader) (*SecNonce, PubNonce, error) {
+	params := tebn254.GetEdwardsCurve()
+	if rng == nil {
+		rng = rand.Reader
+	}
+
+	sn := &SecNonce{}
+	var pn PubNonce
+	for j, k := range []**big.Int{&sn.K1, &sn.K2} {
+		for {
+			v, err := rand.Int(rng, &params.Order)
+			if err != nil {
+				return nil, PubNonce{}, err
+			}
+			if v.Sign() != 0 {
+				*k = v
+				break
+			}
+		}
+		R := &pn.R1
+		if j == 1 {
+			R = &pn.R2
+		}
+		R.ScalarMultiplication(&params.Base, *k)
+	}
+	return sn, pn, nil
+}
+
+func NonceAgg(nonces []PubNonce) PubNonce {
+	var out PubNonce
+	out.R1.X.SetZero()
+	out.R1.Y.SetOne()
+	out.R2.X.SetZero()
+	out.R2.Y.SetOne()
+	for i := range nonces {
+		out.R1.Add(&out.R1, &nonces[i].R1)
+		out.R2.Add(&out.R2, &nonces[i].R2)
+	}
+	return out
+}
+
+type SigningSession struct {
+	Ctx *KeyAggContext
+	Msg fr.Element
+	B   *big.Int            // nonce coefficient
+	R   tebn254.PointAffine // final nonce R1 + b*R2
+	E   *big.Int            // challenge
+}
+
+func NewSigningSession(ctx *KeyAggContext, aggNonce PubNonce, msg fr.Element) *SigningSession {
+	var ax, ay fr.Element
+	ax.SetBigInt(ctx.AggKey.Ax)
+	ay.SetBigInt(ctx.AggKey.Ay)
+	b := hashFr(ax, ay, aggNonce.R1.X, aggNonce.R1.Y, aggNonce.R2.X, aggNonce.R2.Y, msg)
+
+	s := &SigningSession{Ctx: ctx, Msg: msg, B: b.BigInt(new(big.Int))}
+	var bR2 tebn254.PointAffine
+	bR2.ScalarMultiplication(&aggNonce.R2, s.B)
+	s.R.Add(&aggNonce.R1, &bR2)
+
+	e := Challenge(s.R.X.BigInt(new(big.Int)), s.R.Y.BigInt(new(big.Int)), ctx.AggKey, msg)
+	s.E = e.BigInt(new(big.Int))
+	return s
+}
+
+// round 2: partial signature of registry slot index; sn is cleared so it cannot be reused
+func (s *SigningSession) PartialSign(index int, sk *big.Int, sn *SecNonce) (*big.Int, error) {
+	if index < 0 || index >= len(s.Ctx.Coeffs) || s.Ctx.Coeffs[index] == nil {
+		return nil, fmt.Errorf("slot %d is not a participant", index)
+	}
+	if sn.K1 == nil || sn.K2 == nil {
+		return nil, ErrNonceReused
+	}
+	params := tebn254.GetEdwardsCurve()
+	order := &params.Order
+
+	// s_i = k_i1 + b*k_i2 + e*a_i*sk_i
+	out := new(big.Int).Mul(s.E, s.Ctx.Coeffs[index])
+	out.Mul(out, sk)
+	out.Add(out, new(big.Int).Mul(s.B, sn.K2))
+	out.Add(out, sn.K1)
+	out.Mod(out, order)
+
+	sn.K1, sn.K2 = nil, nil
+	return out, nil
+}
+
+// [s_i]G == R_i1 + b*R_i2 + [e*a_i]A_i, lets the aggregator blame a bad signer
+func (s *SigningSession) PartialVerify(index int, pub PubKey, pn PubNonce, partial *big.Int) bool {
+	if index < 0 || index >= len(s.Ctx.Coeffs) || s.Ctx.Coeffs[index] == nil {
+		return false
+	}
+	params := tebn254.GetEdwardsCurve()
+
+	var lhs, bR2, eaA, rhs, A tebn254.PointAffine
+	lhs.ScalarMultiplication(&params.Base, partial)
+	bR2.ScalarMultiplication(&pn.R2, s.B)
+	A.X.SetBigInt(pub.Ax)
+	A.Y.SetBigInt(pub.Ay)
+	eaA.ScalarMultiplication(&A, new(big.Int).Mul(s.E, s.Ctx.Coeffs[index]))
+	rhs.Add(&pn.R1, &bR2)
+	rhs.Add(&rhs, &eaA)
+	return lhs.Equal(&rhs)
+}
+
+func (s *SigningSession) Aggregate(partials []*big.Int) SchnorrSignature {
+	params := tebn254.GetEdwardsCurve()
+	order := &params.Order
+	S := new(big.Int)
+	for _, p := range partials {
+		S.Add(S, p)
+	}
+	S.Mod(S, order)
+	return SchnorrSignature{
+		Rx: s.R.X.BigInt(new(big.Int)),
+		Ry: s.R.Y.BigInt(new(big.Int)),
+		S:  S,
+	}
+}
+
+// runs both rounds for the given signers with locally held keys
+func MuSig2Sign(keys []KeyPair, signers []int, msg fr.Element, rng io.Reader) (*KeyAggContext, SchnorrSignature, error) {
+	ctx, err := AggregateKeys(keys, signers)
+	if err != nil {
+		return nil, SchnorrSignature{}, fmt.Errorf("key aggregation: %w", err)
+	}
+
+	secs := make([]*SecNonce, len(signers))
+	pubs := make([]PubNonce, len(signers))
+	for j := range signers {
+		if secs[j], pubs[j], err = NonceGen(rng); err != nil {
+			return nil, SchnorrSignature{}, fmt.Errorf("nonce(%d): %w", signers[j], err)
+		}
+	}
+
+	session := NewSigningSession(ctx, NonceAgg(pubs), msg)
+	partials := make([]*big.Int, len(signers))
+	for j, i := range signers {
+		if partials[j], err = session.PartialSign(i, keys[i].Priv.Sk, secs[j]); err != nil {
+			return nil, SchnorrSignature{}, fmt.Errorf("partial sign(%d): %w", i, err)
+		}
+		if !session.PartialVerify(i, keys[i].Pub, pubs[j], partials[j]) {
+			return nil, SchnorrSignature{}, fmt.Errorf("invalid partial signature from %d", i)
+		}
+	}
+
+	sig := session.Aggregate(partials)
+	if !Verify(ctx.AggKey, msg, sig) {
+		return nil, SchnorrSignature{}, errors.New("aggregated signature does not verify")
+	}
+	return ctx, sig, nil
+}
+
+type MuSigWitnessData struct {
+	Root     fr.Element
+	Keys     []PubKey
+	Bitmap   *big.Int
+	Sig      SchnorrSignature
+	Message  fr.Element
+	SumValid int
+}
+
+func PrepareMuSigWitnessData(keys []KeyPair, signers []int, message fr.Element, rng io.Reader) (*MuSigWitnessData, error) {
+	if len(keys) != multischnorr.MaxK {
+		return nil, fmt.Errorf("registry has %d keys, expected maxK=%d", len(keys), multischnorr.MaxK)
+	}
+
+	fmt.Printf("Running MuSig2 session with %d signers...\n", len(signers))
+	ctx, sig, err := MuSig2Sign(keys, signers, message, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	pubs := make([]PubKey, len(keys))
+	for i := range keys {
+		pubs[i] = keys[i].Pub
+	}
+	fmt.Printf("Witness data prepared: root=%s, bitmap=%s, sumValid=%d\n", ctx.Root.String(), ctx.Bitmap.Text(16), len(signers))
+	return &MuSigWitnessData{
+		Root:     ctx.Root,
+		Keys:     pubs,
+		Bitmap:   ctx.Bitmap,
+		Sig:      sig,
+		Message:  message,
+		SumValid: len(signers),
+	}, nil
+}
+
+// true if signers covers every non-padding slot of the registry
+func IsFullSet(keys []KeyPair, signers []int) bool {
+	in := make(map[int]bool, len(signers))
+	for _, i := range signers {
+		in[i] = true
+	}
+	for i := range keys {
+		if keys[i].Pub.Ax.Sign() != 0 && !in[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hashFr(xs ...fr.Element) fr.Element {
+	h := mimc.NewMiMC()
+	for i := range xs {
+		h.Write(xs[i].Marshal())
+	}
+	var out fr.Element
+	_ = out.SetBytes(h.Sum(nil))
+	return out
+}
@@ -21,16 +21,20 @@ type WitnessData struct {
 }
 
 type SerializableKeyPair struct {
-	PrivSk string `json:"priv_sk"`
-	PubAx  string `json:"pub_ax"`
-	PubAy  string `json:"pub_ay"`
+	PrivSk    string `json:"priv_sk"`
+	PubAx     string `json:"pub_ax"`
+	PubAy     string `json:"pub_ay"`
+	Committee int    `json:"committee,omitempty"`
 }
 
 type SerializableKeys struct {
 	Keys []SerializableKeyPair `json:"keys"`
 }
 
-var keyPath = RepoPath("../keys.json")
+// keys.json at the repo root, as written by keygen
+func DefaultKeyPath() string {
+	return RepoPath("../keys.json")
+}
 
 func toSerializable(keys []KeyPair) SerializableKeys {
 	sk := SerializableKeys{
@@ -38,9 +42,10 @@ func toSerializable(keys []KeyPair) SerializableKeys {
 	}
 	for i, k := range keys {
 		sk.Keys[i] = SerializableKeyPair{
-			PrivSk: k.Priv.Sk.Text(16),
-			PubAx:  k.Pub.Ax.Text(16),
-			PubAy:  k.Pub.Ay.Text(16),
+			PrivSk:    k.Priv.Sk.Text(16),
+			PubAx:     k.Pub.Ax.Text(16),
+			PubAy:     k.Pub.Ay.Text(16),
+			Committee: k.Committee,
 		}
 	}
 	return sk
@@ -63,31 +68,42 @@ func fromSerializable(sk SerializableKeys) ([]KeyPair, error) {
 		}
 
 		keys[i] = KeyPair{
-			Priv: PrivKey{Sk: privSk},
-			Pub:  PubKey{Ax: pubAx, Ay: pubAy},
+			Priv:      PrivKey{Sk: privSk},
+			Pub:       PubKey{Ax: pubAx, Ay: pubAy},
+			Committee: k.Committee,
 		}
 	}
 	return keys, nil
 }
 
 func SaveKeysToFile(keys []KeyPair) error {
+	return SaveKeysTo(DefaultKeyPath(), keys)
+}
+
+// SaveKeysTo writes a registry in the keys.json format to path.
+func SaveKeysTo(path string, keys []KeyPair) error {
 	sk := toSerializable(keys)
 	data, err := json.MarshalIndent(sk, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal keys: %w", err)
 	}
 
-	err = os.WriteFile(keyPath, data, 0600)
+	err = os.WriteFile(path, data, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to write keys to file: %w", err)
 	}
 
-	fmt.Printf("Saved %d keys to %s\n", len(keys), keyPath)
+	fmt.Printf("Saved %d keys to %s\n", len(keys), path)
 	return nil
 }
 
 func LoadKeysFromFile() ([]KeyPair, error) {
-	data, err := os.ReadFile(keyPath)
+	return LoadKeysFrom(DefaultKeyPath())
+}
+
+// LoadKeysFrom reads a registry in the keys.json format from path.
+func LoadKeysFrom(path string) ([]KeyPair, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -103,11 +119,13 @@ func LoadKeysFromFile() ([]KeyPair, error) {
 		return nil, err
 	}
 
-	fmt.Printf("Loaded %d keys from %s\n", len(keys), keyPath)
+	fmt.Printf("Loaded %d keys from %s\n", len(keys), path)
 	return keys, nil
 }
 
+// keys is the full registry (MaxK keys, padded)
 func PrepareWitnessData(
+	keys []KeyPair,
 	signerIndices []int,
 	message fr.Element,
 	rng io.Reader,
@@ -117,27 +135,8 @@ func PrepareWitnessData(
 	if len(signerIndices) > maxK {
 		return nil, fmt.Errorf("signerindices (%d) > maxK (%d)", len(signerIndices), maxK)
 	}
-	if len(signerIndices) < 0 {
-		return nil, fmt.Errorf("signerindices must be >= 0")
-	}
-
-	var keys []KeyPair
-	var err error
-
-	if _, err := os.Stat(keyPath); err == nil {
-		fmt.Println("Key file found, loading keys...")
-		keys, err = LoadKeysFromFile()
-		if err != nil {
-			return nil, fmt.Errorf("failed to load keys: %w", err)
-		}
-		if len(keys) != maxK {
-			return nil, fmt.Errorf("keys.json has %d keys, expected maxK=%d; regenerate keys.json", len(keys), maxK)
-		}
-	} else {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("keys.json not found at %s; run keygen first", keyPath)
-		}
-		return nil, fmt.Errorf("stat %s: %w", keyPath, err)
+	if len(keys) != maxK {
+		return nil, fmt.Errorf("registry has %d keys, expected maxK=%d; regenerate keys.json", len(keys), maxK)
 	}
 
 	fmt.Println("Building Merkle root...")
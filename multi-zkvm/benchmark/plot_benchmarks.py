@@ -47,27 +47,33 @@ colors = {
     'sp1': '#FF69B4',      
     'risc0': '#FFD700',    
     'zisk': '#32CD32',     
-    'pico': '#808080'      
+    'pico': '#808080',
+    'gnark': '#1E90FF'
 }
 
 proof_types = {
     'sp1': 'Groth16',
     'risc0': 'Groth16',
     'zisk': 'Compressed',
-    'pico': 'Compressed'
+    'pico': 'Compressed',
+    'gnark': 'Groth16'
 }
 
+# gnark runs swept over several depths are labelled gnark-d<depth>
+def family(zkvm):
+    return zkvm.split('-')[0]
+
 fig, axes = plt.subplots(1, 3, figsize=(18, 6))
 fig.suptitle('zkVM Benchmarks', fontsize=18, fontweight='bold')
 
 x = np.arange(len(signer_counts))
-width = 0.2
+width = 0.8 / len(zkvms)
 offsets = {zkvm: i * width for i, zkvm in enumerate(zkvms)}
 
 ax1 = axes[0]
 for zkvm in zkvms:
     bars = ax1.bar(x + offsets[zkvm], cycles_data[zkvm], width, 
-                   label=zkvm.upper(), color=colors.get(zkvm, 'gray'))
+                   label=zkvm.upper(), color=colors.get(family(zkvm), 'gray'))
     
     for i, bar in enumerate(bars):
         height = bar.get_height()
@@ -77,7 +83,7 @@ for zkvm in zkvms:
                     ha='center', va='bottom', fontsize=9)
 
 ax1.set_xlabel('Number of Signers', fontsize=12, fontweight='bold')
-ax1.set_ylabel('Execution Cycles (Millions)', fontsize=12, fontweight='bold')
+ax1.set_ylabel('Execution Cycles / Constraints (Millions)', fontsize=12, fontweight='bold')
 ax1.set_title('Execution Cycles', fontsize=13, fontweight='bold')
 ax1.set_xticks(x + width * (len(zkvms) - 1) / 2)
 ax1.set_xticklabels(signer_counts)
@@ -89,7 +95,7 @@ ax1.set_ylim(bottom=0, top=max([max(v) for v in cycles_data.values()]) * 1.15)
 ax2 = axes[1]
 for zkvm in zkvms:
     bars = ax2.bar(x + offsets[zkvm], exec_time_data[zkvm], width,
-                   label=zkvm.upper(), color=colors.get(zkvm, 'gray'))
+                   label=zkvm.upper(), color=colors.get(family(zkvm), 'gray'))
     
     for i, bar in enumerate(bars):
         height = bar.get_height()
@@ -110,7 +116,7 @@ ax2.set_yscale('log')
 ax3 = axes[2]
 for zkvm in zkvms:
     bars = ax3.bar(x + offsets[zkvm], prove_time_data[zkvm], width,
-                   label=zkvm.upper(), color=colors.get(zkvm, 'gray'))
+                   label=zkvm.upper(), color=colors.get(family(zkvm), 'gray'))
     
     for i, bar in enumerate(bars):
         height = bar.get_height()
@@ -143,6 +149,6 @@ for num_signers in signer_counts:
         if zkvm in grouped[num_signers]:
             r = grouped[num_signers][zkvm]
             cycles_m = r['execution_cycles'] / 1_000_000
-            proof_type = proof_types.get(zkvm, 'Unknown')
+            proof_type = proof_types.get(family(zkvm), 'Unknown')
             print(f"  {zkvm.upper():<10} {proof_type:<12} {cycles_m:<15.2f} "
                   f"{r['execution_duration']:<18.3f} {r['proving_duration']:<18.3f}")
\ No newline at end of file